@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/savisec/hello-go/internal/auth"
+)
+
+func newAPIKeyCommand() *cobra.Command {
+	apiKeyCmd := &cobra.Command{
+		Use:   "apikey",
+		Short: "Manage API keys",
+	}
+
+	generateCmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate a new API key",
+		Long: "Generate a new API key. The key is printed once; only the salted hash in the " +
+			"printed config entry is needed by the server, under auth.api_keys.",
+		RunE: runAPIKeyGenerate,
+	}
+	generateCmd.Flags().String("id", "", "public key ID (random if empty)")
+	generateCmd.Flags().String("principal", "", "caller the key identifies (defaults to the ID)")
+	generateCmd.Flags().StringSlice("scope", nil, "scope to grant, e.g. echo:write (repeatable)")
+
+	apiKeyCmd.AddCommand(generateCmd)
+
+	return apiKeyCmd
+}
+
+func runAPIKeyGenerate(cmd *cobra.Command, args []string) error {
+	id, _ := cmd.Flags().GetString("id")
+	principal, _ := cmd.Flags().GetString("principal")
+	scopes, _ := cmd.Flags().GetStringSlice("scope")
+
+	raw, key, err := auth.GenerateKey(id, principal, scopes)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "API key (shown once): %s\n\nAdd to auth.api_keys:\n", raw)
+	fmt.Fprintf(out, "  - id: %s\n", key.ID)
+	if key.Principal != "" {
+		fmt.Fprintf(out, "    principal: %s\n", key.Principal)
+	}
+	if len(key.Scopes) > 0 {
+		fmt.Fprintf(out, "    scopes: [%s]\n", strings.Join(key.Scopes, ", "))
+	}
+	fmt.Fprintf(out, "    salt: %s\n    hash: %s\n", key.Salt, key.Hash)
+	return nil
+}
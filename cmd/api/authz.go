@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/savisec/hello-go/api"
+	"github.com/savisec/hello-go/internal/authz"
+	"github.com/savisec/hello-go/internal/config"
+	"github.com/savisec/hello-go/internal/lambdactx"
+	"github.com/savisec/hello-go/internal/operation"
+)
+
+func newAuthzCommand() *cobra.Command {
+	authzCmd := &cobra.Command{
+		Use:   "authz",
+		Short: "Inspect the authorization policy",
+	}
+
+	checkCmd := &cobra.Command{
+		Use:   "check",
+		Short: "Dry-run an authorization decision",
+		Long: "Evaluate the authorization policy for a caller and operation and print the decision. " +
+			"Nothing is written to the audit log. Exits non-zero if the request would be denied.",
+		RunE: runAuthzCheck,
+		// A denial is the answer, not a usage mistake.
+		SilenceUsage: true,
+	}
+	checkCmd.Flags().String("policy", "", "policy file (defaults to authz.policy_file)")
+	checkCmd.Flags().String("operation", "", "OpenAPI operation ID, e.g. echo")
+	checkCmd.Flags().String("subject", "", "caller principal; omit for an anonymous caller")
+	checkCmd.Flags().String("authorizer", "api-key", "authentication mechanism of the caller")
+	checkCmd.Flags().StringSlice("scope", nil, "scope the caller holds (repeatable)")
+	checkCmd.Flags().StringArray("claim", nil, "claim the caller holds as name=value (repeatable)")
+	checkCmd.Flags().String("body", "", "JSON request body, or @file to read it from a file")
+	_ = checkCmd.MarkFlagRequired("operation")
+
+	authzCmd.AddCommand(checkCmd)
+
+	return authzCmd
+}
+
+func runAuthzCheck(cmd *cobra.Command, args []string) error {
+	policyPath, _ := cmd.Flags().GetString("policy")
+	operationID, _ := cmd.Flags().GetString("operation")
+	subject, _ := cmd.Flags().GetString("subject")
+	authorizer, _ := cmd.Flags().GetString("authorizer")
+	scopes, _ := cmd.Flags().GetStringSlice("scope")
+	claims, _ := cmd.Flags().GetStringArray("claim")
+	body, _ := cmd.Flags().GetString("body")
+
+	if policyPath == "" {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		policyPath = cfg.Authz.PolicyFile
+	}
+
+	policy, err := authz.LoadPolicy(policyPath)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+
+	resolver, err := operation.NewResolver(api.OpenAPISpec)
+	if err != nil {
+		return err
+	}
+	if _, ok := resolver.Lookup(operationID); !ok {
+		fmt.Fprintf(out, "warning: operation %q is not in the OpenAPI spec\n", operationID)
+	}
+
+	in := authz.Input{Operation: operationID}
+	if subject != "" {
+		in.Identity = &lambdactx.Identity{
+			Subject:    subject,
+			Authorizer: authorizer,
+			Scopes:     scopes,
+			Claims:     make(map[string]string, len(claims)),
+		}
+		for _, claim := range claims {
+			name, value, ok := strings.Cut(claim, "=")
+			if !ok {
+				return fmt.Errorf("claim %q is not name=value", claim)
+			}
+			in.Identity.Claims[name] = value
+		}
+	}
+
+	if body != "" {
+		data := []byte(body)
+		if path, ok := strings.CutPrefix(body, "@"); ok {
+			if data, err = os.ReadFile(path); err != nil {
+				return fmt.Errorf("failed to read body: %w", err)
+			}
+		}
+		if err := json.Unmarshal(data, &in.Body); err != nil {
+			return fmt.Errorf("failed to parse body: %w", err)
+		}
+		// The server rejects bodies the policy and handler could read
+		// differently; so does the dry run.
+		if _, err := authz.DecodeBody(data); err != nil {
+			return fmt.Errorf("failed to parse body: %w", err)
+		}
+	}
+
+	decision := authz.NewEngine(policy).Decide(in)
+
+	outcome := "deny"
+	if decision.Allowed {
+		outcome = "allow"
+	}
+	fmt.Fprintf(out, "Decision: %s\n", outcome)
+	if decision.Rule != "" {
+		fmt.Fprintf(out, "Rule:     %s\n", decision.Rule)
+	}
+	fmt.Fprintf(out, "Reason:   %s\n", decision.Reason)
+	fmt.Fprintf(out, "Roles:    %s\n", strings.Join(decision.Roles, ", "))
+
+	if !decision.Allowed {
+		return errors.New("request would be denied")
+	}
+	return nil
+}
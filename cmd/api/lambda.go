@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/savisec/hello-go/api"
+	"github.com/savisec/hello-go/internal/app"
+	"github.com/savisec/hello-go/internal/lambdaemu"
+	"github.com/savisec/hello-go/internal/logging"
+)
+
+func newLambdaCommand() *cobra.Command {
+	lambdaCmd := &cobra.Command{
+		Use:   "lambda",
+		Short: "Run the Lambda handler locally",
+	}
+
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the Lambda handler over local HTTP",
+		Long: "Run the Lambda handler in-process behind a local HTTP server. Plain HTTP requests are " +
+			"translated into API Gateway v2 events, and raw events can be POSTed to " +
+			lambdaemu.InvocationsPath + " as with the runtime interface emulator. " +
+			"With lambda.mode set to streaming, HTTP requests go to the response streaming " +
+			"entrypoint as function URL events and bodies are flushed as they are produced; " +
+			"raw events still use the buffered handler, and only those are recorded.",
+		RunE: runLambdaServe,
+	}
+	serveCmd.Flags().String("addr", "localhost:9000", "address to listen on")
+	serveCmd.Flags().Duration("timeout", 30*time.Second, "per-invocation timeout")
+	serveCmd.Flags().String("record", "", "append redacted events and responses to this JSONL file")
+
+	invokeCmd := &cobra.Command{
+		Use:   "invoke",
+		Short: "Run a single event through the Lambda handler",
+		Long: "Run the event in a JSON file through the Lambda handler and print the response. " +
+			"A .jsonl file written by 'lambda serve --record' is replayed instead, and every " +
+			"response is compared with the recorded one.",
+		RunE: runLambdaInvoke,
+	}
+	invokeCmd.Flags().String("event", "", "path to the event JSON or a JSONL recording")
+	_ = invokeCmd.MarkFlagRequired("event")
+
+	fixturesCmd := &cobra.Command{
+		Use:   "fixtures",
+		Short: "Generate API Gateway v2 event fixtures from the OpenAPI spec",
+		Long:  "Write one <operationId>.json event per operation in the OpenAPI spec",
+		RunE:  runLambdaFixtures,
+	}
+	fixturesCmd.Flags().String("spec", "", "path to an OpenAPI spec (defaults to the embedded api/openapi.yml)")
+	fixturesCmd.Flags().String("out", ".", "directory to write fixtures to")
+
+	lambdaCmd.AddCommand(serveCmd, invokeCmd, fixturesCmd)
+
+	return lambdaCmd
+}
+
+func runLambdaServe(cmd *cobra.Command, args []string) error {
+	addr, _ := cmd.Flags().GetString("addr")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	recordPath, _ := cmd.Flags().GetString("record")
+
+	ctx, cancel := signal.NotifyContext(context.Background(),
+		syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	application, err := app.InitializeLambda(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize lambda: %w", err)
+	}
+
+	handler := application.Handler
+	if recordPath != "" {
+		f, err := os.OpenFile(recordPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return fmt.Errorf("failed to open recording file: %w", err)
+		}
+
+		//nolint:errcheck
+		//goland:noinspection GoUnhandledErrorResult
+		defer f.Close()
+
+		redactor, err := logging.NewRedactor(application.Config.Logging.Redaction, application.Fingerprinter)
+		if err != nil {
+			return fmt.Errorf("failed to setup redaction: %w", err)
+		}
+		handler = lambdaemu.NewRecorder(handler, f, redactor, application.Logger)
+	}
+
+	emulator := lambdaemu.New(handler, timeout, application.Logger)
+	if application.Streaming != nil {
+		emulator = lambdaemu.NewStreaming(handler, application.Streaming, timeout, application.Logger)
+	}
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           emulator,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serverErrCh := make(chan error, 1)
+	go func() {
+		application.Logger.Info("Serving Lambda handler locally", "addr", addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErrCh <- err
+		}
+	}()
+
+	select {
+	case err := <-serverErrCh:
+		application.Logger.Error("Server error", "error", err)
+		return err
+	case <-ctx.Done():
+		application.Logger.Info("Shutdown signal received")
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer shutdownCancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shutdown server: %w", err)
+	}
+	return application.Shutdown(shutdownCtx)
+}
+
+func runLambdaInvoke(cmd *cobra.Command, args []string) error {
+	eventPath, _ := cmd.Flags().GetString("event")
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	event, err := os.ReadFile(eventPath)
+	if err != nil {
+		return fmt.Errorf("failed to read event: %w", err)
+	}
+
+	application, err := app.InitializeLambda(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize lambda: %w", err)
+	}
+
+	//nolint:errcheck
+	//goland:noinspection GoUnhandledErrorResult
+	defer application.Shutdown(context.Background())
+
+	if filepath.Ext(eventPath) == ".jsonl" {
+		return replayRecording(ctx, cmd.OutOrStdout(), application, event)
+	}
+
+	resp, err := application.Handler.Invoke(lambdaemu.NewContext(ctx), event)
+	if err != nil {
+		return fmt.Errorf("invocation failed: %w", err)
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, resp, "", "  "); err != nil {
+		// Not every handler returns JSON; print the raw payload.
+		out.Reset()
+		out.Write(resp)
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), out.String())
+	return nil
+}
+
+func replayRecording(ctx context.Context, w io.Writer, application *app.LambdaApplication, recording []byte) error {
+	results, err := lambdaemu.Replay(ctx, application.Handler, bytes.NewReader(recording))
+	if err != nil {
+		return err
+	}
+
+	mismatches := 0
+	for _, result := range results {
+		if result.Match() {
+			fmt.Fprintf(w, "line %d: ok\n", result.Line)
+			continue
+		}
+
+		mismatches++
+		got := string(result.Response)
+		if result.Err != nil {
+			got = "error: " + result.Err.Error()
+		}
+		want := string(result.Recording.Response)
+		if result.Recording.Error != "" {
+			want = "error: " + result.Recording.Error
+		}
+		fmt.Fprintf(w, "line %d: mismatch\n  want: %s\n  got:  %s\n", result.Line, want, got)
+	}
+
+	if mismatches > 0 {
+		return fmt.Errorf("%d of %d recorded invocations did not match", mismatches, len(results))
+	}
+	return nil
+}
+
+func runLambdaFixtures(cmd *cobra.Command, args []string) error {
+	specPath, _ := cmd.Flags().GetString("spec")
+	outDir, _ := cmd.Flags().GetString("out")
+
+	spec := api.OpenAPISpec
+	if specPath != "" {
+		var err error
+		if spec, err = os.ReadFile(specPath); err != nil {
+			return fmt.Errorf("failed to read OpenAPI spec: %w", err)
+		}
+	}
+
+	fixtures, err := lambdaemu.GenerateFixtures(spec)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	for _, fixture := range fixtures {
+		data, err := json.MarshalIndent(fixture.Event, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode fixture %s: %w", fixture.OperationID, err)
+		}
+
+		path := filepath.Join(outDir, fixture.OperationID+".json")
+		if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+			return fmt.Errorf("failed to write fixture: %w", err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Wrote %s\n", path)
+	}
+
+	return nil
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/savisec/hello-go/internal/app"
 	"github.com/savisec/hello-go/internal/config"
+	"github.com/savisec/hello-go/internal/logging"
 )
 
 func main() {
@@ -32,6 +33,11 @@ func newRootCommand() *cobra.Command {
 
 	rootCmd.AddCommand(newServeCommand())
 	rootCmd.AddCommand(newHealthCommand())
+	rootCmd.AddCommand(newAuditCommand())
+	rootCmd.AddCommand(newLambdaCommand())
+	rootCmd.AddCommand(newAPIKeyCommand())
+	rootCmd.AddCommand(newAuthzCommand())
+	rootCmd.AddCommand(newSigningCommand())
 
 	return rootCmd
 }
@@ -55,6 +61,8 @@ func runServe(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to initialize application: %w", err)
 	}
 
+	go reopenLogsOnHangup(ctx, application.Logger)
+
 	serverErrCh := make(chan error, 1)
 
 	go func() {
@@ -86,6 +94,27 @@ func runServe(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// reopenLogsOnHangup reopens file log sinks on SIGHUP so an external
+// logrotate can move files aside without restarting the server.
+func reopenLogsOnHangup(ctx context.Context, logger *slog.Logger) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			if err := logging.Reopen(); err != nil {
+				logger.Error("Failed to reopen log sinks", "error", err)
+				continue
+			}
+			logger.Info("Reopened log sinks")
+		}
+	}
+}
+
 func newHealthCommand() *cobra.Command {
 	return &cobra.Command{
 		Use:   "health",
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/savisec/hello-go/internal/signing"
+)
+
+func newSigningCommand() *cobra.Command {
+	signingCmd := &cobra.Command{
+		Use:   "signing",
+		Short: "Manage HMAC request signing keys",
+	}
+
+	generateCmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate a new signing key",
+		Long: "Generate a new shared secret for HMAC request signing and print the config entry " +
+			"for auth.signing.keys. Unlike API keys, the server needs the secret itself, so give " +
+			"the partner the secret over a secure channel and keep the entry out of version control.",
+		RunE: runSigningGenerate,
+	}
+	generateCmd.Flags().String("id", "", "public key ID (random if empty)")
+	generateCmd.Flags().String("principal", "", "caller the key identifies (defaults to the ID)")
+	generateCmd.Flags().StringSlice("scope", nil, "scope to grant, e.g. echo:write (repeatable)")
+
+	signingCmd.AddCommand(generateCmd)
+
+	return signingCmd
+}
+
+func runSigningGenerate(cmd *cobra.Command, args []string) error {
+	id, _ := cmd.Flags().GetString("id")
+	principal, _ := cmd.Flags().GetString("principal")
+	scopes, _ := cmd.Flags().GetStringSlice("scope")
+
+	if id == "" {
+		var b [6]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return fmt.Errorf("failed to generate key id: %w", err)
+		}
+		id = hex.EncodeToString(b[:])
+	}
+
+	secret, err := signing.GenerateSecret()
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintln(out, "Add to auth.signing.keys:")
+	fmt.Fprintf(out, "  - id: %s\n", id)
+	if principal != "" {
+		fmt.Fprintf(out, "    principal: %s\n", principal)
+	}
+	if len(scopes) > 0 {
+		fmt.Fprintf(out, "    scopes: [%s]\n", strings.Join(scopes, ", "))
+	}
+	fmt.Fprintf(out, "    secret: %s\n", secret)
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/savisec/hello-go/internal/config"
+	"github.com/savisec/hello-go/internal/extension"
+	"github.com/savisec/hello-go/internal/logging"
+)
+
+func main() {
+	// Extensions start in /opt; read the same configs/ as the function.
+	if taskRoot := os.Getenv("LAMBDA_TASK_ROOT"); taskRoot != "" {
+		if err := os.Chdir(taskRoot); err != nil {
+			slog.Error("failed to change to task root", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("failed to load config", "error", err)
+		os.Exit(1)
+	}
+
+	logger, err := logging.Setup(cfg.Logging, logging.NewFingerprinter(cfg.Logging.Redaction.HashSalt))
+	if err != nil {
+		slog.Error("failed to setup logging", "error", err)
+		os.Exit(1)
+	}
+
+	exporter, err := extension.NewOTLPExporter(cfg.Extension.Endpoint, cfg.Telemetry.ServiceName)
+	if err != nil {
+		logger.Error("failed to create exporter", "error", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	// Lambda requires the registered name to match the file name.
+	name := filepath.Base(os.Args[0])
+	ext := extension.New(cfg.Extension, name, os.Getenv("AWS_LAMBDA_RUNTIME_API"), exporter, logger)
+	if err := ext.Run(ctx); err != nil {
+		logger.Error("extension failed", "error", err)
+		os.Exit(1)
+	}
+}
@@ -2,67 +2,46 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
 
-	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	chiadapter "github.com/awslabs/aws-lambda-go-api-proxy/chi"
-	"github.com/go-chi/chi/v5"
 
-	"github.com/savisec/hello-go/internal/config"
-	"github.com/savisec/hello-go/internal/logging"
-	"github.com/savisec/hello-go/internal/router"
-	"github.com/savisec/hello-go/internal/telemetry"
+	"github.com/savisec/hello-go/internal/app"
+	"github.com/savisec/hello-go/internal/lambdaruntime"
 )
 
-var (
-	chiLambda         *chiadapter.ChiLambdaV2
-	telemetryProvider *telemetry.Provider
-	logger            *slog.Logger
-)
-
-func init() {
+func main() {
 	ctx := context.Background()
 
-	cfg, err := config.Load()
+	application, err := app.InitializeLambda(ctx)
 	if err != nil {
-		slog.Error("failed to load config", "error", err)
-		os.Exit(1)
-	}
-
-	logger = logging.Setup(cfg.Logging)
+		slog.Error("failed to initialize lambda", "error", err)
 
-	telemetryProvider, err = telemetry.Setup(ctx, cfg.Telemetry)
-	if err != nil {
-		logger.Error("failed to setup telemetry", "error", err)
+		// Tell the runtime why init failed; the exit alone would only show up
+		// as an unexplained Runtime.ExitError.
+		if reportErr := lambdaruntime.ReportInitError(ctx, err); reportErr != nil && !errors.Is(reportErr, lambdaruntime.ErrNoRuntimeAPI) {
+			slog.Error("failed to report init error", "error", reportErr)
+		}
 		os.Exit(1)
 	}
 
-	r := router.BuildRouter(logger)
-	chiLambda = chiadapter.NewV2(r.(*chi.Mux))
-
 	// Set up signal handler for graceful shutdown
-	go shutdownHook()
+	go shutdownHook(application)
+
+	lambda.Start(application.Entrypoint)
 }
 
-func shutdownHook() {
+func shutdownHook(application *app.LambdaApplication) {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	<-sigChan
 
-	if shutdownErr := telemetryProvider.Shutdown(context.Background()); shutdownErr != nil {
-		logger.Error("failed to shutdown telemetry", "error", shutdownErr)
+	if shutdownErr := application.Shutdown(context.Background()); shutdownErr != nil {
+		application.Logger.Error("failed to shutdown", "error", shutdownErr)
 	}
 	os.Exit(0)
 }
-
-func handler(ctx context.Context, req events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
-	return chiLambda.ProxyWithContextV2(ctx, req)
-}
-
-func main() {
-	lambda.Start(handler)
-}
@@ -3,6 +3,12 @@
 // Code generated by github.com/oapi-codegen/oapi-codegen/v2 version v2.5.0 DO NOT EDIT.
 package api
 
+const (
+	ApiKeyAuthScopes    = "apiKeyAuth.Scopes"
+	BearerAuthScopes    = "bearerAuth.Scopes"
+	HmacSignatureScopes = "hmacSignature.Scopes"
+)
+
 // EchoMessage defines model for EchoMessage.
 type EchoMessage struct {
 	// Author The author of the message
@@ -4,6 +4,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
@@ -85,6 +86,16 @@ func (siw *ServerInterfaceWrapper) Readyz(w http.ResponseWriter, r *http.Request
 // Echo operation middleware
 func (siw *ServerInterfaceWrapper) Echo(w http.ResponseWriter, r *http.Request) {
 
+	ctx := r.Context()
+
+	ctx = context.WithValue(ctx, BearerAuthScopes, []string{"echo:write"})
+
+	ctx = context.WithValue(ctx, ApiKeyAuthScopes, []string{"echo:write"})
+
+	ctx = context.WithValue(ctx, HmacSignatureScopes, []string{"echo:write"})
+
+	r = r.WithContext(ctx)
+
 	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		siw.Handler.Echo(w, r)
 	}))
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 
+	"github.com/savisec/hello-go/internal/audit"
 	"github.com/savisec/hello-go/internal/config"
 	"github.com/savisec/hello-go/internal/httpserver"
 	"github.com/savisec/hello-go/internal/logging"
@@ -15,6 +16,8 @@ import (
 // Application encompasses the server, telemetry, configuration, and logger.
 type Application struct {
 	Server            *httpserver.Server
+	Audit             *audit.Logger
+	Fingerprinter     *logging.Fingerprinter
 	TelemetryProvider *telemetry.Provider
 	Config            *config.Config
 	Logger            *slog.Logger
@@ -27,19 +30,36 @@ func Initialize(ctx context.Context) (*Application, error) {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
-	logger := logging.Setup(cfg.Logging)
+	fingerprinter := logging.NewFingerprinter(cfg.Logging.Redaction.HashSalt)
+	logger, err := logging.Setup(cfg.Logging, fingerprinter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup logging: %w", err)
+	}
+
+	auditLogger, err := audit.New(cfg.Audit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup audit log: %w", err)
+	}
 
 	telemetryProvider, err := telemetry.Setup(ctx, cfg.Telemetry)
 	if err != nil {
 		return nil, fmt.Errorf("failed to setup telemetry: %w", err)
 	}
 
-	r := router.BuildRouter(logger)
+	r, err := router.BuildRouter(cfg, logger, auditLogger, fingerprinter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build router: %w", err)
+	}
 
-	server := httpserver.New(cfg.Server, r, logger)
+	server, err := httpserver.New(cfg.Server, r, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create server: %w", err)
+	}
 
 	return &Application{
 		Server:            server,
+		Audit:             auditLogger,
+		Fingerprinter:     fingerprinter,
 		TelemetryProvider: telemetryProvider,
 		Config:            cfg,
 		Logger:            logger,
@@ -57,5 +77,14 @@ func (app *Application) Shutdown(ctx context.Context) error {
 		return fmt.Errorf("failed to shutdown telemetry: %w", err)
 	}
 
+	if err := app.Audit.Close(); err != nil {
+		return fmt.Errorf("failed to close audit log: %w", err)
+	}
+
+	logging.Flush(ctx)
+	if err := logging.Close(); err != nil {
+		return fmt.Errorf("failed to close log sinks: %w", err)
+	}
+
 	return nil
 }
@@ -0,0 +1,175 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/aws/aws-lambda-go/lambda"
+
+	"github.com/savisec/hello-go/internal/audit"
+	"github.com/savisec/hello-go/internal/config"
+	"github.com/savisec/hello-go/internal/lambdaproxy"
+	"github.com/savisec/hello-go/internal/logging"
+	"github.com/savisec/hello-go/internal/router"
+	"github.com/savisec/hello-go/internal/services"
+	"github.com/savisec/hello-go/internal/telemetry"
+	"github.com/savisec/hello-go/internal/triggers"
+)
+
+const (
+	lambdaModeBuffered  = "buffered"
+	lambdaModeStreaming = "streaming"
+)
+
+// LambdaApplication holds the Lambda handler chain and its dependencies.
+type LambdaApplication struct {
+	// Handler serves every supported event with a buffered response.
+	Handler lambda.Handler
+	// Entrypoint is what lambda.Start should run, as selected by lambda.mode.
+	Entrypoint any
+	// Streaming is the response streaming entrypoint; nil unless lambda.mode
+	// is streaming.
+	Streaming         telemetry.StreamingFunc
+	Audit             *audit.Logger
+	Fingerprinter     *logging.Fingerprinter
+	TelemetryProvider *telemetry.Provider
+	Config            *config.Config
+	Logger            *slog.Logger
+}
+
+// InitError reports a failed Lambda initialization and the phase that
+// failed, so the runtime can be told what went wrong.
+type InitError struct {
+	Err   error
+	Phase string
+}
+
+func (e *InitError) Error() string {
+	return fmt.Sprintf("lambda init failed in %s phase: %v", e.Phase, e.Err)
+}
+
+func (e *InitError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorType names the error for the Lambda runtime, e.g. "InitError.config".
+func (e *InitError) ErrorType() string {
+	return "InitError." + e.Phase
+}
+
+// InitializeLambda builds the Lambda handler chain from config. Each phase
+// is timed and, once telemetry is available, reported as a span and
+// metrics. Failures are returned as an *InitError.
+func InitializeLambda(ctx context.Context) (*LambdaApplication, error) {
+	timer := telemetry.NewInitTimer()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, &InitError{Phase: "config", Err: err}
+	}
+	if os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != "" {
+		useCloudWatchFormats(cfg)
+	}
+	timer.Mark("config")
+
+	fingerprinter := logging.NewFingerprinter(cfg.Logging.Redaction.HashSalt)
+	logger, err := logging.Setup(cfg.Logging, fingerprinter)
+	if err != nil {
+		return nil, &InitError{Phase: "logging", Err: err}
+	}
+	timer.Mark("logging")
+
+	auditLogger, err := audit.New(cfg.Audit)
+	if err != nil {
+		return nil, &InitError{Phase: "audit", Err: err}
+	}
+	timer.Mark("audit")
+
+	telemetryProvider, err := telemetry.Setup(ctx, cfg.Telemetry)
+	if err != nil {
+		return nil, &InitError{Phase: "telemetry", Err: err}
+	}
+	timer.Mark("telemetry")
+
+	r, err := router.BuildRouter(cfg, logger, auditLogger, fingerprinter)
+	if err != nil {
+		return nil, &InitError{Phase: "router", Err: err}
+	}
+	timer.Mark("router")
+
+	publisher, err := triggers.NewPublisher(cfg.Lambda.Destination, logger)
+	if err != nil {
+		return nil, &InitError{Phase: "handler", Err: fmt.Errorf("failed to setup result destination: %w", err)}
+	}
+
+	echoService := services.NewEchoService(logger, fingerprinter)
+	dispatcher := triggers.NewHandler(echoService, publisher, lambdaproxy.New(r), logger)
+	handler := telemetry.NewLambdaHandler(dispatcher, telemetryProvider, cfg.Lambda.FlushTimeout)
+
+	var (
+		entrypoint any
+		streaming  telemetry.StreamingFunc
+	)
+	switch cfg.Lambda.Mode {
+	case "", lambdaModeBuffered:
+		entrypoint = handler
+	case lambdaModeStreaming:
+		streaming = telemetry.WrapStreaming(lambdaproxy.NewStreaming(r).Handle, telemetryProvider, cfg.Lambda.FlushTimeout)
+		entrypoint = streaming
+	default:
+		return nil, &InitError{Phase: "handler", Err: fmt.Errorf("unknown lambda mode %q", cfg.Lambda.Mode)}
+	}
+	timer.Mark("handler")
+
+	telemetry.RecordInit(ctx, timer)
+
+	attrs := []any{"total", timer.Total()}
+	for _, phase := range timer.Phases() {
+		attrs = append(attrs, phase.Name, phase.Duration)
+	}
+	logger.Debug("Lambda initialized", attrs...)
+
+	return &LambdaApplication{
+		Handler:           handler,
+		Entrypoint:        entrypoint,
+		Streaming:         streaming,
+		Audit:             auditLogger,
+		Fingerprinter:     fingerprinter,
+		TelemetryProvider: telemetryProvider,
+		Config:            cfg,
+		Logger:            logger,
+	}, nil
+}
+
+// useCloudWatchFormats switches the human-oriented defaults to formats
+// CloudWatch understands when running in Lambda: Powertools-style JSON logs
+// and EMF metrics. Explicitly configured JSON logs or OTLP export are kept.
+func useCloudWatchFormats(cfg *config.Config) {
+	switch cfg.Logging.Format {
+	case "", "text", "pretty":
+		cfg.Logging.Format = "lambda-json"
+	}
+	switch cfg.Telemetry.Exporter {
+	case "", "stdout":
+		cfg.Telemetry.Exporter = "emf"
+	}
+}
+
+func (app *LambdaApplication) Shutdown(ctx context.Context) error {
+	if err := app.TelemetryProvider.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to shutdown telemetry: %w", err)
+	}
+
+	if err := app.Audit.Close(); err != nil {
+		return fmt.Errorf("failed to close audit log: %w", err)
+	}
+
+	logging.Flush(ctx)
+	if err := logging.Close(); err != nil {
+		return fmt.Errorf("failed to close log sinks: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,335 @@
+package audit_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/internal/audit"
+	"github.com/savisec/hello-go/internal/config"
+)
+
+var (
+	testKeyHex = strings.Repeat("0f", 32)
+	testKey, _ = hex.DecodeString(testKeyHex)
+)
+
+func writeChain(t *testing.T, n int) []string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	logger := audit.NewLogger(&buf, testKey)
+	for i := 0; i < n; i++ {
+		err := logger.Log(context.Background(), audit.Event{
+			Type:    audit.EventAuthDecision,
+			Outcome: audit.OutcomeAllow,
+			Actor:   "alice",
+			Details: map[string]any{"attempt": i},
+		})
+		require.NoError(t, err)
+	}
+
+	return strings.Split(strings.TrimSpace(buf.String()), "\n")
+}
+
+func TestVerify_IntactChain(t *testing.T) {
+	lines := writeChain(t, 5)
+
+	count, err := audit.Verify(strings.NewReader(strings.Join(lines, "\n")), testKey, audit.VerifyOptions{})
+
+	require.NoError(t, err)
+	assert.Equal(t, 5, count)
+}
+
+// checkpoint returns the seq and hash of an encoded record.
+func checkpoint(t *testing.T, encoded string) audit.Checkpoint {
+	t.Helper()
+
+	var l struct {
+		Hash   string       `json:"hash"`
+		Record audit.Record `json:"record"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(encoded), &l))
+	return audit.Checkpoint{Seq: l.Record.Seq, Hash: l.Hash}
+}
+
+func TestVerify_Anchors(t *testing.T) {
+	lines := writeChain(t, 5)
+	head := checkpoint(t, lines[4])
+	archived := checkpoint(t, lines[1])
+
+	tests := []struct {
+		opts     audit.VerifyOptions
+		name     string
+		wantErr  string
+		lines    []string
+		wantRecs int
+	}{
+		{name: "full log with head", lines: lines, opts: audit.VerifyOptions{Head: &head}, wantRecs: 5},
+		{
+			name: "archived prefix with checkpoint", lines: lines[2:],
+			opts: audit.VerifyOptions{Checkpoint: &archived, Head: &head}, wantRecs: 3,
+		},
+		{name: "archived prefix without checkpoint", lines: lines[2:], wantErr: "expected seq 1, got 3"},
+		{
+			name: "checkpoint of another record", lines: lines[3:],
+			opts: audit.VerifyOptions{Checkpoint: &archived}, wantErr: "expected seq 3, got 4",
+		},
+		{
+			name: "forged checkpoint hash", lines: lines[2:],
+			opts:    audit.VerifyOptions{Checkpoint: &audit.Checkpoint{Seq: 2, Hash: head.Hash}},
+			wantErr: "does not link to genesis or the checkpoint",
+		},
+		{
+			name: "truncated tail", lines: lines[:3],
+			opts: audit.VerifyOptions{Head: &head}, wantErr: "log ends at seq 3 but the head is seq 5",
+		},
+		{name: "empty log with head", opts: audit.VerifyOptions{Head: &head}, wantErr: "log ends at seq 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := audit.Verify(strings.NewReader(strings.Join(tt.lines, "\n")), testKey, tt.opts)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantRecs, count)
+		})
+	}
+}
+
+func TestVerify_RequiresKey(t *testing.T) {
+	lines := writeChain(t, 2)
+
+	_, err := audit.Verify(strings.NewReader(strings.Join(lines, "\n")), []byte("another key entirely"), audit.VerifyOptions{})
+	var chainErr *audit.ChainError
+	require.ErrorAs(t, err, &chainErr)
+	assert.Equal(t, 1, chainErr.Line)
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	tests := []struct {
+		tamper   func(lines []string) []string
+		name     string
+		wantLine int
+	}{
+		{
+			name: "modified record",
+			tamper: func(lines []string) []string {
+				lines[2] = strings.Replace(lines[2], `"actor":"alice"`, `"actor":"mallory"`, 1)
+				return lines
+			},
+			wantLine: 3,
+		},
+		{
+			name: "deleted record",
+			tamper: func(lines []string) []string {
+				return append(lines[:2], lines[3:]...)
+			},
+			wantLine: 3,
+		},
+		{
+			name: "reordered records",
+			tamper: func(lines []string) []string {
+				lines[1], lines[2] = lines[2], lines[1]
+				return lines
+			},
+			wantLine: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := tt.tamper(writeChain(t, 5))
+
+			_, err := audit.Verify(strings.NewReader(strings.Join(lines, "\n")), testKey, audit.VerifyOptions{})
+
+			var chainErr *audit.ChainError
+			require.ErrorAs(t, err, &chainErr)
+			assert.Equal(t, tt.wantLine, chainErr.Line)
+		})
+	}
+}
+
+func TestNew_FileSinkResumesChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	cfg := config.AuditConfig{Enabled: true, Sink: "file", Path: path, Key: testKeyHex}
+
+	for i := 0; i < 2; i++ {
+		logger, err := audit.New(cfg)
+		require.NoError(t, err)
+		require.NoError(t, logger.Log(context.Background(), audit.Event{Type: audit.EventConfigReload, Outcome: audit.OutcomeSuccess}))
+		require.NoError(t, logger.Log(context.Background(), audit.Event{Type: audit.EventAuthSuccess, Outcome: audit.OutcomeSuccess}))
+		require.NoError(t, logger.Close())
+	}
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = f.Close() })
+
+	head, err := audit.ReadHead(audit.HeadPath(path))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), head.Seq)
+
+	count, err := audit.Verify(f, testKey, audit.VerifyOptions{Head: &head})
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+}
+
+func TestNew_FileSinkRecoversFromCrash(t *testing.T) {
+	tests := []struct {
+		crash func(t *testing.T, path string, staleHead []byte)
+		name  string
+	}{
+		{
+			name: "head not yet updated",
+			crash: func(t *testing.T, path string, staleHead []byte) {
+				require.NoError(t, os.WriteFile(audit.HeadPath(path), staleHead, 0o600))
+			},
+		},
+		{
+			name: "record cut short",
+			crash: func(t *testing.T, path string, _ []byte) {
+				f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+				require.NoError(t, err)
+				_, err = f.WriteString(`{"record":{"time":"2025-`)
+				require.NoError(t, err)
+				require.NoError(t, f.Close())
+			},
+		},
+		{
+			name: "head not yet updated and record cut short",
+			crash: func(t *testing.T, path string, staleHead []byte) {
+				require.NoError(t, os.WriteFile(audit.HeadPath(path), staleHead, 0o600))
+				f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+				require.NoError(t, err)
+				_, err = f.WriteString(`{"rec`)
+				require.NoError(t, err)
+				require.NoError(t, f.Close())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.log")
+			cfg := config.AuditConfig{Enabled: true, Path: path, Key: testKeyHex}
+			event := audit.Event{Type: audit.EventConfigReload, Outcome: audit.OutcomeSuccess}
+
+			logger, err := audit.New(cfg)
+			require.NoError(t, err)
+			require.NoError(t, logger.Log(context.Background(), event))
+			staleHead, err := os.ReadFile(audit.HeadPath(path))
+			require.NoError(t, err)
+			require.NoError(t, logger.Log(context.Background(), event))
+			require.NoError(t, logger.Close())
+
+			tt.crash(t, path, staleHead)
+
+			logger, err = audit.New(cfg)
+			require.NoError(t, err, "a crash must not block startup")
+			require.NoError(t, logger.Log(context.Background(), event))
+			require.NoError(t, logger.Close())
+
+			head, err := audit.ReadHead(audit.HeadPath(path))
+			require.NoError(t, err)
+			assert.Equal(t, uint64(3), head.Seq)
+
+			f, err := os.Open(path)
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = f.Close() })
+			count, err := audit.Verify(f, testKey, audit.VerifyOptions{Head: &head})
+			require.NoError(t, err)
+			assert.Equal(t, 3, count)
+		})
+	}
+}
+
+func TestNew_FileSinkDetectsTampering(t *testing.T) {
+	tests := []struct {
+		tamper  func(t *testing.T, path string)
+		name    string
+		key     string
+		wantErr string
+	}{
+		{
+			name: "truncated log",
+			tamper: func(t *testing.T, path string) {
+				data, err := os.ReadFile(path)
+				require.NoError(t, err)
+				lines := strings.SplitAfter(string(data), "\n")
+				require.NoError(t, os.WriteFile(path, []byte(lines[0]), 0o600))
+			},
+			wantErr: "ends at seq 1 but its head is seq 2",
+		},
+		{
+			name: "record replayed after the head",
+			tamper: func(t *testing.T, path string) {
+				data, err := os.ReadFile(path)
+				require.NoError(t, err)
+				lines := strings.SplitAfter(string(data), "\n")
+				require.NoError(t, os.WriteFile(path, []byte(lines[0]+lines[1]+lines[1]), 0o600))
+			},
+			wantErr: "does not continue the chain",
+		},
+		{
+			name: "head from another log",
+			tamper: func(t *testing.T, path string) {
+				require.NoError(t, os.WriteFile(audit.HeadPath(path), []byte(`{"hash":"00","seq":2}`), 0o600))
+			},
+			wantErr: "does not match its head",
+		},
+		{
+			name:    "wrong key",
+			tamper:  func(*testing.T, string) {},
+			key:     strings.Repeat("ab", 32),
+			wantErr: "does not match the audit key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.log")
+			cfg := config.AuditConfig{Enabled: true, Path: path, Key: testKeyHex}
+
+			logger, err := audit.New(cfg)
+			require.NoError(t, err)
+			for i := 0; i < 2; i++ {
+				require.NoError(t, logger.Log(context.Background(), audit.Event{Type: audit.EventConfigReload, Outcome: audit.OutcomeSuccess}))
+			}
+			require.NoError(t, logger.Close())
+
+			tt.tamper(t, path)
+			if tt.key != "" {
+				cfg.Key = tt.key
+			}
+			_, err = audit.New(cfg)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestNew_RequiresKey(t *testing.T) {
+	for _, key := range []string{"", "not hex", "abcd"} {
+		_, err := audit.New(config.AuditConfig{Enabled: true, Sink: "stdout", Key: key})
+		assert.Error(t, err, "key %q", key)
+	}
+}
+
+func TestNew_Disabled(t *testing.T) {
+	logger, err := audit.New(config.AuditConfig{})
+	require.NoError(t, err)
+
+	assert.NoError(t, logger.Log(context.Background(), audit.Event{Type: audit.EventAuthSuccess}))
+	assert.NoError(t, logger.Close())
+}
@@ -0,0 +1,74 @@
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// genesisHash is the prev_hash of the first record in a chain.
+const genesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// minKeyBytes is the shortest chain key accepted.
+const minKeyBytes = 16
+
+// line is the on-disk format of one record. The hash covers the exact bytes
+// of Record, so verification never depends on re-encoding.
+type line struct {
+	Record json.RawMessage `json:"record"`
+	Hash   string          `json:"hash"`
+}
+
+// hashRecord returns the HMAC-SHA256 of raw under key. Without the key,
+// whoever can write the log cannot recompute the chain after editing it.
+func hashRecord(key, raw []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(raw)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ParseKey decodes a hex encoded chain key, as generated by
+// signing.GenerateSecret.
+func ParseKey(s string) ([]byte, error) {
+	if s == "" {
+		return nil, errors.New("audit key is not set")
+	}
+	key, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("audit key must be hex encoded: %w", err)
+	}
+	if len(key) < minKeyBytes {
+		return nil, fmt.Errorf("audit key must be at least %d bytes", minKeyBytes)
+	}
+	return key, nil
+}
+
+// Checkpoint is the position of a record in a chain: its sequence number
+// and hash. The head of a log is the checkpoint of its last record.
+type Checkpoint struct {
+	Hash string `json:"hash"`
+	Seq  uint64 `json:"seq"`
+}
+
+// String formats c as "seq:hash", the form ParseCheckpoint reads.
+func (c Checkpoint) String() string {
+	return strconv.FormatUint(c.Seq, 10) + ":" + c.Hash
+}
+
+// ParseCheckpoint parses a checkpoint written as "seq:hash".
+func ParseCheckpoint(s string) (Checkpoint, error) {
+	seq, hash, ok := strings.Cut(s, ":")
+	if !ok {
+		return Checkpoint{}, fmt.Errorf("checkpoint %q must be seq:hash", s)
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return Checkpoint{}, fmt.Errorf("checkpoint %q has an invalid seq: %w", s, err)
+	}
+	return Checkpoint{Seq: n, Hash: hash}, nil
+}
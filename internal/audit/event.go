@@ -0,0 +1,44 @@
+package audit
+
+import "time"
+
+// EventType identifies the kind of security-relevant event being recorded.
+type EventType string
+
+const (
+	EventAuthSuccess  EventType = "auth.success"
+	EventAuthFailure  EventType = "auth.failure"
+	EventAuthDecision EventType = "auth.decision"
+	EventConfigReload EventType = "config.reload"
+)
+
+// Outcome is the result of the audited action.
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeFailure Outcome = "failure"
+	OutcomeAllow   Outcome = "allow"
+	OutcomeDeny    Outcome = "deny"
+)
+
+// Event is a single audit entry as supplied by callers.
+type Event struct {
+	Details   map[string]any `json:"details,omitempty"`
+	Type      EventType      `json:"type"`
+	Outcome   Outcome        `json:"outcome"`
+	Actor     string         `json:"actor,omitempty"`
+	Action    string         `json:"action,omitempty"`
+	Resource  string         `json:"resource,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+	SourceIP  string         `json:"source_ip,omitempty"`
+}
+
+// Record is an Event placed in the hash chain.
+type Record struct {
+	Time     time.Time `json:"time"`
+	Stream   string    `json:"stream"`
+	PrevHash string    `json:"prev_hash"`
+	Event
+	Seq uint64 `json:"seq"`
+}
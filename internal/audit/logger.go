@@ -0,0 +1,310 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/savisec/hello-go/internal/config"
+)
+
+const (
+	sinkStdout = "stdout"
+	sinkFile   = "file"
+
+	streamName = "audit"
+
+	// headSuffix names the file, next to a file sink, that holds the
+	// checkpoint of the last record written.
+	headSuffix = ".head"
+)
+
+// Logger appends hash-chained audit records to a dedicated sink.
+type Logger struct {
+	w        io.Writer
+	closer   io.Closer
+	now      func() time.Time
+	prevHash string
+	headPath string
+	key      []byte
+	seq      uint64
+	mu       sync.Mutex
+}
+
+// New creates a Logger from config. A disabled config yields a Logger that
+// discards every event.
+func New(cfg config.AuditConfig) (*Logger, error) {
+	if !cfg.Enabled {
+		return NewLogger(nil, nil), nil
+	}
+
+	key, err := ParseKey(cfg.Key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid audit config: %w", err)
+	}
+
+	switch strings.ToLower(cfg.Sink) {
+	case "", sinkFile:
+		return openFile(cfg.Path, key)
+	case sinkStdout:
+		return NewLogger(os.Stdout, key), nil
+	default:
+		return nil, fmt.Errorf("unknown audit sink %q", cfg.Sink)
+	}
+}
+
+// NewLogger creates a Logger that starts a new chain on w, keyed by key. A
+// nil writer discards every event.
+func NewLogger(w io.Writer, key []byte) *Logger {
+	return &Logger{
+		w:        w,
+		now:      time.Now,
+		prevHash: genesisHash,
+		key:      key,
+	}
+}
+
+// HeadPath returns the path of the head file kept next to the audit log at
+// path.
+func HeadPath(path string) string {
+	return path + headSuffix
+}
+
+// openFile opens path for appending and continues the chain from its last
+// record, if any. The log must reach the head recorded next to it, so a
+// log cut short is noticed at startup rather than extended. Records past
+// the head, left by a crash between writing a record and its head, are
+// accepted when they continue the chain, and the head is brought up to
+// date.
+func openFile(path string, key []byte) (*Logger, error) {
+	if path == "" {
+		return nil, errors.New("audit file sink requires a path")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	var head *Checkpoint
+	stored, err := ReadHead(HeadPath(path))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		head = &stored
+	}
+
+	tail, err := recoverTail(path, key, head)
+	if err != nil {
+		return nil, err
+	}
+	if head == nil || *head != tail {
+		if err := writeHead(HeadPath(path), tail); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	l := NewLogger(f, key)
+	l.closer = f
+	l.headPath = HeadPath(path)
+	l.seq = tail.Seq
+	l.prevHash = tail.Hash
+
+	return l, nil
+}
+
+// ReadHead reads the checkpoint stored in a head file.
+func ReadHead(path string) (Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Checkpoint{}, fmt.Errorf("failed to read audit head: %w", err)
+	}
+
+	var head Checkpoint
+	if err := json.Unmarshal(data, &head); err != nil {
+		return Checkpoint{}, fmt.Errorf("failed to parse audit head: %w", err)
+	}
+
+	return head, nil
+}
+
+// writeHead replaces the head file with head. It writes a temporary file
+// and renames it over the head, so the head is never seen half-written.
+func writeHead(path string, head Checkpoint) error {
+	data, err := json.Marshal(head)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit head: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write audit head: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write audit head: %w", err)
+	}
+
+	return nil
+}
+
+// recoverTail returns the checkpoint of the last record in path. When head
+// is known, the log must contain the head record, and every record after
+// it must be written with key and continue the chain. Without a head only
+// the last record is checked against key. A final line without a newline
+// is a record a crash cut short; no head can cover it, so it is removed.
+func recoverTail(path string, key []byte, head *Checkpoint) (Checkpoint, error) {
+	genesis := Checkpoint{Hash: genesisHash}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if head != nil && *head != genesis {
+			return Checkpoint{}, fmt.Errorf("audit log is missing but its head is seq %d", head.Seq)
+		}
+		return genesis, nil
+	}
+	if err != nil {
+		return Checkpoint{}, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	//nolint:errcheck
+	defer f.Close()
+
+	// Records are followed from the head, or from genesis when the head is
+	// at genesis; without a head, from whatever the first record is.
+	tail := genesis
+	found := head == nil || *head == genesis
+	var last line
+	var complete int64
+	var torn bool
+
+	reader := bufio.NewReader(f)
+	for {
+		data, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			torn = len(bytes.TrimSpace(data)) > 0
+			break
+		}
+		if err != nil {
+			return Checkpoint{}, fmt.Errorf("failed to read audit log: %w", err)
+		}
+		complete += int64(len(data))
+
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 {
+			continue
+		}
+
+		var l line
+		var rec Record
+		if err := json.Unmarshal(data, &l); err != nil {
+			return Checkpoint{}, fmt.Errorf("failed to parse audit record after seq %d: %w", tail.Seq, err)
+		}
+		if err := json.Unmarshal(l.Record, &rec); err != nil {
+			return Checkpoint{}, fmt.Errorf("failed to parse audit record after seq %d: %w", tail.Seq, err)
+		}
+
+		switch {
+		case head == nil:
+			// Only the last record is checked, below.
+		case !found:
+			if rec.Seq != head.Seq {
+				tail = Checkpoint{Seq: rec.Seq, Hash: l.Hash}
+				continue
+			}
+			if l.Hash != head.Hash {
+				return Checkpoint{}, fmt.Errorf("audit record %d does not match its head", rec.Seq)
+			}
+			if hashRecord(key, l.Record) != l.Hash {
+				return Checkpoint{}, fmt.Errorf("audit record %d does not match the audit key", rec.Seq)
+			}
+			found = true
+		default:
+			if hashRecord(key, l.Record) != l.Hash {
+				return Checkpoint{}, fmt.Errorf("audit record %d does not match the audit key", rec.Seq)
+			}
+			if rec.Seq != tail.Seq+1 || rec.PrevHash != tail.Hash {
+				return Checkpoint{}, fmt.Errorf("audit record %d does not continue the chain from seq %d", rec.Seq, tail.Seq)
+			}
+		}
+		tail = Checkpoint{Seq: rec.Seq, Hash: l.Hash}
+		last = l
+	}
+
+	if !found {
+		return Checkpoint{}, fmt.Errorf("audit log ends at seq %d but its head is seq %d; it may have been truncated", tail.Seq, head.Seq)
+	}
+	if head == nil && tail != genesis && hashRecord(key, last.Record) != last.Hash {
+		return Checkpoint{}, errors.New("last audit record does not match the audit key")
+	}
+
+	if torn {
+		if err := os.Truncate(path, complete); err != nil {
+			return Checkpoint{}, fmt.Errorf("failed to remove partial audit record: %w", err)
+		}
+	}
+
+	return tail, nil
+}
+
+// Log appends event to the chain.
+func (l *Logger) Log(_ context.Context, event Event) error {
+	if l.w == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rec := Record{
+		Time:     l.now().UTC(),
+		Stream:   streamName,
+		Seq:      l.seq + 1,
+		PrevHash: l.prevHash,
+		Event:    event,
+	}
+
+	raw, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+
+	hash := hashRecord(l.key, raw)
+	out, err := json.Marshal(line{Record: raw, Hash: hash})
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+
+	if _, err := l.w.Write(append(out, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+
+	l.seq = rec.Seq
+	l.prevHash = hash
+
+	if l.headPath != "" {
+		return writeHead(l.headPath, Checkpoint{Seq: l.seq, Hash: hash})
+	}
+	return nil
+}
+
+// Close releases the underlying file, if any.
+func (l *Logger) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
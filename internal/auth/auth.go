@@ -0,0 +1,123 @@
+// Package auth authenticates API callers, by API key or JWT, and checks
+// the scopes they were granted.
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/savisec/hello-go/internal/lambdactx"
+	"github.com/savisec/hello-go/internal/operation"
+)
+
+// AuthorizerAPIKey is the lambdactx.Identity.Authorizer of API key callers.
+const AuthorizerAPIKey = "api-key"
+
+// TenantClaim is the lambdactx.Identity.Claims entry naming the tenant of
+// an API key or signing key owner.
+const TenantClaim = "tenant"
+
+// ErrInvalidCredentials is returned for a malformed, unknown or wrong key.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Authenticator verifies API keys against a KeyStore and, if configured,
+// JWT bearer tokens.
+type Authenticator struct {
+	store KeyStore
+	jwt   *JWTValidator
+}
+
+// NewAuthenticator creates an Authenticator. A nil validator disables JWT
+// bearer tokens.
+func NewAuthenticator(store KeyStore, validator *JWTValidator) *Authenticator {
+	return &Authenticator{store: store, jwt: validator}
+}
+
+// AuthenticateBearer verifies a bearer token, which is either a JWT or an
+// API key.
+func (a *Authenticator) AuthenticateBearer(ctx context.Context, token string) (*lambdactx.Identity, error) {
+	if a.jwt != nil && LooksLikeJWT(token) {
+		return a.jwt.Validate(ctx, token)
+	}
+	return a.Authenticate(ctx, token)
+}
+
+// Authenticate verifies the raw "<id>.<secret>" key and returns the
+// identity it belongs to.
+func (a *Authenticator) Authenticate(ctx context.Context, raw string) (*lambdactx.Identity, error) {
+	if a.store == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	id, secret, ok := ParseKey(raw)
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	key, err := a.store.Lookup(ctx, id)
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up api key: %w", err)
+	}
+
+	salt, err := hex.DecodeString(key.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode salt of api key %q: %w", key.ID, err)
+	}
+	if subtle.ConstantTimeCompare([]byte(HashSecret(salt, secret)), []byte(key.Hash)) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+
+	claims := map[string]string{"key_id": key.ID}
+	if key.Tenant != "" {
+		claims[TenantClaim] = key.Tenant
+	}
+	return &lambdactx.Identity{
+		Subject:    key.Principal,
+		Scopes:     key.Scopes,
+		Authorizer: AuthorizerAPIKey,
+		Claims:     claims,
+	}, nil
+}
+
+// Allowed reports whether identity, authenticated by the named security
+// scheme, meets any of the requirements. A requirement is only met through
+// a scheme it lists. It also returns the scopes of the first requirement
+// the scheme could meet, for use in error responses.
+func Allowed(identity *lambdactx.Identity, scheme string, requirements []operation.Requirement) (bool, []string) {
+	if len(requirements) == 0 {
+		return true, nil
+	}
+
+	var scopes []string
+	for i, req := range requirements {
+		if !slices.Contains(req.Schemes, scheme) {
+			continue
+		}
+		if hasScopes(identity, req.Scopes) {
+			return true, nil
+		}
+		if scopes == nil {
+			scopes = requirements[i].Scopes
+		}
+	}
+	if scopes == nil {
+		scopes = requirements[0].Scopes
+	}
+	return false, scopes
+}
+
+func hasScopes(identity *lambdactx.Identity, scopes []string) bool {
+	for _, scope := range scopes {
+		if identity == nil || !slices.Contains(identity.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/internal/auth"
+	"github.com/savisec/hello-go/internal/config"
+	"github.com/savisec/hello-go/internal/lambdactx"
+	"github.com/savisec/hello-go/internal/operation"
+)
+
+func newAuthenticator(t *testing.T) (*auth.Authenticator, string) {
+	t.Helper()
+
+	raw, key, err := auth.GenerateKey("ci", "ci-bot", []string{"echo:write"})
+	require.NoError(t, err)
+
+	store, err := auth.NewConfigKeyStore([]config.APIKeyConfig{{
+		ID:        key.ID,
+		Principal: key.Principal,
+		Scopes:    key.Scopes,
+		Salt:      key.Salt,
+		Hash:      key.Hash,
+	}})
+	require.NoError(t, err)
+
+	return auth.NewAuthenticator(store, nil), raw
+}
+
+func TestAuthenticator_Authenticate(t *testing.T) {
+	authenticator, raw := newAuthenticator(t)
+
+	identity, err := authenticator.Authenticate(context.Background(), raw)
+	require.NoError(t, err)
+	assert.Equal(t, &lambdactx.Identity{
+		Subject:    "ci-bot",
+		Scopes:     []string{"echo:write"},
+		Authorizer: auth.AuthorizerAPIKey,
+		Claims:     map[string]string{"key_id": "ci"},
+	}, identity)
+
+	for _, bad := range []string{"", "ci", "ci.", "ci.wrong-secret", "other" + raw[2:]} {
+		_, err := authenticator.Authenticate(context.Background(), bad)
+		assert.ErrorIs(t, err, auth.ErrInvalidCredentials, "key %q", bad)
+	}
+}
+
+func TestNewConfigKeyStore_Validates(t *testing.T) {
+	_, key, err := auth.GenerateKey("ci", "", nil)
+	require.NoError(t, err)
+
+	valid := config.APIKeyConfig{ID: key.ID, Salt: key.Salt, Hash: key.Hash}
+
+	tests := []struct {
+		name string
+		keys []config.APIKeyConfig
+	}{
+		{name: "missing id", keys: []config.APIKeyConfig{{Salt: key.Salt, Hash: key.Hash}}},
+		{name: "id with separator", keys: []config.APIKeyConfig{{ID: "a.b", Salt: key.Salt, Hash: key.Hash}}},
+		{name: "duplicate id", keys: []config.APIKeyConfig{valid, valid}},
+		{name: "bad salt", keys: []config.APIKeyConfig{{ID: "ci", Salt: "zz", Hash: key.Hash}}},
+		{name: "short hash", keys: []config.APIKeyConfig{{ID: "ci", Salt: key.Salt, Hash: "abcd"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := auth.NewConfigKeyStore(tt.keys)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	requirements := []operation.Requirement{
+		{Schemes: []string{"bearerAuth"}, Scopes: []string{"echo:write"}},
+		{Schemes: []string{"apiKeyAuth"}, Scopes: []string{"echo:admin"}},
+	}
+
+	tests := []struct {
+		identity   *lambdactx.Identity
+		name       string
+		scheme     string
+		wantScopes []string
+		want       bool
+	}{
+		{name: "first alternative", scheme: "bearerAuth", identity: &lambdactx.Identity{Scopes: []string{"echo:write"}}, want: true},
+		{name: "second alternative", scheme: "apiKeyAuth", identity: &lambdactx.Identity{Scopes: []string{"echo:admin"}}, want: true},
+		{name: "no matching scope", scheme: "bearerAuth", identity: &lambdactx.Identity{Scopes: []string{"echo:read"}}, wantScopes: []string{"echo:write"}},
+		{name: "scope of another scheme", scheme: "bearerAuth", identity: &lambdactx.Identity{Scopes: []string{"echo:admin"}}, wantScopes: []string{"echo:write"}},
+		{name: "scopes reported for the scheme", scheme: "apiKeyAuth", identity: &lambdactx.Identity{Scopes: []string{"echo:write"}}, wantScopes: []string{"echo:admin"}},
+		{name: "scheme not listed", scheme: "hmacSignature", identity: &lambdactx.Identity{Scopes: []string{"echo:write", "echo:admin"}}, wantScopes: []string{"echo:write"}},
+		{name: "no identity", scheme: "bearerAuth", wantScopes: []string{"echo:write"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, scopes := auth.Allowed(tt.identity, tt.scheme, requirements)
+			assert.Equal(t, tt.want, allowed)
+			assert.Equal(t, tt.wantScopes, scopes)
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/savisec/hello-go/internal/config"
+)
+
+const (
+	defaultJWKSRefreshInterval = 15 * time.Minute
+	defaultJWKSRefreshBackoff  = 30 * time.Second
+
+	// jwksFetchTimeout bounds a refresh, and the HTTP client's requests. A
+	// refresh runs detached from the request that started it, so that
+	// requests waiting on it share its outcome.
+	jwksFetchTimeout = 10 * time.Second
+
+	// maxJWKSBytes bounds the size of a fetched key set.
+	maxJWKSBytes = 1 << 20
+)
+
+// ErrUnknownKey is returned when the key set has no key with the given ID.
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// jwk is a JSON Web Key as published in a JWKS.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey is a verification key and, if the JWKS pins one, its algorithm.
+type publicKey struct {
+	key crypto.PublicKey
+	alg string
+}
+
+// JWKS caches the signing keys of a JSON Web Key Set read from a URL or a
+// local file. Keys are refreshed every refresh interval, and early when a
+// token names a key ID the cache does not know, which is how a rotated-in
+// key is picked up. Refreshes are at least the refresh backoff apart, and
+// that gap doubles with each consecutive failure up to the refresh
+// interval. While refreshes fail, the last good keys stay in use.
+//
+// A refresh runs outside the lock, once for all the requests waiting on
+// it, and on its own timeout: a request that gives up stops waiting but
+// neither cancels the refresh nor counts as a failure.
+type JWKS struct {
+	fetch           func(ctx context.Context) ([]byte, error)
+	now             func() time.Time
+	keys            map[string]publicKey
+	fetchedAt       time.Time
+	nextAttempt     time.Time
+	refreshes       singleflight.Group
+	refreshInterval time.Duration
+	refreshBackoff  time.Duration
+	failures        int
+	mu              sync.Mutex
+}
+
+// NewJWKS creates a JWKS for cfg.JWKSURL or cfg.JWKSFile. Keys are first
+// fetched on demand.
+func NewJWKS(cfg config.JWTConfig) (*JWKS, error) {
+	client := &http.Client{Timeout: jwksFetchTimeout}
+
+	var fetch func(ctx context.Context) ([]byte, error)
+	switch {
+	case cfg.JWKSURL != "" && cfg.JWKSFile != "":
+		return nil, errors.New("only one of jwks_url and jwks_file may be set")
+	case cfg.JWKSURL != "":
+		fetch = func(ctx context.Context) ([]byte, error) {
+			return fetchURL(ctx, client, cfg.JWKSURL)
+		}
+	case cfg.JWKSFile != "":
+		fetch = func(context.Context) ([]byte, error) {
+			return os.ReadFile(cfg.JWKSFile)
+		}
+	default:
+		return nil, errors.New("one of jwks_url and jwks_file is required")
+	}
+
+	refreshInterval := cfg.RefreshInterval
+	if refreshInterval <= 0 {
+		refreshInterval = defaultJWKSRefreshInterval
+	}
+	refreshBackoff := cfg.RefreshBackoff
+	if refreshBackoff <= 0 {
+		refreshBackoff = defaultJWKSRefreshBackoff
+	}
+
+	return &JWKS{
+		fetch:           fetch,
+		now:             time.Now,
+		refreshInterval: refreshInterval,
+		refreshBackoff:  refreshBackoff,
+	}, nil
+}
+
+func fetchURL(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	//nolint:errcheck
+	//goland:noinspection GoUnhandledErrorResult
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxJWKSBytes))
+}
+
+// Key returns the key with the given ID. An empty kid matches the only key
+// of a single-key set. If ctx ends while a refresh is under way, a stale
+// key is returned if there is one, and ctx's error otherwise.
+func (j *JWKS) Key(ctx context.Context, kid string) (crypto.PublicKey, string, error) {
+	j.mu.Lock()
+	now := j.now()
+	key, known := j.lookup(kid)
+	stale := j.keys == nil || now.Sub(j.fetchedAt) >= j.refreshInterval
+	due := (stale || !known) && !now.Before(j.nextAttempt)
+	j.mu.Unlock()
+
+	var refreshErr error
+	if due {
+		select {
+		case res := <-j.refreshes.DoChan("", func() (any, error) { return nil, j.refresh() }):
+			refreshErr = res.Err
+		case <-ctx.Done():
+			if known {
+				return key.key, key.alg, nil
+			}
+			return nil, "", ctx.Err()
+		}
+
+		j.mu.Lock()
+		key, known = j.lookup(kid)
+		j.mu.Unlock()
+	}
+
+	if known {
+		return key.key, key.alg, nil
+	}
+	if refreshErr != nil {
+		return nil, "", refreshErr
+	}
+	return nil, "", ErrUnknownKey
+}
+
+// lookup must be called with j.mu held.
+func (j *JWKS) lookup(kid string) (publicKey, bool) {
+	if kid == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key, true
+		}
+	}
+	key, ok := j.keys[kid]
+	return key, ok
+}
+
+// refresh fetches the keys, without holding j.mu, and records the outcome.
+func (j *JWKS) refresh() error {
+	ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+	defer cancel()
+	keys, err := j.load(ctx)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := j.now()
+	if err != nil {
+		j.failures++
+		backoff := j.refreshBackoff << min(j.failures-1, 16)
+		j.nextAttempt = now.Add(min(backoff, j.refreshInterval))
+		return fmt.Errorf("failed to refresh JWKS: %w", err)
+	}
+
+	j.keys = keys
+	j.fetchedAt = now
+	j.failures = 0
+	j.nextAttempt = now.Add(j.refreshBackoff)
+	return nil
+}
+
+func (j *JWKS) load(ctx context.Context) (map[string]publicKey, error) {
+	data, err := j.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]publicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode key %q: %w", k.Kid, err)
+		}
+		if key == nil {
+			// Key types we cannot verify with are skipped, not fatal.
+			continue
+		}
+		keys[k.Kid] = publicKey{key: key, alg: k.Alg}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS contains no usable signing keys")
+	}
+	return keys, nil
+}
+
+// publicKey decodes k, returning nil for unsupported key types.
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("RSA exponent out of range")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, nil
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("invalid P-256 coordinates")
+		}
+		return ecdsa.ParseUncompressedPublicKey(elliptic.P256(), append(append([]byte{4}, x...), y...))
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, nil
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, nil
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,316 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/internal/config"
+)
+
+// jwksServer serves a JWKS whose keys and availability tests can change.
+type jwksServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	keys     []map[string]string
+	failing  bool
+	requests int
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	t.Helper()
+
+	s := &jwksServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.requests++
+		if s.failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": s.keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) setKeys(keys ...map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+func (s *jwksServer) setFailing(failing bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failing = failing
+}
+
+func (s *jwksServer) requestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// testClock is a settable time source for the JWKS and validator.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func (c *testClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func encodeSegment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// publicJWK encodes the public half of key as a JWK.
+func publicJWK(t *testing.T, kid, alg string, key crypto.Signer) map[string]string {
+	t.Helper()
+
+	switch pub := key.Public().(type) {
+	case *rsa.PublicKey:
+		return map[string]string{
+			"kty": "RSA", "kid": kid, "alg": alg, "use": "sig",
+			"n": encodeSegment(pub.N.Bytes()),
+			"e": encodeSegment(big.NewInt(int64(pub.E)).Bytes()),
+		}
+	case *ecdsa.PublicKey:
+		point, err := pub.Bytes()
+		require.NoError(t, err)
+		return map[string]string{
+			"kty": "EC", "kid": kid, "alg": alg, "crv": "P-256",
+			"x": encodeSegment(point[1:33]),
+			"y": encodeSegment(point[33:]),
+		}
+	case ed25519.PublicKey:
+		return map[string]string{
+			"kty": "OKP", "kid": kid, "alg": alg, "crv": "Ed25519",
+			"x": encodeSegment(pub),
+		}
+	}
+	t.Fatalf("unsupported key type %T", key)
+	return nil
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+func newECKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func newEd25519Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func newTestJWKS(t *testing.T, cfg config.JWTConfig, clock *testClock) *JWKS {
+	t.Helper()
+	keys, err := NewJWKS(cfg)
+	require.NoError(t, err)
+	keys.now = clock.Now
+	return keys
+}
+
+func TestNewJWKS_RequiresOneSource(t *testing.T) {
+	_, err := NewJWKS(config.JWTConfig{})
+	assert.Error(t, err)
+
+	_, err = NewJWKS(config.JWTConfig{JWKSURL: "https://example.com/jwks.json", JWKSFile: "jwks.json"})
+	assert.Error(t, err)
+}
+
+func TestJWKS_PicksUpRotatedKeys(t *testing.T) {
+	server := newJWKSServer(t)
+	server.setKeys(publicJWK(t, "old", "RS256", newRSAKey(t)))
+
+	clock := &testClock{now: time.Unix(1_700_000_000, 0)}
+	keys := newTestJWKS(t, config.JWTConfig{JWKSURL: server.URL, RefreshBackoff: time.Minute}, clock)
+
+	_, alg, err := keys.Key(context.Background(), "old")
+	require.NoError(t, err)
+	assert.Equal(t, "RS256", alg)
+
+	server.setKeys(publicJWK(t, "new", "ES256", newECKey(t)))
+
+	// An unknown kid does not refetch within the backoff of the last fetch.
+	_, _, err = keys.Key(context.Background(), "new")
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	assert.Equal(t, 1, server.requestCount())
+
+	clock.Advance(time.Minute)
+	_, alg, err = keys.Key(context.Background(), "new")
+	require.NoError(t, err)
+	assert.Equal(t, "ES256", alg)
+	assert.Equal(t, 2, server.requestCount())
+
+	_, _, err = keys.Key(context.Background(), "old")
+	assert.ErrorIs(t, err, ErrUnknownKey, "rotated-out keys are dropped")
+}
+
+func TestJWKS_BacksOffAndKeepsStaleKeys(t *testing.T) {
+	server := newJWKSServer(t)
+	server.setKeys(publicJWK(t, "k1", "EdDSA", newEd25519Key(t)))
+
+	clock := &testClock{now: time.Unix(1_700_000_000, 0)}
+	keys := newTestJWKS(t, config.JWTConfig{
+		JWKSURL:         server.URL,
+		RefreshInterval: 10 * time.Minute,
+		RefreshBackoff:  time.Minute,
+	}, clock)
+
+	_, _, err := keys.Key(context.Background(), "k1")
+	require.NoError(t, err)
+
+	server.setFailing(true)
+	clock.Advance(10 * time.Minute)
+
+	// The keys are stale and the refresh fails, but k1 is still served.
+	_, _, err = keys.Key(context.Background(), "k1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, server.requestCount())
+
+	// An unknown kid surfaces the refresh failure rather than ErrUnknownKey.
+	clock.Advance(time.Minute)
+	_, _, err = keys.Key(context.Background(), "k2")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrUnknownKey)
+	assert.Equal(t, 3, server.requestCount())
+
+	// The second consecutive failure doubles the backoff to two minutes.
+	clock.Advance(time.Minute)
+	_, _, err = keys.Key(context.Background(), "k1")
+	require.NoError(t, err)
+	assert.Equal(t, 3, server.requestCount())
+
+	server.setFailing(false)
+	clock.Advance(time.Minute)
+	_, _, err = keys.Key(context.Background(), "k1")
+	require.NoError(t, err)
+	assert.Equal(t, 4, server.requestCount())
+}
+
+// blockingJWKS returns a JWKS whose fetches wait for release, and a count
+// of the fetches started.
+func blockingJWKS(t *testing.T, data []byte) (*JWKS, chan struct{}, *atomic.Int32) {
+	t.Helper()
+
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	keys := newTestJWKS(t, config.JWTConfig{JWKSFile: "unused"}, &testClock{now: time.Unix(1_700_000_000, 0)})
+	keys.fetch = func(ctx context.Context) ([]byte, error) {
+		fetches.Add(1)
+		select {
+		case <-release:
+			return data, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return keys, release, &fetches
+}
+
+func TestJWKS_SharesRefreshes(t *testing.T) {
+	data, err := json.Marshal(map[string]any{"keys": []map[string]string{publicJWK(t, "k1", "EdDSA", newEd25519Key(t))}})
+	require.NoError(t, err)
+	keys, release, fetches := blockingJWKS(t, data)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for range 10 {
+		wg.Go(func() {
+			_, _, err := keys.Key(context.Background(), "k1")
+			errs <- err
+		})
+	}
+	assert.Eventually(t, func() bool { return fetches.Load() == 1 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), fetches.Load(), "waiting requests share one fetch")
+}
+
+func TestJWKS_CallerCancellation(t *testing.T) {
+	data, err := json.Marshal(map[string]any{"keys": []map[string]string{publicJWK(t, "k1", "EdDSA", newEd25519Key(t))}})
+	require.NoError(t, err)
+	keys, release, fetches := blockingJWKS(t, data)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, _, err := keys.Key(ctx, "k1")
+		done <- err
+	}()
+	assert.Eventually(t, func() bool { return fetches.Load() == 1 }, time.Second, time.Millisecond)
+
+	// The caller stops waiting, but the fetch carries on for later requests.
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	close(release)
+
+	assert.Eventually(t, func() bool {
+		_, _, err := keys.Key(context.Background(), "k1")
+		return err == nil
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(1), fetches.Load(), "the cancelled caller neither restarted nor backed off the fetch")
+}
+
+func TestJWKS_File(t *testing.T) {
+	key := newEd25519Key(t)
+	data, err := json.Marshal(map[string]any{"keys": []map[string]string{
+		publicJWK(t, "file", "EdDSA", key),
+		// Encryption keys and unsupported curves are skipped.
+		{"kty": "RSA", "kid": "enc", "use": "enc"},
+		{"kty": "EC", "kid": "p384", "crv": "P-384"},
+	}})
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	keys := newTestJWKS(t, config.JWTConfig{JWKSFile: path}, &testClock{now: time.Now()})
+
+	got, _, err := keys.Key(context.Background(), "")
+	require.NoError(t, err, "an empty kid matches a single-key set")
+	assert.Equal(t, key.Public(), got)
+
+	_, _, err = keys.Key(context.Background(), "enc")
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/savisec/hello-go/internal/config"
+	"github.com/savisec/hello-go/internal/lambdactx"
+)
+
+// AuthorizerJWT is the lambdactx.Identity.Authorizer of JWT callers.
+const AuthorizerJWT = "jwt"
+
+var defaultJWTAlgorithms = []string{"RS256", "ES256", "EdDSA"}
+
+// JWTValidator validates JWT bearer tokens signed by a key in a JWKS.
+type JWTValidator struct {
+	keys           *JWKS
+	now            func() time.Time
+	principalClaim string
+	options        []jwt.ParserOption
+}
+
+// NewJWTValidator creates a JWTValidator that checks signatures against
+// keys and the claims against cfg. The signature algorithm must be one of
+// cfg.Algorithms; exp is required, and nbf is checked when present.
+func NewJWTValidator(cfg config.JWTConfig, keys *JWKS) (*JWTValidator, error) {
+	if cfg.Issuer == "" || cfg.Audience == "" {
+		return nil, errors.New("jwt issuer and audience are required")
+	}
+
+	algorithms := cfg.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = defaultJWTAlgorithms
+	}
+	for _, alg := range algorithms {
+		if !isSupportedAlgorithm(alg) {
+			return nil, fmt.Errorf("unsupported jwt algorithm %q", alg)
+		}
+	}
+
+	principalClaim := cfg.PrincipalClaim
+	if principalClaim == "" {
+		principalClaim = "sub"
+	}
+
+	v := &JWTValidator{
+		keys:           keys,
+		now:            time.Now,
+		principalClaim: principalClaim,
+	}
+	v.options = []jwt.ParserOption{
+		jwt.WithValidMethods(algorithms),
+		jwt.WithIssuer(cfg.Issuer),
+		jwt.WithAudience(cfg.Audience),
+		jwt.WithLeeway(cfg.ClockSkew),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithTimeFunc(func() time.Time { return v.now() }),
+	}
+	return v, nil
+}
+
+func isSupportedAlgorithm(alg string) bool {
+	switch alg {
+	case "RS256", "RS384", "RS512", "ES256", "EdDSA":
+		return true
+	default:
+		return false
+	}
+}
+
+// LooksLikeJWT reports whether token has the three segments of a compact
+// JWS, as opposed to the two of an API key.
+func LooksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// Validate verifies token and maps its claims to an identity. Invalid
+// tokens return an error wrapping ErrInvalidCredentials; a key set that
+// cannot be fetched returns a different error.
+func (v *JWTValidator) Validate(ctx context.Context, token string) (*lambdactx.Identity, error) {
+	var keyErr error
+	parsed, err := jwt.NewParser(v.options...).Parse(token, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, alg, err := v.keys.Key(ctx, kid)
+		if err != nil {
+			if !errors.Is(err, ErrUnknownKey) {
+				keyErr = err
+			}
+			return nil, err
+		}
+		if alg != "" && alg != t.Method.Alg() {
+			return nil, fmt.Errorf("key %q is for %s, not %s", kid, alg, t.Method.Alg())
+		}
+		return key, nil
+	})
+	if keyErr != nil {
+		return nil, keyErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	subject, _ := claims[v.principalClaim].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("%w: token has no %s claim", ErrInvalidCredentials, v.principalClaim)
+	}
+
+	return &lambdactx.Identity{
+		Subject:    subject,
+		Scopes:     scopesFromClaims(claims),
+		Authorizer: AuthorizerJWT,
+		Claims:     stringClaims(claims),
+	}, nil
+}
+
+// scopesFromClaims reads the space-delimited "scope" claim of RFC 8693, or
+// the "scp" list some providers issue instead.
+func scopesFromClaims(claims jwt.MapClaims) []string {
+	if scope, ok := claims["scope"].(string); ok {
+		return strings.Fields(scope)
+	}
+
+	switch scp := claims["scp"].(type) {
+	case string:
+		return strings.Fields(scp)
+	case []any:
+		scopes := make([]string, 0, len(scp))
+		for _, s := range scp {
+			if s, ok := s.(string); ok {
+				scopes = append(scopes, s)
+			}
+		}
+		return scopes
+	}
+	return nil
+}
+
+// stringClaims flattens claims to strings the way API Gateway's JWT
+// authorizer does, so handlers see the same shape from either.
+func stringClaims(claims jwt.MapClaims) map[string]string {
+	out := make(map[string]string, len(claims))
+	for name, value := range claims {
+		switch value := value.(type) {
+		case string:
+			out[name] = value
+		case float64:
+			out[name] = strconv.FormatFloat(value, 'f', -1, 64)
+		default:
+			b, err := json.Marshal(value)
+			if err == nil {
+				out[name] = string(b)
+			}
+		}
+	}
+	return out
+}
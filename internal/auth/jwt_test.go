@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/internal/config"
+	"github.com/savisec/hello-go/internal/lambdactx"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com/"
+	testAudience = "hello-go"
+)
+
+func signToken(t *testing.T, method jwt.SigningMethod, kid string, key crypto.Signer, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestJWTValidator_Validate(t *testing.T) {
+	rsaKey, ecKey, edKey := newRSAKey(t), newECKey(t), newEd25519Key(t)
+
+	server := newJWKSServer(t)
+	server.setKeys(
+		publicJWK(t, "rsa", "RS256", rsaKey),
+		publicJWK(t, "ec", "ES256", ecKey),
+		publicJWK(t, "ed", "EdDSA", edKey),
+	)
+
+	clock := &testClock{now: time.Unix(1_700_000_000, 0)}
+	cfg := config.JWTConfig{
+		JWKSURL:   server.URL,
+		Issuer:    testIssuer,
+		Audience:  testAudience,
+		ClockSkew: 30 * time.Second,
+	}
+	keys := newTestJWKS(t, cfg, clock)
+	validator, err := NewJWTValidator(cfg, keys)
+	require.NoError(t, err)
+	validator.now = clock.Now
+
+	now := clock.now.Unix()
+	claims := func(overrides jwt.MapClaims) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"iss": testIssuer,
+			"aud": testAudience,
+			"sub": "user-1",
+			"iat": now,
+			"exp": now + 300,
+		}
+		for name, value := range overrides {
+			if value == nil {
+				delete(c, name)
+				continue
+			}
+			c[name] = value
+		}
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		method  jwt.SigningMethod
+		kid     string
+		key     crypto.Signer
+		claims  jwt.MapClaims
+		wantErr bool
+	}{
+		{name: "RS256", method: jwt.SigningMethodRS256, kid: "rsa", key: rsaKey, claims: claims(nil)},
+		{name: "ES256", method: jwt.SigningMethodES256, kid: "ec", key: ecKey, claims: claims(nil)},
+		{name: "EdDSA", method: jwt.SigningMethodEdDSA, kid: "ed", key: edKey, claims: claims(nil)},
+		{name: "audience list", method: jwt.SigningMethodRS256, kid: "rsa", key: rsaKey, claims: claims(jwt.MapClaims{"aud": []string{"other", testAudience}})},
+		{name: "expired within clock skew", method: jwt.SigningMethodRS256, kid: "rsa", key: rsaKey, claims: claims(jwt.MapClaims{"exp": now - 10})},
+		{name: "expired", method: jwt.SigningMethodRS256, kid: "rsa", key: rsaKey, claims: claims(jwt.MapClaims{"exp": now - 60}), wantErr: true},
+		{name: "no exp", method: jwt.SigningMethodRS256, kid: "rsa", key: rsaKey, claims: claims(jwt.MapClaims{"exp": nil}), wantErr: true},
+		{name: "not yet valid", method: jwt.SigningMethodRS256, kid: "rsa", key: rsaKey, claims: claims(jwt.MapClaims{"nbf": now + 60}), wantErr: true},
+		{name: "not yet valid within clock skew", method: jwt.SigningMethodRS256, kid: "rsa", key: rsaKey, claims: claims(jwt.MapClaims{"nbf": now + 10})},
+		{name: "wrong issuer", method: jwt.SigningMethodRS256, kid: "rsa", key: rsaKey, claims: claims(jwt.MapClaims{"iss": "https://evil.example.com/"}), wantErr: true},
+		{name: "wrong audience", method: jwt.SigningMethodRS256, kid: "rsa", key: rsaKey, claims: claims(jwt.MapClaims{"aud": "other"}), wantErr: true},
+		{name: "no subject", method: jwt.SigningMethodRS256, kid: "rsa", key: rsaKey, claims: claims(jwt.MapClaims{"sub": nil}), wantErr: true},
+		{name: "signed by another key", method: jwt.SigningMethodRS256, kid: "rsa", key: newRSAKey(t), claims: claims(nil), wantErr: true},
+		{name: "key pinned to another algorithm", method: jwt.SigningMethodRS384, kid: "rsa", key: rsaKey, claims: claims(nil), wantErr: true},
+		{name: "unknown kid", method: jwt.SigningMethodRS256, kid: "missing", key: rsaKey, claims: claims(nil), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := signToken(t, tt.method, tt.kid, tt.key, tt.claims)
+			assert.True(t, LooksLikeJWT(token))
+
+			identity, err := validator.Validate(context.Background(), token)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidCredentials)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "user-1", identity.Subject)
+			assert.Equal(t, AuthorizerJWT, identity.Authorizer)
+		})
+	}
+
+	t.Run("none algorithm", func(t *testing.T) {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims(nil)).SignedString(jwt.UnsafeAllowNoneSignatureType)
+		require.NoError(t, err)
+
+		_, err = validator.Validate(context.Background(), token)
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+}
+
+func TestJWTValidator_MapsClaims(t *testing.T) {
+	key := newEd25519Key(t)
+	server := newJWKSServer(t)
+	server.setKeys(publicJWK(t, "ed", "EdDSA", key))
+
+	cfg := config.JWTConfig{
+		JWKSURL:        server.URL,
+		Issuer:         testIssuer,
+		Audience:       testAudience,
+		PrincipalClaim: "email",
+	}
+	validator, err := NewJWTValidator(cfg, newTestJWKS(t, cfg, &testClock{now: time.Now()}))
+	require.NoError(t, err)
+
+	exp := time.Now().Add(time.Minute).Unix()
+	base := jwt.MapClaims{"iss": testIssuer, "aud": testAudience, "exp": exp, "email": "dev@example.com"}
+
+	tests := []struct {
+		name  string
+		extra jwt.MapClaims
+		want  []string
+	}{
+		{name: "scope", extra: jwt.MapClaims{"scope": "echo:write admin"}, want: []string{"echo:write", "admin"}},
+		{name: "scp list", extra: jwt.MapClaims{"scp": []string{"echo:write"}}, want: []string{"echo:write"}},
+		{name: "scp string", extra: jwt.MapClaims{"scp": "echo:write"}, want: []string{"echo:write"}},
+		{name: "none", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := jwt.MapClaims{}
+			for name, value := range base {
+				claims[name] = value
+			}
+			for name, value := range tt.extra {
+				claims[name] = value
+			}
+
+			identity, err := validator.Validate(context.Background(), signToken(t, jwt.SigningMethodEdDSA, "ed", key, claims))
+			require.NoError(t, err)
+			assert.Equal(t, "dev@example.com", identity.Subject)
+			assert.Equal(t, tt.want, identity.Scopes)
+			assert.Equal(t, testIssuer, identity.Claims["iss"])
+			assert.Equal(t, testAudience, identity.Claims["aud"])
+		})
+	}
+}
+
+func TestJWTValidator_UnavailableJWKS(t *testing.T) {
+	server := newJWKSServer(t)
+	server.setFailing(true)
+
+	cfg := config.JWTConfig{JWKSURL: server.URL, Issuer: testIssuer, Audience: testAudience}
+	validator, err := NewJWTValidator(cfg, newTestJWKS(t, cfg, &testClock{now: time.Now()}))
+	require.NoError(t, err)
+
+	token := signToken(t, jwt.SigningMethodEdDSA, "ed", newEd25519Key(t), jwt.MapClaims{
+		"iss": testIssuer, "aud": testAudience, "sub": "user-1", "exp": time.Now().Add(time.Minute).Unix(),
+	})
+
+	_, err = validator.Validate(context.Background(), token)
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, ErrInvalidCredentials), "an unreachable JWKS is not the caller's fault")
+}
+
+func TestAuthenticator_AuthenticateBearer(t *testing.T) {
+	key := newEd25519Key(t)
+	server := newJWKSServer(t)
+	server.setKeys(publicJWK(t, "ed", "EdDSA", key))
+
+	cfg := config.JWTConfig{JWKSURL: server.URL, Issuer: testIssuer, Audience: testAudience}
+	validator, err := NewJWTValidator(cfg, newTestJWKS(t, cfg, &testClock{now: time.Now()}))
+	require.NoError(t, err)
+
+	raw, apiKey, err := GenerateKey("ci", "ci-bot", nil)
+	require.NoError(t, err)
+	store, err := NewConfigKeyStore([]config.APIKeyConfig{{ID: apiKey.ID, Principal: apiKey.Principal, Salt: apiKey.Salt, Hash: apiKey.Hash}})
+	require.NoError(t, err)
+
+	authenticator := NewAuthenticator(store, validator)
+
+	token := signToken(t, jwt.SigningMethodEdDSA, "ed", key, jwt.MapClaims{
+		"iss": testIssuer, "aud": testAudience, "sub": "user-1", "exp": time.Now().Add(time.Minute).Unix(),
+	})
+
+	tests := []struct {
+		name  string
+		token string
+		want  *lambdactx.Identity
+	}{
+		{name: "jwt", token: token, want: &lambdactx.Identity{Subject: "user-1", Authorizer: AuthorizerJWT}},
+		{name: "api key", token: raw, want: &lambdactx.Identity{Subject: "ci-bot", Authorizer: AuthorizerAPIKey}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := authenticator.AuthenticateBearer(context.Background(), tt.token)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want.Subject, identity.Subject)
+			assert.Equal(t, tt.want.Authorizer, identity.Authorizer)
+		})
+	}
+}
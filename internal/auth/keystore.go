@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/savisec/hello-go/internal/config"
+)
+
+// ErrKeyNotFound is returned by a KeyStore that has no key with the given ID.
+var ErrKeyNotFound = errors.New("api key not found")
+
+// Key is a stored API key. Only a salted hash of the secret is kept.
+type Key struct {
+	ID        string
+	Principal string
+	Scopes    []string
+	// Salt and Hash are hex encoded; Hash is SHA-256(salt || secret).
+	Salt string
+	Hash string
+	// Tenant is the tenant the key's owner belongs to, if any.
+	Tenant string
+}
+
+// KeyStore looks up API keys by ID.
+type KeyStore interface {
+	Lookup(ctx context.Context, id string) (Key, error)
+}
+
+// ConfigKeyStore serves the keys listed in the config file.
+type ConfigKeyStore struct {
+	keys map[string]Key
+}
+
+// NewConfigKeyStore validates keys and indexes them by ID.
+func NewConfigKeyStore(keys []config.APIKeyConfig) (*ConfigKeyStore, error) {
+	store := &ConfigKeyStore{keys: make(map[string]Key, len(keys))}
+	for i, k := range keys {
+		if k.ID == "" || strings.Contains(k.ID, keySeparator) {
+			return nil, fmt.Errorf("api key %d: id must be set and must not contain %q", i, keySeparator)
+		}
+		if _, ok := store.keys[k.ID]; ok {
+			return nil, fmt.Errorf("api key %q: duplicate id", k.ID)
+		}
+		if _, err := hex.DecodeString(k.Salt); err != nil || k.Salt == "" {
+			return nil, fmt.Errorf("api key %q: salt must be hex encoded", k.ID)
+		}
+		if hash, err := hex.DecodeString(k.Hash); err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("api key %q: hash must be a hex encoded SHA-256 digest", k.ID)
+		}
+
+		principal := k.Principal
+		if principal == "" {
+			principal = k.ID
+		}
+		store.keys[k.ID] = Key{
+			ID:        k.ID,
+			Principal: principal,
+			Scopes:    k.Scopes,
+			Salt:      strings.ToLower(k.Salt),
+			Hash:      strings.ToLower(k.Hash),
+			Tenant:    k.Tenant,
+		}
+	}
+	return store, nil
+}
+
+func (s *ConfigKeyStore) Lookup(_ context.Context, id string) (Key, error) {
+	key, ok := s.keys[id]
+	if !ok {
+		return Key{}, ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// keySeparator splits an API key into its public ID and its secret.
+const keySeparator = "."
+
+// ParseKey splits a raw "<id>.<secret>" API key.
+func ParseKey(raw string) (id, secret string, ok bool) {
+	id, secret, ok = strings.Cut(raw, keySeparator)
+	return id, secret, ok && id != "" && secret != ""
+}
+
+// HashSecret returns the hex encoded SHA-256 of salt followed by secret.
+// API key secrets are random, so a fast hash is enough to make a leaked
+// store useless for authentication.
+func HashSecret(salt []byte, secret string) string {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write([]byte(secret))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// GenerateKey creates a new key with the given ID. It returns the raw key,
+// to hand to the client once, and the Key to store.
+func GenerateKey(id, principal string, scopes []string) (string, Key, error) {
+	if id == "" {
+		var b [6]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return "", Key{}, fmt.Errorf("failed to generate key id: %w", err)
+		}
+		id = hex.EncodeToString(b[:])
+	}
+	if strings.Contains(id, keySeparator) {
+		return "", Key{}, fmt.Errorf("key id must not contain %q", keySeparator)
+	}
+
+	var secret [32]byte
+	var salt [16]byte
+	if _, err := rand.Read(secret[:]); err != nil {
+		return "", Key{}, fmt.Errorf("failed to generate key secret: %w", err)
+	}
+	if _, err := rand.Read(salt[:]); err != nil {
+		return "", Key{}, fmt.Errorf("failed to generate key salt: %w", err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(secret[:])
+	return id + keySeparator + encoded, Key{
+		ID:        id,
+		Principal: principal,
+		Scopes:    scopes,
+		Salt:      hex.EncodeToString(salt[:]),
+		Hash:      HashSecret(salt[:], encoded),
+	}, nil
+}
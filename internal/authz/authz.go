@@ -0,0 +1,269 @@
+// Package authz decides whether an authenticated caller may invoke an
+// OpenAPI operation, according to a declarative policy of roles and
+// allow/deny rules keyed by operation ID.
+package authz
+
+import (
+	"encoding/json"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/savisec/hello-go/internal/lambdactx"
+)
+
+const (
+	attrSubject      = "subject"
+	attrAuthorizer   = "authorizer"
+	attrOperation    = "operation"
+	attrClaimsPrefix = "claims."
+	attrBodyPrefix   = "body."
+)
+
+// Input is what a decision is made on.
+type Input struct {
+	// Identity is nil for anonymous callers.
+	Identity *lambdactx.Identity
+	// Body is the JSON request body as decoded by DecodeBody, if the
+	// policy needs it. Body attributes match keys case-insensitively, as
+	// handlers decoding into structs do.
+	Body      map[string]any
+	Operation string
+}
+
+// Decision is the outcome of evaluating a policy.
+type Decision struct {
+	// Rule names the deciding rule; it is empty when no rule matched and
+	// the request was denied by default.
+	Rule   string
+	Reason string
+	// Roles are the roles the caller was found to hold.
+	Roles   []string
+	Allowed bool
+}
+
+// Engine evaluates a policy. Any matching deny rule wins over allow rules,
+// and a request no rule allows is denied.
+type Engine struct {
+	policy *Policy
+}
+
+func NewEngine(policy *Policy) *Engine {
+	return &Engine{policy: policy}
+}
+
+// Decide evaluates the policy for in.
+func (e *Engine) Decide(in Input) Decision {
+	roles := e.roles(in.Identity)
+
+	var allow *Rule
+	for i := range e.policy.Rules {
+		rule := &e.policy.Rules[i]
+		if !rule.appliesTo(in, roles) {
+			continue
+		}
+		if rule.Effect == EffectDeny {
+			return Decision{Rule: rule.Name, Reason: "denied by rule", Roles: roles}
+		}
+		if allow == nil {
+			allow = rule
+		}
+	}
+
+	if allow == nil {
+		return Decision{Reason: "no rule allows the operation", Roles: roles}
+	}
+	return Decision{Rule: allow.Name, Reason: "allowed by rule", Roles: roles, Allowed: true}
+}
+
+// UsesBody reports whether any rule for the operation has a condition on
+// the request body, so callers only decode bodies when they must.
+func (e *Engine) UsesBody(operationID string) bool {
+	for _, rule := range e.policy.Rules {
+		if !rule.matchesOperation(operationID) {
+			continue
+		}
+		for _, cond := range rule.Conditions {
+			if strings.HasPrefix(cond.Attribute, attrBodyPrefix) || strings.HasPrefix(cond.ValueFrom, attrBodyPrefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// roles returns the sorted names of the roles identity holds.
+func (e *Engine) roles(identity *lambdactx.Identity) []string {
+	if identity == nil {
+		return nil
+	}
+
+	var roles []string
+	for name, role := range e.policy.Roles {
+		if role.heldBy(identity) {
+			roles = append(roles, name)
+		}
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+func (r Role) heldBy(identity *lambdactx.Identity) bool {
+	if slices.Contains(r.Subjects, identity.Subject) {
+		return true
+	}
+	for _, scope := range r.Scopes {
+		if slices.Contains(identity.Scopes, scope) {
+			return true
+		}
+	}
+	for claim, values := range r.Claims {
+		raw, ok := identity.Claims[claim]
+		if !ok {
+			continue
+		}
+		for _, v := range claimValues(raw) {
+			if slices.Contains(values, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// claimValues splits a claim flattened to a JSON array back into its
+// elements; any other claim is a single value.
+func claimValues(raw string) []string {
+	if strings.HasPrefix(raw, "[") {
+		var values []any
+		if err := json.Unmarshal([]byte(raw), &values); err == nil {
+			out := make([]string, 0, len(values))
+			for _, v := range values {
+				if s, ok := scalarString(v); ok {
+					out = append(out, s)
+				}
+			}
+			return out
+		}
+	}
+	return []string{raw}
+}
+
+func (r *Rule) matchesOperation(id string) bool {
+	return slices.Contains(r.Operations, AnyOperation) || slices.Contains(r.Operations, id)
+}
+
+func (r *Rule) appliesTo(in Input, roles []string) bool {
+	if !r.matchesOperation(in.Operation) {
+		return false
+	}
+
+	if len(r.Roles) > 0 || len(r.Subjects) > 0 {
+		matched := in.Identity != nil && slices.Contains(r.Subjects, in.Identity.Subject)
+		for _, role := range r.Roles {
+			matched = matched || slices.Contains(roles, role)
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	for _, cond := range r.Conditions {
+		if !cond.holds(in) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c Condition) holds(in Input) bool {
+	value, present := in.attribute(c.Attribute)
+
+	switch c.Operator {
+	case OpPresent:
+		return present
+	case OpAbsent:
+		return !present
+	}
+	if !present {
+		return false
+	}
+
+	want := c.Value
+	if c.ValueFrom != "" {
+		var ok bool
+		if want, ok = in.attribute(c.ValueFrom); !ok {
+			return false
+		}
+	}
+
+	switch c.Operator {
+	case OpEqual:
+		return value == want
+	case OpNotEq:
+		return value != want
+	case OpIn:
+		return slices.Contains(c.Values, value)
+	case OpNotIn:
+		return !slices.Contains(c.Values, value)
+	}
+	return false
+}
+
+// attribute returns the named request attribute as a string.
+func (in Input) attribute(name string) (string, bool) {
+	switch name {
+	case attrOperation:
+		return in.Operation, true
+	case attrSubject:
+		if in.Identity == nil {
+			return "", false
+		}
+		return in.Identity.Subject, true
+	case attrAuthorizer:
+		if in.Identity == nil {
+			return "", false
+		}
+		return in.Identity.Authorizer, true
+	}
+
+	if claim, ok := strings.CutPrefix(name, attrClaimsPrefix); ok {
+		if in.Identity == nil {
+			return "", false
+		}
+		value, ok := in.Identity.Claims[claim]
+		return value, ok
+	}
+
+	if path, ok := strings.CutPrefix(name, attrBodyPrefix); ok {
+		var value any = in.Body
+		for key := range strings.SplitSeq(path, ".") {
+			obj, ok := value.(map[string]any)
+			if !ok {
+				return "", false
+			}
+			if value, ok = field(obj, key); !ok {
+				return "", false
+			}
+		}
+		return scalarString(value)
+	}
+
+	return "", false
+}
+
+// scalarString formats a JSON scalar; objects, arrays and null are not
+// comparable and report false.
+func scalarString(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return "", false
+	}
+}
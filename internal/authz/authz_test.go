@@ -0,0 +1,235 @@
+package authz_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/internal/authz"
+	"github.com/savisec/hello-go/internal/lambdactx"
+)
+
+const testPolicy = `
+roles:
+  writer:
+    scopes: [echo:write]
+  admin:
+    claims:
+      groups: [admins]
+  auditor:
+    subjects: [carol]
+
+rules:
+  - name: health
+    effect: allow
+    operations: [healthz]
+  - name: writers-as-themselves
+    effect: allow
+    operations: [echo]
+    roles: [writer]
+    conditions:
+      - attribute: body.author
+        operator: eq
+        value_from: subject
+  - name: admins
+    effect: allow
+    operations: [echo]
+    roles: [admin]
+  - name: nested-field
+    effect: allow
+    operations: [publish]
+    subjects: [dave]
+    conditions:
+      - attribute: body.meta.priority
+        operator: in
+        values: ["1", "2"]
+  - name: suspended
+    effect: deny
+    operations: ["*"]
+    conditions:
+      - attribute: claims.suspended
+        operator: eq
+        value: "true"
+`
+
+func TestEngine_Decide(t *testing.T) {
+	policy, err := authz.ParsePolicy([]byte(testPolicy))
+	require.NoError(t, err)
+	engine := authz.NewEngine(policy)
+
+	writer := &lambdactx.Identity{Subject: "alice", Scopes: []string{"echo:write"}}
+	admin := &lambdactx.Identity{Subject: "bob", Claims: map[string]string{"groups": `["devs","admins"]`}}
+
+	tests := []struct {
+		in        authz.Input
+		name      string
+		wantRule  string
+		wantRoles []string
+		wantAllow bool
+	}{
+		{
+			name:      "anonymous health check",
+			in:        authz.Input{Operation: "healthz"},
+			wantAllow: true, wantRule: "health",
+		},
+		{
+			name:      "writer echoing as themselves",
+			in:        authz.Input{Operation: "echo", Identity: writer, Body: map[string]any{"author": "alice"}},
+			wantAllow: true, wantRule: "writers-as-themselves", wantRoles: []string{"writer"},
+		},
+		{
+			name:      "writer echoing as someone else",
+			in:        authz.Input{Operation: "echo", Identity: writer, Body: map[string]any{"author": "mallory"}},
+			wantRoles: []string{"writer"},
+		},
+		{
+			name:      "writer without an author",
+			in:        authz.Input{Operation: "echo", Identity: writer, Body: map[string]any{"message": "hi"}},
+			wantRoles: []string{"writer"},
+		},
+		{
+			name:      "admin from a JSON array claim",
+			in:        authz.Input{Operation: "echo", Identity: admin, Body: map[string]any{"author": "mallory"}},
+			wantAllow: true, wantRule: "admins", wantRoles: []string{"admin"},
+		},
+		{
+			name:      "role from subject has no rule",
+			in:        authz.Input{Operation: "echo", Identity: &lambdactx.Identity{Subject: "carol"}},
+			wantRoles: []string{"auditor"},
+		},
+		{
+			name: "deny wins over allow",
+			in: authz.Input{Operation: "echo", Identity: &lambdactx.Identity{
+				Subject: "bob",
+				Claims:  map[string]string{"groups": "admins", "suspended": "true"},
+			}},
+			wantRule: "suspended", wantRoles: []string{"admin"},
+		},
+		{
+			name: "nested body field",
+			in: authz.Input{Operation: "publish", Identity: &lambdactx.Identity{Subject: "dave"},
+				Body: map[string]any{"meta": map[string]any{"priority": float64(2)}}},
+			wantAllow: true, wantRule: "nested-field",
+		},
+		{
+			name: "nested body field out of range",
+			in: authz.Input{Operation: "publish", Identity: &lambdactx.Identity{Subject: "dave"},
+				Body: map[string]any{"meta": map[string]any{"priority": float64(3)}}},
+		},
+		{
+			name:      "unknown operation",
+			in:        authz.Input{Operation: "delete", Identity: writer},
+			wantRoles: []string{"writer"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := engine.Decide(tt.in)
+			assert.Equal(t, tt.wantAllow, decision.Allowed)
+			assert.Equal(t, tt.wantRule, decision.Rule)
+			assert.Equal(t, tt.wantRoles, decision.Roles)
+		})
+	}
+}
+
+func TestDecodeBody(t *testing.T) {
+	tests := []struct {
+		want    map[string]any
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{name: "object", body: `{"author":"alice"}`, want: map[string]any{"author": "alice"}},
+		{name: "not an object", body: `["alice"]`},
+		{name: "not json", body: `hello`},
+		{name: "duplicate key", body: `{"author":"alice","author":"bob"}`, wantErr: true},
+		{name: "case-variant key", body: `{"author":"alice","Author":"bob"}`, wantErr: true},
+		{name: "unicode folded key", body: `{"kind":"a","\u212aind":"b"}`, wantErr: true},
+		{name: "nested case-variant key", body: `{"meta":[{"p":1,"P":2}]}`, wantErr: true},
+		{name: "same key in different objects", body: `{"a":{"k":1},"b":{"k":2}}`, want: map[string]any{
+			"a": map[string]any{"k": float64(1)}, "b": map[string]any{"k": float64(2)},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := authz.DecodeBody([]byte(tt.body))
+			if tt.wantErr {
+				assert.ErrorIs(t, err, authz.ErrAmbiguousBody)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, body)
+		})
+	}
+}
+
+func TestEngine_UsesBody(t *testing.T) {
+	policy, err := authz.ParsePolicy([]byte(testPolicy))
+	require.NoError(t, err)
+	engine := authz.NewEngine(policy)
+
+	assert.True(t, engine.UsesBody("echo"))
+	assert.True(t, engine.UsesBody("publish"))
+	assert.False(t, engine.UsesBody("healthz"))
+}
+
+func TestParsePolicy_Validates(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		wantErr string
+	}{
+		{
+			name:    "unknown field",
+			policy:  "rules:\n  - name: r\n    effect: allow\n    operation: [echo]\n",
+			wantErr: "field operation not found",
+		},
+		{
+			name:    "bad effect",
+			policy:  "rules:\n  - name: r\n    effect: permit\n    operations: [echo]\n",
+			wantErr: "effect must be",
+		},
+		{
+			name:    "no operations",
+			policy:  "rules:\n  - name: r\n    effect: allow\n",
+			wantErr: "no operations",
+		},
+		{
+			name:    "unknown role",
+			policy:  "rules:\n  - name: r\n    effect: allow\n    operations: [echo]\n    roles: [ghost]\n",
+			wantErr: `unknown role "ghost"`,
+		},
+		{
+			name:    "unknown attribute",
+			policy:  "rules:\n  - name: r\n    effect: allow\n    operations: [echo]\n    conditions:\n      - {attribute: header.x, operator: present}\n",
+			wantErr: `unknown attribute "header.x"`,
+		},
+		{
+			name:    "unknown operator",
+			policy:  "rules:\n  - name: r\n    effect: allow\n    operations: [echo]\n    conditions:\n      - {attribute: subject, operator: like, value: a}\n",
+			wantErr: `unknown operator "like"`,
+		},
+		{
+			name:    "eq without a value",
+			policy:  "rules:\n  - name: r\n    effect: allow\n    operations: [echo]\n    conditions:\n      - {attribute: subject, operator: eq}\n",
+			wantErr: "needs a value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := authz.ParsePolicy([]byte(tt.policy))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestLoadPolicy_ShippedPolicy(t *testing.T) {
+	policy, err := authz.LoadPolicy("../../configs/policy.yml")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"echo"}, policy.Operations())
+}
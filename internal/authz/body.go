@@ -0,0 +1,103 @@
+package authz
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrAmbiguousBody is returned by DecodeBody for a JSON object with two
+// keys that encoding/json would decode into the same struct field. The
+// policy and the handler could otherwise see different values.
+var ErrAmbiguousBody = errors.New("ambiguous request body")
+
+// DecodeBody decodes a JSON request body for Input.Body. Bodies that are
+// not a JSON object decode to nil without error, leaving body attributes
+// absent. Objects, at any depth, with duplicate keys or keys differing
+// only in case are rejected with ErrAmbiguousBody, because handlers
+// decoding into structs match keys case-insensitively and keep the last.
+func DecodeBody(data []byte) (map[string]any, error) {
+	if err := checkKeys(json.NewDecoder(bytes.NewReader(data))); errors.Is(err, ErrAmbiguousBody) {
+		return nil, err
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, nil
+	}
+	return body, nil
+}
+
+// checkKeys reads one JSON value from dec and checks every object in it
+// for keys that fold to the same name. Malformed JSON is reported as is.
+func checkKeys(dec *json.Decoder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return nil
+	}
+
+	switch delim {
+	case '{':
+		seen := map[string]string{}
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key, _ := tok.(string)
+			folded := foldKey(key)
+			if prev, ok := seen[folded]; ok {
+				return fmt.Errorf("%w: keys %q and %q", ErrAmbiguousBody, prev, key)
+			}
+			seen[folded] = key
+			if err := checkKeys(dec); err != nil {
+				return err
+			}
+		}
+	case '[':
+		for dec.More() {
+			if err := checkKeys(dec); err != nil {
+				return err
+			}
+		}
+	}
+	// The closing delimiter.
+	_, err = dec.Token()
+	return err
+}
+
+// foldKey maps keys encoding/json treats as equal, under Unicode simple
+// case folding, to the same string.
+func foldKey(key string) string {
+	var b strings.Builder
+	for _, r := range key {
+		lowest := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			lowest = min(lowest, f)
+		}
+		b.WriteRune(lowest)
+	}
+	return b.String()
+}
+
+// field looks up a key of a decoded object the way encoding/json matches
+// struct fields: exactly if present, else case-insensitively.
+func field(obj map[string]any, key string) (any, bool) {
+	if value, ok := obj[key]; ok {
+		return value, true
+	}
+	folded := foldKey(key)
+	for k, value := range obj {
+		if foldKey(k) == folded {
+			return value, true
+		}
+	}
+	return nil, false
+}
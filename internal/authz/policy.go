@@ -0,0 +1,178 @@
+package authz
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+
+	// AnyOperation in a rule's operations matches every operation.
+	AnyOperation = "*"
+)
+
+// Condition operators. Comparisons need the attribute to be present; a
+// missing attribute fails eq, ne, in and not_in alike.
+const (
+	OpEqual   = "eq"
+	OpNotEq   = "ne"
+	OpIn      = "in"
+	OpNotIn   = "not_in"
+	OpPresent = "present"
+	OpAbsent  = "absent"
+)
+
+// Policy is an authorization policy file: named roles, and the rules that
+// grant or deny operations to them.
+type Policy struct {
+	Roles map[string]Role `yaml:"roles"`
+	Rules []Rule          `yaml:"rules"`
+}
+
+// Role is held by a caller that matches any of its subjects, scopes or
+// claim values.
+type Role struct {
+	// Claims maps a claim name to the values that grant the role. Claims
+	// holding a JSON array, such as a JWT "groups" claim, match if any
+	// element does.
+	Claims   map[string][]string `yaml:"claims"`
+	Subjects []string            `yaml:"subjects"`
+	Scopes   []string            `yaml:"scopes"`
+}
+
+// Rule allows or denies the operations it lists to callers holding any of
+// its roles or matching any of its subjects, when all its conditions hold.
+// A rule with neither roles nor subjects applies to every caller.
+type Rule struct {
+	Name       string      `yaml:"name"`
+	Effect     string      `yaml:"effect"`
+	Operations []string    `yaml:"operations"`
+	Roles      []string    `yaml:"roles"`
+	Subjects   []string    `yaml:"subjects"`
+	Conditions []Condition `yaml:"conditions"`
+}
+
+// Condition compares a request attribute, such as body.author, with a
+// literal or with another attribute. Attributes are subject, authorizer,
+// operation, claims.<name> and body.<field>[.<field>...].
+type Condition struct {
+	Attribute string   `yaml:"attribute"`
+	Operator  string   `yaml:"operator"`
+	Value     string   `yaml:"value"`
+	Values    []string `yaml:"values"`
+	// ValueFrom names the attribute to compare with instead of Value.
+	ValueFrom string `yaml:"value_from"`
+}
+
+// LoadPolicy reads a policy file. Unknown fields are rejected so a typo
+// cannot silently widen or narrow a rule.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+	return ParsePolicy(data)
+}
+
+// ParsePolicy parses and validates a policy in YAML.
+func ParsePolicy(data []byte) (*Policy, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	var policy Policy
+	if err := dec.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("failed to parse policy: %w", err)
+	}
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (p *Policy) validate() error {
+	var errs []error
+	for i, rule := range p.Rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+
+		if rule.Effect != EffectAllow && rule.Effect != EffectDeny {
+			errs = append(errs, fmt.Errorf("rule %s: effect must be %q or %q", name, EffectAllow, EffectDeny))
+		}
+		if len(rule.Operations) == 0 {
+			errs = append(errs, fmt.Errorf("rule %s: no operations", name))
+		}
+		for _, role := range rule.Roles {
+			if _, ok := p.Roles[role]; !ok {
+				errs = append(errs, fmt.Errorf("rule %s: unknown role %q", name, role))
+			}
+		}
+		for _, cond := range rule.Conditions {
+			if err := cond.validate(); err != nil {
+				errs = append(errs, fmt.Errorf("rule %s: %w", name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c Condition) validate() error {
+	if !validAttribute(c.Attribute) {
+		return fmt.Errorf("unknown attribute %q", c.Attribute)
+	}
+	if c.ValueFrom != "" && !validAttribute(c.ValueFrom) {
+		return fmt.Errorf("unknown attribute %q", c.ValueFrom)
+	}
+
+	switch c.Operator {
+	case OpEqual, OpNotEq:
+		if c.Value == "" && c.ValueFrom == "" {
+			return fmt.Errorf("%s on %s needs a value or value_from", c.Operator, c.Attribute)
+		}
+	case OpIn, OpNotIn:
+		if len(c.Values) == 0 {
+			return fmt.Errorf("%s on %s needs values", c.Operator, c.Attribute)
+		}
+	case OpPresent, OpAbsent:
+	default:
+		return fmt.Errorf("unknown operator %q", c.Operator)
+	}
+	return nil
+}
+
+func validAttribute(name string) bool {
+	switch name {
+	case attrSubject, attrAuthorizer, attrOperation:
+		return true
+	}
+	for _, prefix := range []string{attrClaimsPrefix, attrBodyPrefix} {
+		if rest, ok := strings.CutPrefix(name, prefix); ok {
+			return rest != ""
+		}
+	}
+	return false
+}
+
+// Operations returns the operation IDs the policy names, other than
+// AnyOperation.
+func (p *Policy) Operations() []string {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, rule := range p.Rules {
+		for _, id := range rule.Operations {
+			if id != AnyOperation && !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
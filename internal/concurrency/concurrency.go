@@ -0,0 +1,237 @@
+// Package concurrency limits how many requests are handled at once, adapting
+// the limit to observed latency so that a server sheds load before it is
+// overwhelmed rather than queueing work it cannot finish in time.
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+
+	"github.com/savisec/hello-go/internal/config"
+)
+
+const (
+	AlgorithmAIMD     = "aimd"
+	AlgorithmGradient = "gradient"
+)
+
+// Priority decides whether a request may be shed.
+type Priority int
+
+const (
+	// PriorityNormal requests are shed once the limit is reached.
+	PriorityNormal Priority = iota
+	// PriorityCritical requests, such as health checks, are never shed.
+	PriorityCritical
+)
+
+// Algorithm adapts the limit to each finished request.
+type Algorithm interface {
+	// Update returns the new limit after a request took rtt with inflight
+	// requests in progress, itself included. Dropped requests failed
+	// because the server was overloaded.
+	Update(limit float64, rtt time.Duration, inflight int, dropped bool) float64
+}
+
+// AIMD raises the limit by one while latency stays under Threshold and
+// the limit is in use, and multiplies it by Backoff otherwise.
+type AIMD struct {
+	Threshold time.Duration
+	Backoff   float64
+}
+
+func (a *AIMD) Update(limit float64, rtt time.Duration, inflight int, dropped bool) float64 {
+	if dropped || rtt > a.Threshold {
+		return limit * a.Backoff
+	}
+	if float64(inflight)*2 >= limit {
+		return limit + 1
+	}
+	return limit
+}
+
+const (
+	// gradientWindow is roughly how many requests the usual latency is
+	// averaged over.
+	gradientWindow = 100
+	// gradientSmoothing is how far each request moves the limit toward
+	// the one its latency suggests.
+	gradientSmoothing = 0.2
+)
+
+// Gradient compares each request's latency with the usual latency: while
+// it is within Tolerance times the usual, the limit grows by a queue of
+// its square root; beyond that, it shrinks in proportion, to at most half.
+type Gradient struct {
+	Tolerance float64
+	// usual is a moving average of latency, in seconds.
+	usual float64
+}
+
+func (g *Gradient) Update(limit float64, rtt time.Duration, inflight int, dropped bool) float64 {
+	sample := rtt.Seconds()
+	if g.usual == 0 {
+		g.usual = sample
+	} else {
+		g.usual += (sample - g.usual) / gradientWindow
+	}
+
+	gradient := 0.5
+	if !dropped && sample > 0 {
+		gradient = max(0.5, min(1, g.Tolerance*g.usual/sample))
+	}
+	next := limit*(1-gradientSmoothing) + (limit*gradient+math.Sqrt(limit))*gradientSmoothing
+
+	// A limit that is not in use says nothing about whether more would
+	// be handled in time.
+	if next > limit && float64(inflight)*2 < limit {
+		return limit
+	}
+	return next
+}
+
+// Limiter admits requests while fewer than its limit are in progress.
+type Limiter struct {
+	algorithm Algorithm
+	now       func() time.Time
+	shed      metric.Int64Counter
+	limit     float64
+	min       float64
+	max       float64
+	inflight  int
+	mu        sync.Mutex
+}
+
+// NewLimiter creates a Limiter that starts at initial and adapts between
+// minLimit and maxLimit.
+func NewLimiter(algorithm Algorithm, initial, minLimit, maxLimit int) *Limiter {
+	shed, _ := noop.Meter{}.Int64Counter("")
+	return &Limiter{
+		algorithm: algorithm,
+		now:       time.Now,
+		shed:      shed,
+		limit:     float64(max(minLimit, min(maxLimit, initial))),
+		min:       float64(minLimit),
+		max:       float64(maxLimit),
+	}
+}
+
+// New creates a Limiter with the algorithm and bounds in cfg.
+func New(cfg config.ConcurrencyConfig) (*Limiter, error) {
+	if cfg.MinLimit < 1 || cfg.MaxLimit < cfg.MinLimit {
+		return nil, fmt.Errorf("concurrency limits must satisfy 1 <= min_limit (%d) <= max_limit (%d)", cfg.MinLimit, cfg.MaxLimit)
+	}
+
+	var algorithm Algorithm
+	switch strings.ToLower(cfg.Algorithm) {
+	case AlgorithmAIMD:
+		if cfg.LatencyThreshold <= 0 {
+			return nil, errors.New("aimd requires a positive latency_threshold")
+		}
+		if cfg.Backoff <= 0 || cfg.Backoff >= 1 {
+			return nil, fmt.Errorf("aimd backoff must be between 0 and 1, got %g", cfg.Backoff)
+		}
+		algorithm = &AIMD{Threshold: cfg.LatencyThreshold, Backoff: cfg.Backoff}
+	case "", AlgorithmGradient:
+		if cfg.Tolerance < 1 {
+			return nil, fmt.Errorf("gradient tolerance must be at least 1, got %g", cfg.Tolerance)
+		}
+		algorithm = &Gradient{Tolerance: cfg.Tolerance}
+	default:
+		return nil, fmt.Errorf("unknown concurrency algorithm %q", cfg.Algorithm)
+	}
+
+	return NewLimiter(algorithm, cfg.InitialLimit, cfg.MinLimit, cfg.MaxLimit), nil
+}
+
+// Token is held by an admitted request until it finishes.
+type Token struct {
+	limiter  *Limiter
+	start    time.Time
+	priority Priority
+}
+
+// Acquire admits a request if fewer than the limit are in progress, or
+// regardless if it is critical. Critical requests count toward the
+// requests in progress but do not adapt the limit.
+func (l *Limiter) Acquire(ctx context.Context, priority Priority) (*Token, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if priority != PriorityCritical && float64(l.inflight) >= math.Floor(l.limit) {
+		l.shed.Add(ctx, 1)
+		return nil, false
+	}
+	l.inflight++
+	return &Token{limiter: l, start: l.now(), priority: priority}, true
+}
+
+// Release marks the request finished. Dropped requests failed because the
+// server was overloaded, such as by timing out.
+func (t *Token) Release(dropped bool) {
+	l := t.limiter
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if t.priority != PriorityCritical {
+		limit := l.algorithm.Update(l.limit, l.now().Sub(t.start), l.inflight, dropped)
+		l.limit = max(l.min, min(l.max, limit))
+	}
+	l.inflight--
+}
+
+// Stats reports the current limit and requests in progress.
+func (l *Limiter) Stats() (limit, inflight int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit), l.inflight
+}
+
+// RegisterMetrics exports the limit, the requests in progress and the
+// requests shed as metrics of meter.
+func (l *Limiter) RegisterMetrics(meter metric.Meter) error {
+	shed, err := meter.Int64Counter("http.server.concurrency.shed",
+		metric.WithUnit("{request}"),
+		metric.WithDescription("Requests rejected because the concurrency limit was reached"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create shed counter: %w", err)
+	}
+	limit, err := meter.Int64ObservableGauge("http.server.concurrency.limit",
+		metric.WithUnit("{request}"),
+		metric.WithDescription("Requests the server currently handles at once"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create limit gauge: %w", err)
+	}
+	inflight, err := meter.Int64ObservableUpDownCounter("http.server.concurrency.in_flight",
+		metric.WithUnit("{request}"),
+		metric.WithDescription("Requests in progress"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create in-flight counter: %w", err)
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		current, active := l.Stats()
+		o.ObserveInt64(limit, int64(current))
+		o.ObserveInt64(inflight, int64(active))
+		return nil
+	}, limit, inflight)
+	if err != nil {
+		return fmt.Errorf("failed to register concurrency metrics: %w", err)
+	}
+
+	l.mu.Lock()
+	l.shed = shed
+	l.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,149 @@
+package concurrency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/savisec/hello-go/internal/config"
+)
+
+// fixedLimit never changes the limit.
+type fixedLimit struct{}
+
+func (fixedLimit) Update(limit float64, _ time.Duration, _ int, _ bool) float64 { return limit }
+
+func TestLimiter_Acquire(t *testing.T) {
+	ctx := context.Background()
+	l := NewLimiter(fixedLimit{}, 2, 1, 10)
+
+	a, ok := l.Acquire(ctx, PriorityNormal)
+	require.True(t, ok)
+	_, ok = l.Acquire(ctx, PriorityNormal)
+	require.True(t, ok)
+
+	_, ok = l.Acquire(ctx, PriorityNormal)
+	assert.False(t, ok, "over the limit")
+
+	critical, ok := l.Acquire(ctx, PriorityCritical)
+	assert.True(t, ok, "critical requests are never shed")
+	_, inflight := l.Stats()
+	assert.Equal(t, 3, inflight)
+
+	critical.Release(false)
+	a.Release(false)
+	_, ok = l.Acquire(ctx, PriorityNormal)
+	assert.True(t, ok)
+}
+
+func TestAIMD(t *testing.T) {
+	a := &AIMD{Threshold: time.Second, Backoff: 0.5}
+
+	assert.InDelta(t, 11, a.Update(10, 100*time.Millisecond, 5, false), 0.001, "in use and fast")
+	assert.InDelta(t, 10, a.Update(10, 100*time.Millisecond, 2, false), 0.001, "not in use")
+	assert.InDelta(t, 5, a.Update(10, 2*time.Second, 5, false), 0.001, "slow")
+	assert.InDelta(t, 5, a.Update(10, 100*time.Millisecond, 5, true), 0.001, "dropped")
+}
+
+func TestGradient(t *testing.T) {
+	g := &Gradient{Tolerance: 2}
+
+	limit := 100.0
+	for range 50 {
+		limit = g.Update(limit, 10*time.Millisecond, int(limit), false)
+	}
+	assert.Greater(t, limit, 100.0, "grows while latency is usual")
+
+	grown := limit
+	for range 10 {
+		limit = g.Update(limit, 100*time.Millisecond, int(limit), false)
+	}
+	assert.Less(t, limit, grown, "shrinks once latency passes the tolerance")
+
+	idle := &Gradient{Tolerance: 2}
+	assert.InDelta(t, 100, idle.Update(100, 10*time.Millisecond, 10, false), 0.001, "does not grow unused")
+}
+
+func TestLimiter_ClampsLimit(t *testing.T) {
+	ctx := context.Background()
+	l := NewLimiter(&AIMD{Threshold: time.Millisecond, Backoff: 0.1}, 10, 4, 20)
+	now := time.Unix(1_700_000_000, 0)
+	l.now = func() time.Time { return now }
+
+	token, ok := l.Acquire(ctx, PriorityNormal)
+	require.True(t, ok)
+	now = now.Add(time.Second)
+	token.Release(false)
+
+	limit, _ := l.Stats()
+	assert.Equal(t, 4, limit)
+}
+
+func TestLimiter_RegisterMetrics(t *testing.T) {
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	l := NewLimiter(fixedLimit{}, 1, 1, 1)
+	require.NoError(t, l.RegisterMetrics(provider.Meter("test")))
+
+	_, ok := l.Acquire(ctx, PriorityNormal)
+	require.True(t, ok)
+	_, ok = l.Acquire(ctx, PriorityNormal)
+	require.False(t, ok)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+
+	values := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				values[m.Name] = data.DataPoints[0].Value
+			case metricdata.Gauge[int64]:
+				values[m.Name] = data.DataPoints[0].Value
+			}
+		}
+	}
+	assert.Equal(t, map[string]int64{
+		"http.server.concurrency.shed":      1,
+		"http.server.concurrency.limit":     1,
+		"http.server.concurrency.in_flight": 1,
+	}, values)
+}
+
+func TestNew_Validates(t *testing.T) {
+	valid := config.ConcurrencyConfig{MinLimit: 1, MaxLimit: 10, Tolerance: 2}
+
+	_, err := New(valid)
+	require.NoError(t, err)
+
+	tests := []struct {
+		mutate func(cfg *config.ConcurrencyConfig)
+		name   string
+	}{
+		{name: "unknown algorithm", mutate: func(cfg *config.ConcurrencyConfig) { cfg.Algorithm = "vegas" }},
+		{name: "no min", mutate: func(cfg *config.ConcurrencyConfig) { cfg.MinLimit = 0 }},
+		{name: "max under min", mutate: func(cfg *config.ConcurrencyConfig) { cfg.MaxLimit = 0 }},
+		{name: "low tolerance", mutate: func(cfg *config.ConcurrencyConfig) { cfg.Tolerance = 0.5 }},
+		{name: "aimd without threshold", mutate: func(cfg *config.ConcurrencyConfig) { cfg.Algorithm, cfg.Backoff = "aimd", 0.9 }},
+		{name: "aimd backoff", mutate: func(cfg *config.ConcurrencyConfig) {
+			cfg.Algorithm, cfg.LatencyThreshold, cfg.Backoff = "aimd", time.Second, 1
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.mutate(&cfg)
+			_, err := New(cfg)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
@@ -12,28 +13,444 @@ import (
 )
 
 type Config struct {
+	// Env names the deployment environment (APP_ENV), e.g. "dev".
+	Env       string          `mapstructure:"env"`
 	Logging   LoggingConfig   `mapstructure:"logging"`
 	Telemetry TelemetryConfig `mapstructure:"telemetry"`
 	Server    ServerConfig    `mapstructure:"server"`
+	Audit     AuditConfig     `mapstructure:"audit"`
+	Lambda    LambdaConfig    `mapstructure:"lambda"`
+	Extension ExtensionConfig `mapstructure:"extension"`
+	Auth      AuthConfig      `mapstructure:"auth"`
+	Authz     AuthzConfig     `mapstructure:"authz"`
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+	CORS      CORSConfig      `mapstructure:"cors"`
+	Security  SecurityConfig  `mapstructure:"security"`
+	IPFilter  IPFilterConfig  `mapstructure:"ip_filter"`
+	Tenancy   TenancyConfig   `mapstructure:"tenancy"`
 }
 
 type ServerConfig struct {
-	Host         string        `mapstructure:"host"`
-	Port         int           `mapstructure:"port"`
-	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
-	WriteTimeout time.Duration `mapstructure:"write_timeout"`
-	IdleTimeout  time.Duration `mapstructure:"idle_timeout"`
+	Host         string            `mapstructure:"host"`
+	Port         int               `mapstructure:"port"`
+	ReadTimeout  time.Duration     `mapstructure:"read_timeout"`
+	WriteTimeout time.Duration     `mapstructure:"write_timeout"`
+	IdleTimeout  time.Duration     `mapstructure:"idle_timeout"`
+	Concurrency  ConcurrencyConfig `mapstructure:"concurrency"`
+	// TrustedProxies are the addresses or CIDRs of proxies whose
+	// X-Forwarded-For and X-Real-IP headers are believed.
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+}
+
+// ConcurrencyConfig configures the adaptive limit on requests the HTTP
+// server handles at once. Requests over the limit are shed with a 503.
+type ConcurrencyConfig struct {
+	// Algorithm is aimd or gradient.
+	Algorithm string `mapstructure:"algorithm"`
+	// CriticalPaths are never shed. A path ending in / matches every path
+	// below it.
+	CriticalPaths []string `mapstructure:"critical_paths"`
+	InitialLimit  int      `mapstructure:"initial_limit"`
+	MinLimit      int      `mapstructure:"min_limit"`
+	MaxLimit      int      `mapstructure:"max_limit"`
+	// LatencyThreshold is the latency above which aimd backs off.
+	LatencyThreshold time.Duration `mapstructure:"latency_threshold"`
+	// Backoff is the factor aimd multiplies the limit by to back off.
+	Backoff float64 `mapstructure:"backoff"`
+	// Tolerance is how many times its usual latency gradient accepts
+	// before it lowers the limit.
+	Tolerance float64 `mapstructure:"tolerance"`
+	// RetryAfter is sent to shed clients.
+	RetryAfter time.Duration `mapstructure:"retry_after"`
+	Enabled    bool          `mapstructure:"enabled"`
 }
 
 type LoggingConfig struct {
-	Level  string `mapstructure:"level"`
-	Format string `mapstructure:"format"`
+	Level     string          `mapstructure:"level"`
+	Format    string          `mapstructure:"format"`
+	Redaction RedactionConfig `mapstructure:"redaction"`
+	Sampling  SamplingConfig  `mapstructure:"sampling"`
+	// Sinks lists the outputs records fan out to. When empty, records are
+	// written to stdout using Level and Format.
+	Sinks []SinkConfig `mapstructure:"sinks"`
+}
+
+// SinkConfig describes a single log output. Format and Level default to the
+// top-level logging settings.
+type SinkConfig struct {
+	// Type is one of stdout, stderr, file, or syslog.
+	Type   string           `mapstructure:"type"`
+	Format string           `mapstructure:"format"`
+	Level  string           `mapstructure:"level"`
+	File   FileSinkConfig   `mapstructure:"file"`
+	Syslog SyslogSinkConfig `mapstructure:"syslog"`
+}
+
+type FileSinkConfig struct {
+	Path       string        `mapstructure:"path"`
+	MaxSizeMB  int           `mapstructure:"max_size_mb"`
+	MaxAge     time.Duration `mapstructure:"max_age"`
+	MaxBackups int           `mapstructure:"max_backups"`
+	Compress   bool          `mapstructure:"compress"`
+}
+
+type SyslogSinkConfig struct {
+	// Socket is the path of the local syslog unix socket, e.g. /dev/log.
+	Socket string `mapstructure:"socket"`
+	Tag    string `mapstructure:"tag"`
+}
+
+// RedactionConfig controls scrubbing of sensitive values before log records
+// reach a sink.
+type RedactionConfig struct {
+	// Fields are attribute keys whose values are always redacted (case-insensitive).
+	Fields []string `mapstructure:"fields"`
+	// Patterns are names of built-in detectors: email, token, card.
+	Patterns []string `mapstructure:"patterns"`
+	// CustomPatterns maps a detector name to a regular expression.
+	CustomPatterns map[string]string `mapstructure:"custom_patterns"`
+	// Mode is either "mask" or "hash".
+	Mode string `mapstructure:"mode"`
+	// HashSalt keys the HMAC used by hash mode and by logged fingerprints.
+	// When empty, a random key is generated at startup.
+	HashSalt string `mapstructure:"hash_salt"`
+	Enabled  bool   `mapstructure:"enabled"`
+}
+
+// SamplingConfig bounds how many identical log records (same level and
+// message) are written per interval. Records logged with a context from
+// logging.WithoutSampling are always written.
+type SamplingConfig struct {
+	// Levels overrides the default rule for a level (debug, info, warn, error).
+	Levels     map[string]SamplingRule `mapstructure:"levels"`
+	Interval   time.Duration           `mapstructure:"interval"`
+	First      int                     `mapstructure:"first"`
+	Thereafter int                     `mapstructure:"thereafter"`
+	Enabled    bool                    `mapstructure:"enabled"`
+}
+
+// SamplingRule keeps the first First records per interval and then every
+// Thereafter-th one. A Thereafter of zero drops everything after First.
+type SamplingRule struct {
+	First      int  `mapstructure:"first"`
+	Thereafter int  `mapstructure:"thereafter"`
+	Exempt     bool `mapstructure:"exempt"`
+}
+
+// AuditConfig controls the security audit trail, which is written separately
+// from diagnostic logs.
+type AuditConfig struct {
+	// Sink is either "file", the default, or "stdout".
+	Sink string `mapstructure:"sink"`
+	Path string `mapstructure:"path"`
+	// Key is the hex encoded HMAC key of the record chain. Keep it out of
+	// reach of whoever can write the log.
+	Key     string `mapstructure:"key"`
+	Enabled bool   `mapstructure:"enabled"`
+}
+
+// LambdaConfig holds settings that only apply when running under AWS Lambda.
+type LambdaConfig struct {
+	// Mode is "buffered" (default) or "streaming". Streaming serves only
+	// Function URL events with the RESPONSE_STREAM invoke mode.
+	Mode string `mapstructure:"mode"`
+	// Destination receives echo results from SQS, SNS and EventBridge triggers.
+	Destination DestinationConfig `mapstructure:"destination"`
+	// FlushTimeout bounds the telemetry flush at the end of each invocation.
+	FlushTimeout time.Duration `mapstructure:"flush_timeout"`
+}
+
+// DestinationConfig selects where asynchronously produced results are sent.
+type DestinationConfig struct {
+	// Type is one of none, log, or http.
+	Type    string        `mapstructure:"type"`
+	URL     string        `mapstructure:"url"`
+	Timeout time.Duration `mapstructure:"timeout"`
 }
 
 type TelemetryConfig struct {
 	ServiceName    string `mapstructure:"service_name"`
 	ServiceVersion string `mapstructure:"service_version"`
-	Enabled        bool   `mapstructure:"enabled"`
+	// Exporter is "stdout" (default), "otlp", which sends OTLP/HTTP to
+	// Endpoint, e.g. http://localhost:4318 for the Lambda extension, or
+	// "emf", which writes metrics to stdout in CloudWatch Embedded Metric
+	// Format and drops spans.
+	Exporter string `mapstructure:"exporter"`
+	Endpoint string `mapstructure:"endpoint"`
+	Enabled  bool   `mapstructure:"enabled"`
+}
+
+// ExtensionConfig configures the telemetry Lambda extension, which receives
+// the function's telemetry locally and exports it after each response.
+type ExtensionConfig struct {
+	// Endpoint is the OTLP/HTTP base URL telemetry is exported to.
+	Endpoint string `mapstructure:"endpoint"`
+	// ListenAddr is where the function and the Telemetry API deliver to.
+	ListenAddr    string        `mapstructure:"listen_addr"`
+	ExportTimeout time.Duration `mapstructure:"export_timeout"`
+}
+
+// AuthConfig controls API authentication. Operations are public or
+// protected, and need which scopes, as declared in api/openapi.yml.
+type AuthConfig struct {
+	// APIKeys is the key store. Entries are created with
+	// "hello-go apikey generate"; secrets themselves are never stored.
+	APIKeys []APIKeyConfig `mapstructure:"api_keys"`
+	JWT     JWTConfig      `mapstructure:"jwt"`
+	Signing SigningConfig  `mapstructure:"signing"`
+	Enabled bool           `mapstructure:"enabled"`
+	// GatewayAuthorizer accepts the caller an API Gateway authorizer
+	// established, under Lambda, in place of the request's credentials.
+	// Enable it only when every route of the API has an authorizer.
+	GatewayAuthorizer bool `mapstructure:"gateway_authorizer"`
+}
+
+// HasCredentials reports whether any way for callers to authenticate is
+// configured. With none, every protected operation would be refused.
+func (a AuthConfig) HasCredentials() bool {
+	return len(a.APIKeys) > 0 || a.JWT.Enabled || (a.Signing.Enabled && len(a.Signing.Keys) > 0) || a.GatewayAuthorizer
+}
+
+// JWTConfig controls validation of JWT bearer tokens, such as OIDC access
+// tokens, alongside API keys.
+type JWTConfig struct {
+	// JWKSURL or JWKSFile is where the signing keys are read from.
+	JWKSURL  string `mapstructure:"jwks_url"`
+	JWKSFile string `mapstructure:"jwks_file"`
+	// Issuer and Audience must match the iss and aud claims.
+	Issuer   string `mapstructure:"issuer"`
+	Audience string `mapstructure:"audience"`
+	// Algorithms defaults to RS256, ES256 and EdDSA.
+	Algorithms []string `mapstructure:"algorithms"`
+	// PrincipalClaim names the claim identifying the caller; defaults to sub.
+	PrincipalClaim string `mapstructure:"principal_claim"`
+	// ClockSkew is the leeway allowed on exp, nbf and iat.
+	ClockSkew time.Duration `mapstructure:"clock_skew"`
+	// RefreshInterval is how long fetched keys are used before refetching.
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"`
+	// RefreshBackoff is the minimum gap between refetches, doubled after
+	// each failure.
+	RefreshBackoff time.Duration `mapstructure:"refresh_backoff"`
+	Enabled        bool          `mapstructure:"enabled"`
+}
+
+type APIKeyConfig struct {
+	ID string `mapstructure:"id"`
+	// Principal identifies the caller in logs and traces; defaults to ID.
+	Principal string   `mapstructure:"principal"`
+	Scopes    []string `mapstructure:"scopes"`
+	Salt      string   `mapstructure:"salt"`
+	Hash      string   `mapstructure:"hash"`
+	// Tenant is the tenant the key's owner belongs to, if any.
+	Tenant string `mapstructure:"tenant"`
+}
+
+// SigningConfig controls HMAC request signing for machine clients that
+// cannot use OAuth. Only operations that list the hmacSignature scheme in
+// api/openapi.yml accept signed requests.
+type SigningConfig struct {
+	Keys []SigningKeyConfig `mapstructure:"keys"`
+	// NonceStore is where seen nonces are tracked; only "memory" for now.
+	NonceStore string `mapstructure:"nonce_store"`
+	// ReplayWindow is how far a request's timestamp may be from now; its
+	// nonce is remembered for as long.
+	ReplayWindow time.Duration `mapstructure:"replay_window"`
+	Enabled      bool          `mapstructure:"enabled"`
+}
+
+type SigningKeyConfig struct {
+	ID string `mapstructure:"id"`
+	// Principal identifies the caller in logs and traces; defaults to ID.
+	Principal string   `mapstructure:"principal"`
+	Scopes    []string `mapstructure:"scopes"`
+	// Secret is the hex encoded shared secret. Keep it out of version
+	// control, e.g. in configs/private.yml.
+	Secret string `mapstructure:"secret"`
+	// Tenant is the tenant the key's owner belongs to, if any.
+	Tenant string `mapstructure:"tenant"`
+}
+
+// AuthzConfig controls authorization of authenticated callers by a policy
+// of roles and allow/deny rules keyed by OpenAPI operation ID.
+type AuthzConfig struct {
+	PolicyFile string `mapstructure:"policy_file"`
+	Enabled    bool   `mapstructure:"enabled"`
+}
+
+// SecurityConfig hardens responses and bounds requests.
+type SecurityConfig struct {
+	Headers  SecurityHeadersConfig `mapstructure:"headers"`
+	Requests RequestLimitsConfig   `mapstructure:"requests"`
+}
+
+// SecurityHeadersConfig sets security headers on every response. Empty
+// values leave a header out.
+type SecurityHeadersConfig struct {
+	ContentSecurityPolicy string `mapstructure:"content_security_policy"`
+	ReferrerPolicy        string `mapstructure:"referrer_policy"`
+	// StripHeaders are removed from every response, such as Server and
+	// X-Powered-By, so as not to advertise what serves it.
+	StripHeaders []string   `mapstructure:"strip_headers"`
+	HSTS         HSTSConfig `mapstructure:"hsts"`
+	// NoSniff sets X-Content-Type-Options: nosniff.
+	NoSniff bool `mapstructure:"nosniff"`
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// HSTSConfig configures Strict-Transport-Security; a zero MaxAge leaves it
+// out.
+type HSTSConfig struct {
+	MaxAge            time.Duration `mapstructure:"max_age"`
+	IncludeSubdomains bool          `mapstructure:"include_subdomains"`
+	Preload           bool          `mapstructure:"preload"`
+}
+
+// RequestLimitsConfig bounds the size and shape of requests.
+type RequestLimitsConfig struct {
+	// Operations override MaxBodyBytes for the operations they name.
+	Operations     map[string]OperationLimitsConfig `mapstructure:"operations"`
+	MaxBodyBytes   int64                            `mapstructure:"max_body_bytes"`
+	MaxHeaderCount int                              `mapstructure:"max_header_count"`
+	MaxHeaderBytes int                              `mapstructure:"max_header_bytes"`
+	// StrictContentType rejects request bodies whose Content-Type is not
+	// one the operation declares in api/openapi.yml.
+	StrictContentType bool `mapstructure:"strict_content_type"`
+	Enabled           bool `mapstructure:"enabled"`
+}
+
+// OperationLimitsConfig are the request limits of one operation.
+type OperationLimitsConfig struct {
+	MaxBodyBytes int64 `mapstructure:"max_body_bytes"`
+}
+
+// TenancyConfig controls how API requests are attributed to tenants and
+// what each tenant may do.
+type TenancyConfig struct {
+	Tenants map[string]TenantConfig `mapstructure:"tenants"`
+	// Sources are tried in order, from header, subdomain, api_key and
+	// jwt_claim; all but subdomain by default. The tenant of a caller's
+	// credentials always wins; a header or subdomain naming another
+	// tenant is rejected, as is one naming a tenant the caller is not
+	// bound to while auth is enabled.
+	Sources []string `mapstructure:"sources"`
+	// Header carries the tenant for the header source.
+	Header string `mapstructure:"header"`
+	// Domain is the parent of tenant subdomains for the subdomain source,
+	// e.g. api.example.com for team-a.api.example.com.
+	Domain string `mapstructure:"domain"`
+	// Claim is the JWT claim naming the tenant for the jwt_claim source.
+	Claim string `mapstructure:"claim"`
+	// DefaultTenant serves requests no source names a tenant for; when
+	// empty, they are rejected.
+	DefaultTenant string `mapstructure:"default_tenant"`
+	Enabled       bool   `mapstructure:"enabled"`
+}
+
+// TenantConfig holds the settings of one tenant.
+type TenantConfig struct {
+	// Features switches operations, by operation ID, on or off. Operations
+	// not listed are on.
+	Features  map[string]bool       `mapstructure:"features"`
+	RateLimit TenantRateLimitConfig `mapstructure:"rate_limit"`
+	// Principals may name the tenant by header or subdomain when their
+	// credentials are not bound to a tenant. Without them, only callers
+	// bound to the tenant may do so while auth is enabled.
+	Principals []string `mapstructure:"principals"`
+	// MaxMessageBytes bounds echo messages; zero leaves them unbounded but
+	// for the request body limit.
+	MaxMessageBytes int `mapstructure:"max_message_bytes"`
+}
+
+// TenantRateLimitConfig overrides the rate limits of a tenant. Rules left
+// zero fall back to the rate_limit section.
+type TenantRateLimitConfig struct {
+	Operations map[string]RateLimitRule `mapstructure:"operations"`
+	Default    RateLimitRule            `mapstructure:"default"`
+}
+
+// IPFilterConfig controls which client addresses may reach each route
+// group.
+type IPFilterConfig struct {
+	Lists map[string]IPListConfig `mapstructure:"lists"`
+	// Groups, keyed by route group (api or health), name the lists that
+	// apply to them.
+	Groups  map[string]IPFilterRule `mapstructure:"groups"`
+	Enabled bool                    `mapstructure:"enabled"`
+}
+
+// IPListConfig is a named list of addresses and CIDRs, IPv4 or IPv6.
+type IPListConfig struct {
+	// File holds one address or CIDR per line, with # comments. It is
+	// reloaded when it changes.
+	File  string   `mapstructure:"file"`
+	CIDRs []string `mapstructure:"cidrs"`
+}
+
+// IPFilterRule names the lists applied to a route group. Addresses on a
+// Deny list are rejected; if there are Allow lists, addresses must be on
+// one of them.
+type IPFilterRule struct {
+	Allow []string `mapstructure:"allow"`
+	Deny  []string `mapstructure:"deny"`
+}
+
+// CORSConfig controls which browser origins may call the API.
+type CORSConfig struct {
+	// Routes replace Default for the operations they name.
+	Routes  map[string]CORSPolicy `mapstructure:"routes"`
+	Default CORSPolicy            `mapstructure:"default"`
+	Enabled bool                  `mapstructure:"enabled"`
+}
+
+// CORSPolicy is the CORS policy of one or more operations.
+type CORSPolicy struct {
+	// AllowedOrigins are exact origins, "*", or wildcard subdomains such as
+	// https://*.example.com.
+	AllowedOrigins []string `mapstructure:"allowed_origins"`
+	// AllowedOriginPatterns are regular expressions an entire origin may
+	// match.
+	AllowedOriginPatterns []string      `mapstructure:"allowed_origin_patterns"`
+	AllowedMethods        []string      `mapstructure:"allowed_methods"`
+	AllowedHeaders        []string      `mapstructure:"allowed_headers"`
+	ExposedHeaders        []string      `mapstructure:"exposed_headers"`
+	MaxAge                time.Duration `mapstructure:"max_age"`
+	AllowCredentials      bool          `mapstructure:"allow_credentials"`
+}
+
+// RateLimitConfig controls per-client token bucket rate limiting.
+type RateLimitConfig struct {
+	// Operations overrides Default per OpenAPI operation ID.
+	Operations map[string]RateLimitRule `mapstructure:"operations"`
+	// Key is what clients are told apart by: "api_key", "principal" or
+	// "ip". Unauthenticated requests are always keyed by IP.
+	Key string `mapstructure:"key"`
+	// Backend is "memory", per instance, or "redis", shared.
+	Backend string        `mapstructure:"backend"`
+	Redis   RedisConfig   `mapstructure:"redis"`
+	Default RateLimitRule `mapstructure:"default"`
+	// AuthFailures limits failed authentication attempts per source IP,
+	// counted before credentials are checked; zero requests disables it.
+	AuthFailures RateLimitRule `mapstructure:"auth_failures"`
+	Enabled      bool          `mapstructure:"enabled"`
+}
+
+// RateLimitRule is a token bucket refilled with Requests tokens every
+// Period and holding at most Burst.
+type RateLimitRule struct {
+	Requests int           `mapstructure:"requests"`
+	Period   time.Duration `mapstructure:"period"`
+	// Burst defaults to Requests.
+	Burst int `mapstructure:"burst"`
+}
+
+// RedisConfig points at a Redis-compatible server.
+type RedisConfig struct {
+	Addr     string `mapstructure:"addr"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	// KeyPrefix namespaces the keys this service writes.
+	KeyPrefix string `mapstructure:"key_prefix"`
+	DB        int    `mapstructure:"db"`
 }
 
 func Load() (*Config, error) {
@@ -43,16 +460,30 @@ func Load() (*Config, error) {
 	if err := k.Load(file.Provider("./configs/default.yml"), yaml.Parser()); err != nil {
 		return nil, fmt.Errorf("failed to load default config: %w", err)
 	}
+	// Environment-specific defaults, e.g. configs/dev.yml when APP_ENV=dev
+	if env := os.Getenv("APP_ENV"); env != "" {
+		_ = k.Load(file.Provider(fmt.Sprintf("./configs/%s.yml", env)), yaml.Parser())
+	}
 	_ = k.Load(file.Provider("./configs/local.yml"), yaml.Parser())
 	_ = k.Load(file.Provider("./configs/private.yml"), yaml.Parser())
 
-	// Load environment variables
+	// Load environment variables: APP_SERVER_PORT sets server.port. Names
+	// are matched against the keys loaded so far, so that keys with
+	// underscores can be set too, e.g. APP_SECURITY_REQUESTS_MAX_BODY_BYTES.
+	keys := make(map[string]string)
+	for _, key := range k.Keys() {
+		keys[strings.ReplaceAll(key, ".", "_")] = key
+	}
 	provider := env.Provider(
 		".",
 		env.Opt{
 			Prefix: "APP_",
 			TransformFunc: func(k, v string) (string, any) {
-				return strings.ReplaceAll(strings.ToLower(k), "_", "."), v
+				name := strings.ToLower(strings.TrimPrefix(k, "APP_"))
+				if key, ok := keys[name]; ok {
+					return key, v
+				}
+				return strings.ReplaceAll(name, "_", "."), v
 			},
 		},
 	)
@@ -62,7 +493,7 @@ func Load() (*Config, error) {
 	}
 
 	var cfg Config
-	if err := k.Unmarshal("", &cfg); err != nil {
+	if err := k.UnmarshalWithConf("", &cfg, koanf.UnmarshalConf{Tag: "mapstructure"}); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 	return &cfg, nil
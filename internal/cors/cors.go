@@ -0,0 +1,219 @@
+// Package cors decides which browser origins may call which operations,
+// and with which methods and headers.
+package cors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/savisec/hello-go/internal/config"
+)
+
+// defaultMethods are allowed when a policy names none; they are the CORS
+// safelisted methods.
+var defaultMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+
+// Policy is a compiled config.CORSPolicy.
+type Policy struct {
+	exact            map[string]bool
+	patterns         []*regexp.Regexp
+	methods          []string
+	headers          map[string]bool
+	allowedHeaders   string
+	exposedHeaders   string
+	maxAge           time.Duration
+	anyOrigin        bool
+	anyHeader        bool
+	allowCredentials bool
+}
+
+// NewPolicy compiles cfg. Wildcard subdomains match one or more labels:
+// https://*.example.com matches https://app.example.com and
+// https://a.b.example.com but not https://example.com. An allowed origin
+// of * cannot be combined with credentials, which would let every site
+// make requests with the user's cookies and read the responses.
+func NewPolicy(cfg config.CORSPolicy) (*Policy, error) {
+	p := &Policy{
+		exact:            make(map[string]bool),
+		headers:          make(map[string]bool),
+		maxAge:           cfg.MaxAge,
+		allowCredentials: cfg.AllowCredentials,
+	}
+
+	for _, origin := range cfg.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+		switch {
+		case origin == "*":
+			p.anyOrigin = true
+		case strings.Contains(origin, "*"):
+			scheme, host, ok := strings.Cut(origin, "://*.")
+			if !ok || strings.Contains(host, "*") {
+				return nil, fmt.Errorf("invalid wildcard origin %q: only a leading *. in the host is supported", origin)
+			}
+			pattern := "^" + regexp.QuoteMeta(scheme) + `://([a-z0-9-]+\.)+` + regexp.QuoteMeta(host) + "$"
+			p.patterns = append(p.patterns, regexp.MustCompile(pattern))
+		default:
+			p.exact[origin] = true
+		}
+	}
+
+	if p.anyOrigin && p.allowCredentials {
+		return nil, errors.New("allowed origin * cannot be combined with allow_credentials; list the origins instead")
+	}
+
+	for _, expr := range cfg.AllowedOriginPatterns {
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid origin pattern %q: %w", expr, err)
+		}
+		p.patterns = append(p.patterns, re)
+	}
+
+	p.methods = defaultMethods
+	if len(cfg.AllowedMethods) > 0 {
+		p.methods = make([]string, 0, len(cfg.AllowedMethods))
+		for _, method := range cfg.AllowedMethods {
+			p.methods = append(p.methods, strings.ToUpper(method))
+		}
+	}
+
+	for _, header := range cfg.AllowedHeaders {
+		if header == "*" {
+			p.anyHeader = true
+			continue
+		}
+		p.headers[http.CanonicalHeaderKey(header)] = true
+	}
+	p.allowedHeaders = strings.Join(cfg.AllowedHeaders, ", ")
+	p.exposedHeaders = strings.Join(cfg.ExposedHeaders, ", ")
+
+	return p, nil
+}
+
+// AllowsOrigin reports whether origin may call the operations of p.
+func (p *Policy) AllowsOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if p.exact[origin] {
+		return true
+	}
+	for _, re := range p.patterns {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsMethod reports whether a cross-origin request may use method.
+func (p *Policy) AllowsMethod(method string) bool {
+	return slices.Contains(p.methods, method)
+}
+
+// AllowsHeaders reports whether a cross-origin request may send headers,
+// the comma-separated value of Access-Control-Request-Headers.
+func (p *Policy) AllowsHeaders(headers string) bool {
+	if p.anyHeader {
+		return true
+	}
+	for header := range strings.SplitSeq(headers, ",") {
+		header = strings.TrimSpace(header)
+		if header != "" && !p.headers[http.CanonicalHeaderKey(header)] {
+			return false
+		}
+	}
+	return true
+}
+
+// Preflight sets the headers answering a preflight request from origin that
+// asks to send requestHeaders. The caller has checked it is allowed.
+func (p *Policy) Preflight(h http.Header, origin, requestHeaders string) {
+	p.allowOrigin(h, origin)
+	h.Set("Access-Control-Allow-Methods", strings.Join(p.methods, ", "))
+
+	allowed := p.allowedHeaders
+	if p.anyHeader && p.allowCredentials {
+		// A literal * is not a wildcard for credentialed requests.
+		allowed = requestHeaders
+	}
+	if allowed != "" {
+		h.Set("Access-Control-Allow-Headers", allowed)
+	}
+	if p.maxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(int(p.maxAge.Seconds())))
+	}
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+}
+
+// Actual sets the headers on the response to an allowed request from
+// origin.
+func (p *Policy) Actual(h http.Header, origin string) {
+	p.allowOrigin(h, origin)
+	if p.exposedHeaders != "" {
+		h.Set("Access-Control-Expose-Headers", p.exposedHeaders)
+	}
+}
+
+func (p *Policy) allowOrigin(h http.Header, origin string) {
+	if p.anyOrigin {
+		h.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	h.Set("Access-Control-Allow-Origin", origin)
+	if p.allowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// Policies holds the default policy and the policies of operations that
+// override it.
+type Policies struct {
+	def    *Policy
+	routes map[string]*Policy
+}
+
+// New compiles the policies in cfg.
+func New(cfg config.CORSConfig) (*Policies, error) {
+	def, err := NewPolicy(cfg.Default)
+	if err != nil {
+		return nil, fmt.Errorf("default cors policy: %w", err)
+	}
+
+	routes := make(map[string]*Policy, len(cfg.Routes))
+	for id, rc := range cfg.Routes {
+		if routes[id], err = NewPolicy(rc); err != nil {
+			return nil, fmt.Errorf("cors policy for %s: %w", id, err)
+		}
+	}
+	return &Policies{def: def, routes: routes}, nil
+}
+
+// For returns the policy of an operation; an empty ID gets the default.
+func (ps *Policies) For(operationID string) *Policy {
+	if p, ok := ps.routes[operationID]; ok {
+		return p
+	}
+	return ps.def
+}
+
+// Operations lists the operations with a policy of their own.
+func (ps *Policies) Operations() []string {
+	ids := make([]string, 0, len(ps.routes))
+	for id := range ps.routes {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
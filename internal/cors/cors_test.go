@@ -0,0 +1,128 @@
+package cors
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/internal/config"
+)
+
+func TestPolicy_AllowsOrigin(t *testing.T) {
+	p, err := NewPolicy(config.CORSPolicy{
+		AllowedOrigins:        []string{"https://app.example.com", "https://*.example.org"},
+		AllowedOriginPatterns: []string{`https://pr-\d+\.preview\.example\.net`},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{origin: "https://app.example.com", want: true},
+		{origin: "https://APP.example.com", want: true},
+		{origin: "http://app.example.com"},
+		{origin: "https://app.example.com.evil.test"},
+		{origin: "https://a.example.org", want: true},
+		{origin: "https://a.b.example.org", want: true},
+		{origin: "https://example.org"},
+		{origin: "https://evilexample.org"},
+		{origin: "https://pr-42.preview.example.net", want: true},
+		{origin: "https://pr-42.preview.example.net.evil.test"},
+		{origin: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			assert.Equal(t, tt.want, p.AllowsOrigin(tt.origin))
+		})
+	}
+}
+
+func TestPolicy_Preflight(t *testing.T) {
+	p, err := NewPolicy(config.CORSPolicy{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{"post"},
+		AllowedHeaders: []string{"Content-Type", "X-API-Key"},
+		MaxAge:         10 * time.Minute,
+	})
+	require.NoError(t, err)
+
+	assert.True(t, p.AllowsMethod(http.MethodPost))
+	assert.False(t, p.AllowsMethod(http.MethodDelete))
+	assert.True(t, p.AllowsHeaders("content-type, x-api-key"))
+	assert.True(t, p.AllowsHeaders(""))
+	assert.False(t, p.AllowsHeaders("content-type, x-other"))
+
+	h := http.Header{}
+	p.Preflight(h, "https://app.example.com", "content-type")
+	assert.Equal(t, "https://app.example.com", h.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "POST", h.Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type, X-API-Key", h.Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", h.Get("Access-Control-Max-Age"))
+	assert.Empty(t, h.Get("Access-Control-Allow-Credentials"))
+}
+
+func TestPolicy_Wildcards(t *testing.T) {
+	tests := []struct {
+		name            string
+		origin          string
+		wantOrigin      string
+		wantHeaders     string
+		wantCredentials string
+		credentials     bool
+	}{
+		{name: "without credentials", origin: "*", wantOrigin: "*", wantHeaders: "*"},
+		{
+			name: "with credentials", origin: "https://*.example.com", credentials: true,
+			wantOrigin: "https://app.example.com", wantHeaders: "x-api-key", wantCredentials: "true",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPolicy(config.CORSPolicy{
+				AllowedOrigins:   []string{tt.origin},
+				AllowedHeaders:   []string{"*"},
+				AllowCredentials: tt.credentials,
+			})
+			require.NoError(t, err)
+			assert.True(t, p.AllowsOrigin("https://app.example.com"))
+			assert.True(t, p.AllowsHeaders("x-anything"))
+
+			h := http.Header{}
+			p.Preflight(h, "https://app.example.com", "x-api-key")
+			assert.Equal(t, tt.wantOrigin, h.Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, tt.wantHeaders, h.Get("Access-Control-Allow-Headers"))
+			assert.Equal(t, tt.wantCredentials, h.Get("Access-Control-Allow-Credentials"))
+		})
+	}
+}
+
+func TestNew_Routes(t *testing.T) {
+	ps, err := New(config.CORSConfig{
+		Default: config.CORSPolicy{AllowedOrigins: []string{"https://app.example.com"}},
+		Routes: map[string]config.CORSPolicy{
+			"echo": {AllowedOrigins: []string{"https://partner.example.com"}},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.True(t, ps.For("echo").AllowsOrigin("https://partner.example.com"))
+	assert.False(t, ps.For("echo").AllowsOrigin("https://app.example.com"), "routes replace the default")
+	assert.True(t, ps.For("healthz").AllowsOrigin("https://app.example.com"))
+	assert.True(t, ps.For("").AllowsOrigin("https://app.example.com"))
+	assert.Equal(t, []string{"echo"}, ps.Operations())
+
+	_, err = New(config.CORSConfig{Default: config.CORSPolicy{AllowedOrigins: []string{"https://app.*.com"}}})
+	assert.Error(t, err)
+
+	_, err = New(config.CORSConfig{Routes: map[string]config.CORSPolicy{"echo": {AllowedOriginPatterns: []string{"("}}}})
+	assert.Error(t, err)
+
+	_, err = New(config.CORSConfig{Default: config.CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true}})
+	assert.ErrorContains(t, err, "cannot be combined with allow_credentials")
+}
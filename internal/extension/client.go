@@ -0,0 +1,162 @@
+package extension
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	extensionAPIVersion = "2020-01-01"
+	telemetryAPIVersion = "2022-07-01"
+
+	extensionNameHeader       = "Lambda-Extension-Name"
+	extensionIdentifierHeader = "Lambda-Extension-Identifier"
+)
+
+// EventType is a lifecycle event delivered by the Extensions API.
+type EventType string
+
+const (
+	EventInvoke   EventType = "INVOKE"
+	EventShutdown EventType = "SHUTDOWN"
+)
+
+// Event is the payload returned by the Extensions API's next-event call.
+type Event struct {
+	EventType      EventType `json:"eventType"`
+	RequestID      string    `json:"requestId"`
+	ShutdownReason string    `json:"shutdownReason"`
+	DeadlineMs     int64     `json:"deadlineMs"`
+}
+
+// Deadline is the time by which the invocation or shutdown must finish.
+func (e Event) Deadline() time.Time {
+	return time.UnixMilli(e.DeadlineMs)
+}
+
+// Client talks to the Extensions and Telemetry APIs.
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	id         string
+}
+
+// NewClient creates a Client for the runtime API host in
+// AWS_LAMBDA_RUNTIME_API.
+func NewClient(runtimeAPI string) *Client {
+	return &Client{
+		// No timeout: the next-event call blocks until the next invocation.
+		httpClient: &http.Client{},
+		baseURL:    "http://" + runtimeAPI,
+	}
+}
+
+// Register registers the extension under name, which must match its file
+// name in /opt/extensions, for the given lifecycle events.
+func (c *Client) Register(ctx context.Context, name string, events ...EventType) error {
+	body, err := json.Marshal(map[string][]EventType{"events": events})
+	if err != nil {
+		return fmt.Errorf("failed to encode registration: %w", err)
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, c.baseURL+"/"+extensionAPIVersion+"/extension/register", body,
+		map[string]string{extensionNameHeader: name})
+	if err != nil {
+		return fmt.Errorf("failed to register extension: %w", err)
+	}
+
+	c.id = resp.header.Get(extensionIdentifierHeader)
+	if c.id == "" {
+		return fmt.Errorf("failed to register extension: no %s in response", extensionIdentifierHeader)
+	}
+	return nil
+}
+
+// Next blocks until the next lifecycle event.
+func (c *Client) Next(ctx context.Context) (Event, error) {
+	resp, err := c.do(ctx, http.MethodGet, c.baseURL+"/"+extensionAPIVersion+"/extension/event/next", nil,
+		map[string]string{extensionIdentifierHeader: c.id})
+	if err != nil {
+		return Event{}, fmt.Errorf("failed to get next event: %w", err)
+	}
+
+	var event Event
+	if err := json.Unmarshal(resp.body, &event); err != nil {
+		return Event{}, fmt.Errorf("failed to decode event: %w", err)
+	}
+	return event, nil
+}
+
+// Subscribe asks the Telemetry API to deliver the given telemetry types,
+// e.g. "platform" and "function", to destination over HTTP.
+func (c *Client) Subscribe(ctx context.Context, destination string, types ...string) error {
+	body, err := json.Marshal(map[string]any{
+		"schemaVersion": "2022-12-13",
+		"types":         types,
+		"buffering": map[string]int{
+			"maxItems":  1000,
+			"maxBytes":  256 * 1024,
+			"timeoutMs": 25,
+		},
+		"destination": map[string]string{
+			"protocol": "HTTP",
+			"URI":      destination,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode subscription: %w", err)
+	}
+
+	if _, err := c.do(ctx, http.MethodPut, c.baseURL+"/"+telemetryAPIVersion+"/telemetry", body,
+		map[string]string{extensionIdentifierHeader: c.id}); err != nil {
+		return fmt.Errorf("failed to subscribe to telemetry: %w", err)
+	}
+	return nil
+}
+
+type response struct {
+	header http.Header
+	body   []byte
+}
+
+func (c *Client) do(ctx context.Context, method, url string, body []byte, headers map[string]string) (*response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	//nolint:errcheck
+	//goland:noinspection GoUnhandledErrorResult
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(respBody))
+	}
+
+	return &response{header: resp.Header, body: respBody}, nil
+}
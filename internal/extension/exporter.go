@@ -0,0 +1,151 @@
+package extension
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Exporter sends a Batch to its final destination.
+type Exporter interface {
+	Export(ctx context.Context, batch Batch) error
+}
+
+// ExportError reports the part of a batch an Exporter failed to send, so
+// that it can be retried.
+type ExportError struct {
+	Err    error
+	Failed Batch
+}
+
+func (e *ExportError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExportError) Unwrap() error {
+	return e.Err
+}
+
+// OTLPExporter forwards buffered OTLP requests to an OTLP/HTTP endpoint
+// unchanged, and converts function logs into an OTLP/JSON logs request.
+type OTLPExporter struct {
+	client      *http.Client
+	endpoint    string
+	serviceName string
+}
+
+// NewOTLPExporter creates an OTLPExporter for the OTLP/HTTP base URL
+// endpoint, e.g. https://collector.example.com.
+func NewOTLPExporter(endpoint, serviceName string) (*OTLPExporter, error) {
+	if endpoint == "" {
+		return nil, errors.New("extension endpoint is required")
+	}
+
+	return &OTLPExporter{
+		client:      &http.Client{},
+		endpoint:    strings.TrimSuffix(endpoint, "/"),
+		serviceName: serviceName,
+	}, nil
+}
+
+// Export posts every payload in batch, continuing past failures so that
+// one rejected signal does not drop the others. When anything fails, the
+// error is an *ExportError holding what was not sent.
+func (e *OTLPExporter) Export(ctx context.Context, batch Batch) error {
+	var (
+		errs   []error
+		failed Batch
+	)
+
+	for _, p := range batch.Payloads {
+		if err := e.post(ctx, p.Signal, p.ContentType, p.Body); err != nil {
+			errs = append(errs, err)
+			failed.Payloads = append(failed.Payloads, p)
+		}
+	}
+
+	if len(batch.Logs) > 0 {
+		body, err := json.Marshal(e.logsRequest(batch.Logs))
+		if err != nil {
+			// Encoding fails the same way every time, so there is no point
+			// retrying these.
+			errs = append(errs, fmt.Errorf("failed to encode logs: %w", err))
+		} else if err := e.post(ctx, SignalLogs, "application/json", body); err != nil {
+			errs = append(errs, err)
+			failed.Logs = batch.Logs
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ExportError{Err: errors.Join(errs...), Failed: failed}
+}
+
+func (e *OTLPExporter) post(ctx context.Context, signal Signal, contentType string, body []byte) error {
+	url := e.endpoint + "/v1/" + string(signal)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build %s export: %w", signal, err)
+	}
+	if contentType == "" {
+		contentType = "application/x-protobuf"
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export %s: %w", signal, err)
+	}
+
+	//nolint:errcheck
+	//goland:noinspection GoUnhandledErrorResult
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("failed to export %s: status %d", signal, resp.StatusCode)
+	}
+	return nil
+}
+
+// logsRequest builds an OTLP/JSON ExportLogsServiceRequest.
+func (e *OTLPExporter) logsRequest(logs []LogRecord) map[string]any {
+	records := make([]map[string]any, len(logs))
+	for i, log := range logs {
+		records[i] = map[string]any{
+			"timeUnixNano": strconv.FormatInt(log.Time.UnixNano(), 10),
+			"body":         map[string]string{"stringValue": log.Message},
+		}
+	}
+
+	return map[string]any{
+		"resourceLogs": []map[string]any{{
+			"resource": map[string]any{
+				"attributes": []map[string]any{{
+					"key":   "service.name",
+					"value": map[string]string{"stringValue": e.serviceName},
+				}},
+			},
+			"scopeLogs": []map[string]any{{
+				"scope":      map[string]string{"name": "hello-go/extension"},
+				"logRecords": records,
+			}},
+		}},
+	}
+}
+
+// exportWithTimeout bounds an export by timeout.
+func exportWithTimeout(ctx context.Context, exporter Exporter, batch Batch, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return exporter.Export(ctx, batch)
+}
@@ -0,0 +1,164 @@
+// Package extension implements an internal Lambda extension that takes the
+// function's telemetry off the request path. The function exports OTLP to
+// the extension over localhost; the extension buffers it, together with
+// function logs from the Telemetry API, and exports it to the real
+// collector once the response has been sent. Failed exports are kept, up
+// to a bound, and retried by the next flush. The SHUTDOWN event flushes
+// whatever is left.
+package extension
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/savisec/hello-go/internal/config"
+)
+
+const (
+	// telemetryHost is the hostname the Telemetry API requires in
+	// subscription destinations.
+	telemetryHost = "sandbox.localdomain"
+
+	defaultListenAddr    = ":4318"
+	defaultExportTimeout = time.Second
+)
+
+// Extension runs the extension lifecycle.
+type Extension struct {
+	client          *Client
+	exporter        Exporter
+	receiver        *receiver
+	logger          *slog.Logger
+	name            string
+	listenAddr      string
+	destinationHost string
+	exportTimeout   time.Duration
+}
+
+// New creates an Extension named name that talks to the runtime API host
+// runtimeAPI and sends buffered telemetry to exporter.
+func New(cfg config.ExtensionConfig, name, runtimeAPI string, exporter Exporter, logger *slog.Logger) *Extension {
+	listenAddr := cfg.ListenAddr
+	if listenAddr == "" {
+		listenAddr = defaultListenAddr
+	}
+
+	exportTimeout := cfg.ExportTimeout
+	if exportTimeout <= 0 {
+		exportTimeout = defaultExportTimeout
+	}
+
+	return &Extension{
+		client:          NewClient(runtimeAPI),
+		exporter:        exporter,
+		receiver:        newReceiver(logger),
+		logger:          logger,
+		name:            name,
+		listenAddr:      listenAddr,
+		destinationHost: telemetryHost,
+		exportTimeout:   exportTimeout,
+	}
+}
+
+// Run registers the extension and processes lifecycle events until
+// SHUTDOWN, or until ctx is cancelled.
+func (e *Extension) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", e.listenAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", e.listenAddr, err)
+	}
+
+	srv := &http.Server{
+		Handler:           e.receiver,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			e.logger.Error("Telemetry receiver stopped", "error", err)
+		}
+	}()
+
+	//nolint:errcheck
+	//goland:noinspection GoUnhandledErrorResult
+	defer srv.Close()
+
+	if err := e.client.Register(ctx, e.name, EventInvoke, EventShutdown); err != nil {
+		return err
+	}
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	destination := fmt.Sprintf("http://%s:%d%s", e.destinationHost, port, telemetryPath)
+	if err := e.client.Subscribe(ctx, destination, "platform", "function"); err != nil {
+		return err
+	}
+
+	e.logger.Info("Telemetry extension registered", "name", e.name, "addr", listener.Addr().String())
+
+	for {
+		event, err := e.client.Next(ctx)
+		if err != nil {
+			return err
+		}
+
+		switch event.EventType {
+		case EventInvoke:
+			// Calling Next again tells Lambda the extension is done, so the
+			// export has to happen in between: after the function has
+			// responded and before the environment is frozen.
+			e.awaitRuntimeDone(ctx, event)
+			e.flush(ctx)
+		case EventShutdown:
+			e.logger.Info("Telemetry extension shutting down", "reason", event.ShutdownReason)
+			e.flush(ctx)
+			return nil
+		}
+	}
+}
+
+// awaitRuntimeDone waits until the Telemetry API reports that the runtime
+// finished event's invocation, or until the invocation deadline.
+func (e *Extension) awaitRuntimeDone(ctx context.Context, event Event) {
+	timer := time.NewTimer(time.Until(event.Deadline()))
+	defer timer.Stop()
+
+	for {
+		select {
+		case requestID := <-e.receiver.done:
+			if requestID == event.RequestID {
+				return
+			}
+		case <-timer.C:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (e *Extension) flush(ctx context.Context) {
+	batch := e.receiver.drain()
+	if batch.Empty() {
+		return
+	}
+
+	err := exportWithTimeout(ctx, e.exporter, batch, e.exportTimeout)
+	if err == nil {
+		return
+	}
+
+	// drain has already emptied the buffer, so put back what was not sent
+	// for the next flush, or the one on SHUTDOWN, to retry.
+	failed := batch
+	var exportErr *ExportError
+	if errors.As(err, &exportErr) {
+		failed = exportErr.Failed
+	}
+	dropped := e.receiver.requeue(failed, maxRetryBytes)
+	e.logger.Error("Failed to export telemetry", "error", err,
+		"requeued", failed.Len()-dropped, "dropped", dropped)
+}
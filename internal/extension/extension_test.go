@@ -0,0 +1,296 @@
+package extension
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/internal/config"
+)
+
+type exported struct {
+	path string
+	body string
+}
+
+// collector is a stand-in OTLP/HTTP collector that records every request
+// it accepts. While unavailable is set, it rejects them all.
+type collector struct {
+	requests    []exported
+	mu          sync.Mutex
+	unavailable bool
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.unavailable {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	c.requests = append(c.requests, exported{path: r.URL.Path, body: string(body)})
+}
+
+func (c *collector) setUnavailable(unavailable bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.unavailable = unavailable
+}
+
+func (c *collector) received() []exported {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]exported(nil), c.requests...)
+}
+
+// runtimeAPI is a stand-in for the Extensions and Telemetry APIs. Each call
+// to the next-event endpoint runs the matching step, which plays the part of
+// the function and the platform, before returning that step's event.
+type runtimeAPI struct {
+	t           *testing.T
+	destination string
+	steps       []func(destination string) Event
+	mu          sync.Mutex
+}
+
+func (a *runtimeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/2020-01-01/extension/register":
+		assert.Equal(a.t, "telemetry-extension", r.Header.Get(extensionNameHeader))
+		w.Header().Set(extensionIdentifierHeader, "ext-1")
+		_, _ = w.Write([]byte(`{}`))
+	case "/2022-07-01/telemetry":
+		assert.Equal(a.t, "ext-1", r.Header.Get(extensionIdentifierHeader))
+		var sub struct {
+			Types       []string          `json:"types"`
+			Destination map[string]string `json:"destination"`
+		}
+		require.NoError(a.t, json.NewDecoder(r.Body).Decode(&sub))
+		assert.Equal(a.t, []string{"platform", "function"}, sub.Types)
+
+		a.mu.Lock()
+		a.destination = sub.Destination["URI"]
+		a.mu.Unlock()
+	case "/2020-01-01/extension/event/next":
+		a.mu.Lock()
+		step := a.steps[0]
+		a.steps = a.steps[1:]
+		destination := a.destination
+		a.mu.Unlock()
+
+		_ = json.NewEncoder(w).Encode(step(destination))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func post(t *testing.T, url, body string) {
+	t.Helper()
+
+	resp, err := http.Post(url, "application/json", bytes.NewBufferString(body))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestExtension_ExportsAfterResponseAndFlushesOnShutdown(t *testing.T) {
+	collector := &collector{}
+	collectorServer := httptest.NewServer(collector)
+	t.Cleanup(collectorServer.Close)
+
+	deadline := time.Now().Add(5 * time.Second).UnixMilli()
+	api := &runtimeAPI{t: t}
+	api.steps = []func(string) Event{
+		func(destination string) Event {
+			base := destination[:len(destination)-len(telemetryPath)]
+			// The function exports a span while the invocation is running.
+			post(t, base+"/v1/traces", `{"resourceSpans":[{"name":"first"}]}`)
+			go func() {
+				time.Sleep(20 * time.Millisecond)
+				post(t, destination, `[
+					{"time":"2026-01-02T03:04:05Z","type":"function","record":"hello from the function"},
+					{"time":"2026-01-02T03:04:05Z","type":"platform.runtimeDone","record":{"requestId":"other"}},
+					{"time":"2026-01-02T03:04:06Z","type":"platform.runtimeDone","record":{"requestId":"req-1"}}
+				]`)
+			}()
+			return Event{EventType: EventInvoke, RequestID: "req-1", DeadlineMs: deadline}
+		},
+		func(destination string) Event {
+			// Calling next again means the previous invocation was exported.
+			assert.Len(t, collector.received(), 2)
+
+			base := destination[:len(destination)-len(telemetryPath)]
+			post(t, base+"/v1/traces", `{"resourceSpans":[{"name":"second"}]}`)
+			return Event{EventType: EventShutdown, ShutdownReason: "spindown", DeadlineMs: deadline}
+		},
+	}
+	apiServer := httptest.NewServer(api)
+	t.Cleanup(apiServer.Close)
+
+	exporter, err := NewOTLPExporter(collectorServer.URL, "hello-go")
+	require.NoError(t, err)
+
+	ext := New(config.ExtensionConfig{ListenAddr: "127.0.0.1:0", ExportTimeout: time.Second},
+		"telemetry-extension", apiServer.Listener.Addr().String(), exporter, slog.New(slog.DiscardHandler))
+	ext.destinationHost = "127.0.0.1"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, ext.Run(ctx))
+
+	requests := collector.received()
+	require.Len(t, requests, 3)
+
+	assert.Equal(t, "/v1/traces", requests[0].path)
+	assert.JSONEq(t, `{"resourceSpans":[{"name":"first"}]}`, requests[0].body)
+
+	assert.Equal(t, "/v1/logs", requests[1].path)
+	assert.Contains(t, requests[1].body, `"stringValue":"hello from the function"`)
+	assert.Contains(t, requests[1].body, `"timeUnixNano":"1767323045000000000"`)
+
+	assert.Equal(t, "/v1/traces", requests[2].path)
+	assert.JSONEq(t, `{"resourceSpans":[{"name":"second"}]}`, requests[2].body)
+}
+
+func TestExtension_RetriesFailedExports(t *testing.T) {
+	collector := &collector{unavailable: true}
+	collectorServer := httptest.NewServer(collector)
+	t.Cleanup(collectorServer.Close)
+
+	deadline := time.Now().Add(5 * time.Second).UnixMilli()
+	api := &runtimeAPI{t: t}
+	api.steps = []func(string) Event{
+		func(destination string) Event {
+			base := destination[:len(destination)-len(telemetryPath)]
+			post(t, base+"/v1/traces", `{"resourceSpans":[{"name":"first"}]}`)
+			go func() {
+				time.Sleep(20 * time.Millisecond)
+				post(t, destination, `[
+					{"time":"2026-01-02T03:04:05Z","type":"function","record":"hello from the function"},
+					{"time":"2026-01-02T03:04:06Z","type":"platform.runtimeDone","record":{"requestId":"req-1"}}
+				]`)
+			}()
+			return Event{EventType: EventInvoke, RequestID: "req-1", DeadlineMs: deadline}
+		},
+		func(destination string) Event {
+			// The export after the first invocation was rejected.
+			assert.Empty(t, collector.received())
+			collector.setUnavailable(false)
+
+			base := destination[:len(destination)-len(telemetryPath)]
+			post(t, base+"/v1/traces", `{"resourceSpans":[{"name":"second"}]}`)
+			return Event{EventType: EventShutdown, ShutdownReason: "spindown", DeadlineMs: deadline}
+		},
+	}
+	apiServer := httptest.NewServer(api)
+	t.Cleanup(apiServer.Close)
+
+	exporter, err := NewOTLPExporter(collectorServer.URL, "hello-go")
+	require.NoError(t, err)
+
+	ext := New(config.ExtensionConfig{ListenAddr: "127.0.0.1:0", ExportTimeout: time.Second},
+		"telemetry-extension", apiServer.Listener.Addr().String(), exporter, slog.New(slog.DiscardHandler))
+	ext.destinationHost = "127.0.0.1"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, ext.Run(ctx))
+
+	// SHUTDOWN retries the failed batch ahead of what arrived since.
+	requests := collector.received()
+	require.Len(t, requests, 3)
+
+	assert.JSONEq(t, `{"resourceSpans":[{"name":"first"}]}`, requests[0].body)
+	assert.JSONEq(t, `{"resourceSpans":[{"name":"second"}]}`, requests[1].body)
+	assert.Equal(t, "/v1/logs", requests[2].path)
+	assert.Contains(t, requests[2].body, `"stringValue":"hello from the function"`)
+}
+
+func TestReceiver_RequeueDropsOldestBeyondLimit(t *testing.T) {
+	r := newReceiver(slog.New(slog.DiscardHandler))
+	r.add(Payload{Signal: SignalTraces, Body: []byte("new")})
+
+	dropped := r.requeue(Batch{
+		Payloads: []Payload{
+			{Signal: SignalTraces, Body: []byte("old1")},
+			{Signal: SignalMetrics, Body: []byte("old2")},
+		},
+		Logs: []LogRecord{{Message: "l1"}, {Message: "l2"}},
+	}, 8)
+	assert.Equal(t, 1, dropped)
+
+	batch := r.drain()
+	require.Len(t, batch.Payloads, 2)
+	assert.Equal(t, "old2", string(batch.Payloads[0].Body))
+	assert.Equal(t, "new", string(batch.Payloads[1].Body))
+	assert.Equal(t, []LogRecord{{Message: "l1"}, {Message: "l2"}}, batch.Logs)
+}
+
+func TestOTLPExporter_ReportsEverySignalFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+
+	exporter, err := NewOTLPExporter(server.URL, "hello-go")
+	require.NoError(t, err)
+
+	err = exporter.Export(context.Background(), Batch{
+		Payloads: []Payload{
+			{Signal: SignalTraces, Body: []byte("t")},
+			{Signal: SignalMetrics, Body: []byte("m")},
+		},
+		Logs: []LogRecord{{Time: time.Now(), Message: "log"}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to export traces: status 503")
+	assert.Contains(t, err.Error(), "failed to export metrics: status 503")
+	assert.Contains(t, err.Error(), "failed to export logs: status 503")
+
+	var exportErr *ExportError
+	require.ErrorAs(t, err, &exportErr)
+	assert.Len(t, exportErr.Failed.Payloads, 2)
+	assert.Len(t, exportErr.Failed.Logs, 1)
+}
+
+func TestOTLPExporter_ReportsOnlyFailedPayloads(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/metrics" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	exporter, err := NewOTLPExporter(server.URL, "hello-go")
+	require.NoError(t, err)
+
+	err = exporter.Export(context.Background(), Batch{
+		Payloads: []Payload{
+			{Signal: SignalTraces, Body: []byte("t")},
+			{Signal: SignalMetrics, Body: []byte("m")},
+		},
+		Logs: []LogRecord{{Time: time.Now(), Message: "log"}},
+	})
+
+	var exportErr *ExportError
+	require.ErrorAs(t, err, &exportErr)
+	assert.Equal(t, []Payload{{Signal: SignalMetrics, Body: []byte("m")}}, exportErr.Failed.Payloads)
+	assert.Empty(t, exportErr.Failed.Logs)
+}
+
+func TestNewOTLPExporter_RequiresEndpoint(t *testing.T) {
+	_, err := NewOTLPExporter("", "hello-go")
+	require.Error(t, err)
+}
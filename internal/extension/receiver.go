@@ -0,0 +1,206 @@
+package extension
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// maxReceiveBytes bounds a single delivery from the function or the
+	// Telemetry API.
+	maxReceiveBytes = 4 << 20
+
+	// maxRetryBytes bounds the failed exports kept for the next flush.
+	maxRetryBytes = 8 << 20
+
+	telemetryPath = "/telemetry"
+)
+
+// Signal names the kind of OTLP data in a Payload.
+type Signal string
+
+const (
+	SignalTraces  Signal = "traces"
+	SignalMetrics Signal = "metrics"
+	SignalLogs    Signal = "logs"
+)
+
+// Payload is one OTLP/HTTP export request received from the function. It is
+// kept encoded and forwarded as is.
+type Payload struct {
+	Signal      Signal
+	ContentType string
+	Body        []byte
+}
+
+// LogRecord is a function log line delivered by the Telemetry API.
+type LogRecord struct {
+	Time    time.Time
+	Message string
+}
+
+// Batch is the telemetry buffered between two exports.
+type Batch struct {
+	Payloads []Payload
+	Logs     []LogRecord
+}
+
+// Empty reports whether there is nothing to export.
+func (b Batch) Empty() bool {
+	return len(b.Payloads) == 0 && len(b.Logs) == 0
+}
+
+// Len returns the number of payloads and log records in b.
+func (b Batch) Len() int {
+	return len(b.Payloads) + len(b.Logs)
+}
+
+// size approximates the memory b holds by its bodies and messages.
+func (b Batch) size() int {
+	n := 0
+	for _, p := range b.Payloads {
+		n += len(p.Body)
+	}
+	for _, log := range b.Logs {
+		n += len(log.Message)
+	}
+	return n
+}
+
+// telemetryEvent is one entry of a Telemetry API delivery.
+type telemetryEvent struct {
+	Time   time.Time       `json:"time"`
+	Type   string          `json:"type"`
+	Record json.RawMessage `json:"record"`
+}
+
+// receiver buffers OTLP requests from the function and Telemetry API
+// deliveries. Request IDs of finished invocations are sent on done.
+type receiver struct {
+	logger *slog.Logger
+	done   chan string
+	batch  Batch
+	mu     sync.Mutex
+}
+
+func newReceiver(logger *slog.Logger) *receiver {
+	return &receiver{
+		logger: logger,
+		done:   make(chan string, 16),
+	}
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxReceiveBytes))
+	if err != nil {
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	switch req.URL.Path {
+	case "/v1/traces":
+		r.add(Payload{Signal: SignalTraces, ContentType: req.Header.Get("Content-Type"), Body: body})
+	case "/v1/metrics":
+		r.add(Payload{Signal: SignalMetrics, ContentType: req.Header.Get("Content-Type"), Body: body})
+	case "/v1/logs":
+		r.add(Payload{Signal: SignalLogs, ContentType: req.Header.Get("Content-Type"), Body: body})
+	case telemetryPath:
+		if err := r.handleTelemetry(body); err != nil {
+			r.logger.Warn("Failed to decode Telemetry API delivery", "error", err)
+			http.Error(w, "Invalid telemetry payload", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.NotFound(w, req)
+		return
+	}
+
+	// OTLP/HTTP clients accept an empty success response.
+	w.WriteHeader(http.StatusOK)
+}
+
+func (r *receiver) add(p Payload) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.batch.Payloads = append(r.batch.Payloads, p)
+}
+
+func (r *receiver) handleTelemetry(body []byte) error {
+	var events []telemetryEvent
+	if err := json.Unmarshal(body, &events); err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		switch event.Type {
+		case "function":
+			// Text logs arrive as a JSON string, JSON logs as an object.
+			message := string(event.Record)
+			var text string
+			if json.Unmarshal(event.Record, &text) == nil {
+				message = text
+			}
+
+			r.mu.Lock()
+			r.batch.Logs = append(r.batch.Logs, LogRecord{Time: event.Time, Message: message})
+			r.mu.Unlock()
+		case "platform.runtimeDone":
+			var record struct {
+				RequestID string `json:"requestId"`
+			}
+			if err := json.Unmarshal(event.Record, &record); err != nil {
+				return err
+			}
+
+			// Never block a delivery; a waiter that missed its request ID
+			// falls back to the invocation deadline.
+			select {
+			case r.done <- record.RequestID:
+			default:
+			}
+		}
+	}
+
+	return nil
+}
+
+// drain returns everything buffered so far and resets the buffer.
+func (r *receiver) drain() Batch {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	batch := r.batch
+	r.batch = Batch{}
+	return batch
+}
+
+// requeue puts failed, the unexported part of a drained batch, back ahead of
+// whatever arrived since, so the next flush retries it. At most limit bytes
+// of it are kept, dropping the oldest payloads and then the oldest log
+// records; it returns how many entries were dropped.
+func (r *receiver) requeue(failed Batch, limit int) int {
+	before := failed.Len()
+	for failed.size() > limit && len(failed.Payloads) > 0 {
+		failed.Payloads = failed.Payloads[1:]
+	}
+	for failed.size() > limit && len(failed.Logs) > 0 {
+		failed.Logs = failed.Logs[1:]
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.batch.Payloads = append(failed.Payloads, r.batch.Payloads...)
+	r.batch.Logs = append(failed.Logs, r.batch.Logs...)
+	return before - failed.Len()
+}
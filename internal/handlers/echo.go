@@ -2,11 +2,13 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 
 	"github.com/savisec/hello-go/internal/api"
 	"github.com/savisec/hello-go/internal/services"
+	"github.com/savisec/hello-go/internal/tenancy"
 )
 
 // EchoHandler handles echo-related HTTP requests.
@@ -28,18 +30,31 @@ func (h *EchoHandler) PostV1Echo(w http.ResponseWriter, r *http.Request) {
 	var req api.EchoMessage
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		// The body is bounded by the RequestLimits middleware.
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			h.logger.Warn("Request body too large", "limit", maxBytesErr.Limit)
+			writeErrorResponse(w, http.StatusRequestEntityTooLarge, "Request body too large", h.logger)
+			return
+		}
 		h.logger.Error("Failed to decode request body", "error", err)
 		writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON", h.logger)
 		return
 	}
 
-	if req.Message == "" || req.Author == "" {
+	if err := services.ValidateEchoMessage(req); err != nil {
 		h.logger.Error("Missing required fields in request")
 		writeErrorResponse(w, http.StatusBadRequest, "Missing required fields: message and author", h.logger)
 		return
 	}
 
-	response := h.echoService.Echo(req)
+	if t, ok := tenancy.FromContext(r.Context()); ok && t.MaxMessageBytes > 0 && len(req.Message) > t.MaxMessageBytes {
+		h.logger.WarnContext(r.Context(), "Message too large for tenant", "limit", t.MaxMessageBytes)
+		writeErrorResponse(w, http.StatusRequestEntityTooLarge, "Message too large", h.logger)
+		return
+	}
+
+	response := h.echoService.Echo(r.Context(), req)
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
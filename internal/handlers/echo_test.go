@@ -13,8 +13,10 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/savisec/hello-go/internal/api"
+	"github.com/savisec/hello-go/internal/logging"
 
 	"github.com/savisec/hello-go/internal/services"
+	"github.com/savisec/hello-go/internal/tenancy"
 )
 
 func TestEchoHandler_PostV1Echo(t *testing.T) {
@@ -22,7 +24,7 @@ func TestEchoHandler_PostV1Echo(t *testing.T) {
 		Level: slog.LevelError,
 	}))
 
-	echoService := services.NewEchoService(logger)
+	echoService := services.NewEchoService(logger, logging.NewFingerprinter("test"))
 	handler := NewEchoHandler(echoService, logger)
 
 	tests := []struct {
@@ -31,6 +33,8 @@ func TestEchoHandler_PostV1Echo(t *testing.T) {
 		expectedError  string
 		expectedBody   string
 		expectedStatus int
+		tenant         *tenancy.Tenant
+		maxBodyBytes   int64
 	}{
 		{
 			name: "successful echo",
@@ -72,6 +76,36 @@ func TestEchoHandler_PostV1Echo(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			expectedError:  "Missing required fields: message and author",
 		},
+		{
+			name: "body over the limit",
+			requestBody: api.EchoMessage{
+				Message: "Hello, World!",
+				Author:  "Alice",
+			},
+			maxBodyBytes:   16,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedError:  "Request body too large",
+		},
+		{
+			name: "message over the tenant limit",
+			requestBody: api.EchoMessage{
+				Message: "Hello, World!",
+				Author:  "Alice",
+			},
+			tenant:         &tenancy.Tenant{ID: "team-a", MaxMessageBytes: 5},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedError:  "Message too large",
+		},
+		{
+			name: "message within the tenant limit",
+			requestBody: api.EchoMessage{
+				Message: "Hello, World!",
+				Author:  "Alice",
+			},
+			tenant:         &tenancy.Tenant{ID: "team-a", MaxMessageBytes: 13},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"message":"Hello, World!","author":"Alice"}`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -88,7 +122,14 @@ func TestEchoHandler_PostV1Echo(t *testing.T) {
 			req := httptest.NewRequest(http.MethodPost, "/v1/echo", &body)
 			req.Header.Set("Content-Type", "application/json")
 
+			if tt.tenant != nil {
+				req = req.WithContext(tenancy.NewContext(req.Context(), tt.tenant))
+			}
+
 			w := httptest.NewRecorder()
+			if tt.maxBodyBytes > 0 {
+				req.Body = http.MaxBytesReader(w, req.Body, tt.maxBodyBytes)
+			}
 
 			handler.PostV1Echo(w, req)
 
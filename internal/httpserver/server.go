@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/netip"
 	"time"
 
 	// Add the embed import
@@ -13,11 +14,15 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
 
 	"github.com/savisec/hello-go/api"
 
+	"github.com/savisec/hello-go/internal/concurrency"
 	"github.com/savisec/hello-go/internal/config"
 	"github.com/savisec/hello-go/internal/handlers"
+	"github.com/savisec/hello-go/internal/lambdactx"
+	appmiddleware "github.com/savisec/hello-go/internal/middleware"
 )
 
 type Server struct {
@@ -25,11 +30,28 @@ type Server struct {
 	logger *slog.Logger
 }
 
+// meterName scopes the server's own metrics.
+const meterName = "github.com/savisec/hello-go/internal/httpserver"
+
 // New creates a new Server instance with the provided configuration and router.
-func New(cfg config.ServerConfig, router chi.Router, logger *slog.Logger) *Server {
+// When enabled, the concurrency limit sits in front of the router, so shed
+// requests cost as little as possible.
+func New(cfg config.ServerConfig, router chi.Router, logger *slog.Logger) (*Server, error) {
+	handler := http.Handler(router)
+	if cfg.Concurrency.Enabled {
+		limiter, err := concurrency.New(cfg.Concurrency)
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup concurrency limit: %w", err)
+		}
+		if err := limiter.RegisterMetrics(otel.Meter(meterName)); err != nil {
+			return nil, err
+		}
+		handler = appmiddleware.NewConcurrencyLimit(limiter, cfg.Concurrency.CriticalPaths, cfg.Concurrency.RetryAfter, logger).ServeHTTP(handler)
+	}
+
 	srv := &http.Server{
 		Addr:         cfg.Address(),
-		Handler:      otelhttp.NewHandler(router, "hello-go"),
+		Handler:      otelhttp.NewHandler(handler, "hello-go"),
 		ReadTimeout:  cfg.ReadTimeout,
 		WriteTimeout: cfg.WriteTimeout,
 		IdleTimeout:  cfg.IdleTimeout,
@@ -38,7 +60,7 @@ func New(cfg config.ServerConfig, router chi.Router, logger *slog.Logger) *Serve
 	return &Server{
 		server: srv,
 		logger: logger,
-	}
+	}, nil
 }
 
 func (s *Server) Start() error {
@@ -57,15 +79,20 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	return s.server.Shutdown(ctx)
 }
 
-// NewRouter sets up the router with middlewares and routes.
-func NewRouter() chi.Router {
+// NewRouter sets up the router with middlewares and routes. Client addresses
+// are taken from forwarding headers only when sent by trustedProxies. The
+// given middlewares run after the standard ones and before routing, so they
+// see every request, even those no route serves.
+func NewRouter(logger *slog.Logger, trustedProxies []netip.Prefix, middlewares ...func(http.Handler) http.Handler) chi.Router {
 	r := chi.NewRouter()
 
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(appmiddleware.NewRealIP(trustedProxies).ServeHTTP)
+	r.Use(lambdactx.Middleware)
+	r.Use(appmiddleware.NewAccessLogger(logger).ServeHTTP)
+	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(60 * time.Second))
+	r.Use(middlewares...)
 
 	// Serve openapi.yml unconditionally
 	r.Get("/api/openapi.yml", serveOpenAPISpec)
@@ -0,0 +1,152 @@
+// Package ipfilter matches client addresses against named lists of IPv4 and
+// IPv6 CIDRs, kept in config or in files that are reloaded as they change.
+package ipfilter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// ParsePrefixes parses addresses and CIDRs. A bare address is a prefix of
+// just itself.
+func ParsePrefixes(entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		prefix, err := parsePrefix(entry)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
+
+func parsePrefix(entry string) (netip.Prefix, error) {
+	entry = strings.TrimSpace(entry)
+	if strings.Contains(entry, "/") {
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid CIDR %q: %w", entry, err)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(entry)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid address %q: %w", entry, err)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// ReadPrefixes parses one address or CIDR per line. Blank lines and
+// anything after a # are ignored.
+func ReadPrefixes(r io.Reader) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		entry, _, _ := strings.Cut(scanner.Text(), "#")
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		prefix, err := parsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, scanner.Err()
+}
+
+func readPrefixFile(path string) ([]netip.Prefix, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return ReadPrefixes(f)
+}
+
+// Contains reports whether addr is in any of prefixes.
+func Contains(prefixes []netip.Prefix, addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// List is a named set of prefixes that can be replaced while in use.
+type List struct {
+	prefixes atomic.Pointer[[]netip.Prefix]
+	name     string
+}
+
+func NewList(name string, prefixes []netip.Prefix) *List {
+	l := &List{name: name}
+	l.Set(prefixes)
+	return l
+}
+
+func (l *List) Name() string {
+	return l.name
+}
+
+// Set replaces the prefixes of l.
+func (l *List) Set(prefixes []netip.Prefix) {
+	l.prefixes.Store(&prefixes)
+}
+
+// Len is the number of prefixes in l.
+func (l *List) Len() int {
+	return len(*l.prefixes.Load())
+}
+
+// Contains reports whether addr is in l.
+func (l *List) Contains(addr netip.Addr) bool {
+	return Contains(*l.prefixes.Load(), addr)
+}
+
+// Rule applies lists to a route group: addresses on a Deny list are
+// rejected, and if there are Allow lists, addresses must be on one.
+type Rule struct {
+	Allow []*List
+	Deny  []*List
+}
+
+// Check reports whether addr may pass, and if not, why: the deny list it
+// is on, or that it is on no allow list. Invalid addresses, such as an
+// unknown client address, pass only rules without allow lists.
+func (r Rule) Check(addr netip.Addr) (bool, string) {
+	if !addr.IsValid() {
+		if len(r.Allow) > 0 {
+			return false, "unknown address"
+		}
+		return true, ""
+	}
+	for _, list := range r.Deny {
+		if list.Contains(addr) {
+			return false, "on deny list " + list.Name()
+		}
+	}
+	if len(r.Allow) == 0 {
+		return true, ""
+	}
+	for _, list := range r.Allow {
+		if list.Contains(addr) {
+			return true, ""
+		}
+	}
+	return false, "on no allow list"
+}
+
+// Empty reports whether r applies no lists.
+func (r Rule) Empty() bool {
+	return len(r.Allow) == 0 && len(r.Deny) == 0
+}
@@ -0,0 +1,180 @@
+package ipfilter
+
+import (
+	"log/slog"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/internal/audit"
+	"github.com/savisec/hello-go/internal/config"
+)
+
+func TestReadPrefixes(t *testing.T) {
+	prefixes, err := ReadPrefixes(strings.NewReader(`
+# partners
+203.0.113.0/24   # acme
+198.51.100.7
+2001:db8::/32
+::ffff:192.0.2.1
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("203.0.113.0/24"),
+		netip.MustParsePrefix("198.51.100.7/32"),
+		netip.MustParsePrefix("2001:db8::/32"),
+		netip.MustParsePrefix("192.0.2.1/32"),
+	}, prefixes)
+
+	_, err = ReadPrefixes(strings.NewReader("203.0.113.0/24\nnot-an-ip\n"))
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func TestRule_Check(t *testing.T) {
+	partners := NewList("partners", []netip.Prefix{
+		netip.MustParsePrefix("203.0.113.0/24"),
+		netip.MustParsePrefix("2001:db8::/32"),
+	})
+	blocked := NewList("blocked", []netip.Prefix{netip.MustParsePrefix("203.0.113.66/32")})
+
+	allowAndDeny := Rule{Allow: []*List{partners}, Deny: []*List{blocked}}
+	denyOnly := Rule{Deny: []*List{blocked}}
+
+	tests := []struct {
+		name       string
+		addr       string
+		rule       Rule
+		wantReason string
+		want       bool
+	}{
+		{name: "on allow list", rule: allowAndDeny, addr: "203.0.113.10", want: true},
+		{name: "ipv6 on allow list", rule: allowAndDeny, addr: "2001:db8::1", want: true},
+		{name: "ipv4-mapped ipv6", rule: allowAndDeny, addr: "::ffff:203.0.113.10", want: true},
+		{name: "deny wins", rule: allowAndDeny, addr: "203.0.113.66", wantReason: "on deny list blocked"},
+		{name: "on no allow list", rule: allowAndDeny, addr: "198.51.100.1", wantReason: "on no allow list"},
+		{name: "unknown address with allow lists", rule: allowAndDeny, wantReason: "unknown address"},
+		{name: "deny only", rule: denyOnly, addr: "198.51.100.1", want: true},
+		{name: "unknown address, deny only", rule: denyOnly, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, _ := netip.ParseAddr(tt.addr)
+			ok, reason := tt.rule.Check(addr)
+			assert.Equal(t, tt.want, ok)
+			assert.Equal(t, tt.wantReason, reason)
+		})
+	}
+}
+
+func TestLists_ReloadsFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "partners.txt")
+	require.NoError(t, os.WriteFile(path, []byte("203.0.113.0/24\n"), 0o600))
+
+	auditPath := filepath.Join(dir, "audit.log")
+	auditLogger, err := audit.New(config.AuditConfig{Enabled: true, Path: auditPath, Key: strings.Repeat("ab", 16)})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = auditLogger.Close() })
+
+	lists, err := NewLists(map[string]config.IPListConfig{
+		"partners": {File: path, CIDRs: []string{"2001:db8::/32"}},
+	}, auditLogger, slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = lists.Close() })
+
+	rule, err := lists.Rule(config.IPFilterRule{Allow: []string{"partners"}})
+	require.NoError(t, err)
+
+	allowed := func(addr string) bool {
+		ok, _ := rule.Check(netip.MustParseAddr(addr))
+		return ok
+	}
+	assert.True(t, allowed("203.0.113.1"))
+	assert.True(t, allowed("2001:db8::1"))
+	assert.False(t, allowed("198.51.100.1"))
+
+	// Replace the file the way config management does.
+	tmp := filepath.Join(dir, "partners.txt.tmp")
+	require.NoError(t, os.WriteFile(tmp, []byte("198.51.100.0/24\n"), 0o600))
+	require.NoError(t, os.Rename(tmp, path))
+
+	assert.Eventually(t, func() bool { return allowed("198.51.100.1") }, 5*time.Second, 10*time.Millisecond)
+	assert.False(t, allowed("203.0.113.1"))
+	assert.True(t, allowed("2001:db8::1"), "config entries are kept")
+
+	// A broken file leaves the list as it was.
+	require.NoError(t, os.WriteFile(path, []byte("garbage\n"), 0o600))
+	time.Sleep(3 * reloadDelay)
+	assert.True(t, allowed("198.51.100.1"))
+
+	// Both reloads are audited.
+	assert.Eventually(t, func() bool {
+		data, _ := os.ReadFile(auditPath)
+		return strings.Count(string(data), `"type":"config.reload"`) >= 2 &&
+			strings.Contains(string(data), `"outcome":"failure"`)
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestLists_ReloadsConfigMapSwaps(t *testing.T) {
+	// Lay the directory out as the kubelet does: the file is a symlink
+	// through ..data, which points at a timestamped directory.
+	dir := t.TempDir()
+	writeVersion := func(version, contents string) {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, version), 0o700))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, version, "partners.txt"), []byte(contents), 0o600))
+	}
+	writeVersion("..2025_01_01", "203.0.113.0/24\n")
+	require.NoError(t, os.Symlink("..2025_01_01", filepath.Join(dir, "..data")))
+	require.NoError(t, os.Symlink(filepath.Join("..data", "partners.txt"), filepath.Join(dir, "partners.txt")))
+
+	auditLogger, err := audit.New(config.AuditConfig{})
+	require.NoError(t, err)
+
+	lists, err := NewLists(map[string]config.IPListConfig{
+		"partners": {File: filepath.Join(dir, "partners.txt")},
+	}, auditLogger, slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = lists.Close() })
+
+	rule, err := lists.Rule(config.IPFilterRule{Allow: []string{"partners"}})
+	require.NoError(t, err)
+
+	allowed := func(addr string) bool {
+		ok, _ := rule.Check(netip.MustParseAddr(addr))
+		return ok
+	}
+	assert.True(t, allowed("203.0.113.1"))
+
+	// An update writes a new directory and renames a new symlink over ..data.
+	writeVersion("..2025_01_02", "198.51.100.0/24\n")
+	require.NoError(t, os.Symlink("..2025_01_02", filepath.Join(dir, "..data_tmp")))
+	require.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, "..2025_01_01")))
+
+	assert.Eventually(t, func() bool { return allowed("198.51.100.1") }, 5*time.Second, 10*time.Millisecond)
+	assert.False(t, allowed("203.0.113.1"))
+}
+
+func TestLists_Validates(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+	auditLogger := audit.NewLogger(nil, nil)
+
+	_, err := NewLists(map[string]config.IPListConfig{"bad": {CIDRs: []string{"10.0.0.0/33"}}}, auditLogger, logger)
+	assert.Error(t, err)
+
+	_, err = NewLists(map[string]config.IPListConfig{"missing": {File: filepath.Join(t.TempDir(), "none.txt")}}, auditLogger, logger)
+	assert.Error(t, err)
+
+	lists, err := NewLists(map[string]config.IPListConfig{"ok": {CIDRs: []string{"10.0.0.0/8"}}}, auditLogger, logger)
+	require.NoError(t, err)
+	_, err = lists.Rule(config.IPFilterRule{Deny: []string{"ok", "other"}})
+	assert.ErrorContains(t, err, `unknown ip list "other"`)
+	assert.NoError(t, lists.Close())
+}
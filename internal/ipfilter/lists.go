@@ -0,0 +1,237 @@
+package ipfilter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/savisec/hello-go/internal/audit"
+	"github.com/savisec/hello-go/internal/config"
+)
+
+// reloadDelay is how long a list file must go unchanged before it is
+// reloaded.
+const reloadDelay = 100 * time.Millisecond
+
+// source is where a list's prefixes come from.
+type source struct {
+	list *List
+	file string
+	// target is file with symlinks resolved, as of the last load.
+	target string
+	static []netip.Prefix
+}
+
+// load reads the list's file, if any, and sets the list to it plus the
+// prefixes from config.
+func (s *source) load() error {
+	prefixes := slices.Clone(s.static)
+	if s.file != "" {
+		s.target, _ = filepath.EvalSymlinks(s.file)
+		fromFile, err := readPrefixFile(s.file)
+		if err != nil {
+			return fmt.Errorf("failed to read ip list %s: %w", s.list.Name(), err)
+		}
+		prefixes = append(prefixes, fromFile...)
+	}
+	s.list.Set(prefixes)
+	return nil
+}
+
+// changedBy reports whether a change to name, in a watched directory, may
+// have changed the list's file. Besides the file itself, that is any entry
+// beside it once the file resolves somewhere new: a Kubernetes ConfigMap
+// volume, for one, updates by swapping its ..data symlink, and the file is
+// a symlink through it that is never itself written.
+func (s *source) changedBy(name string) bool {
+	if s.file == "" {
+		return false
+	}
+	if name == s.file {
+		return true
+	}
+	if filepath.Dir(name) != filepath.Dir(s.file) {
+		return false
+	}
+	target, err := filepath.EvalSymlinks(s.file)
+	return err == nil && target != s.target
+}
+
+// Lists holds the named lists from config and keeps those backed by files
+// up to date.
+type Lists struct {
+	watcher *fsnotify.Watcher
+	audit   *audit.Logger
+	logger  *slog.Logger
+	lists   map[string]*List
+	sources []*source
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewLists loads the lists in cfg and, if any have a file, watches the
+// files for changes until Close. A file that fails to reload leaves its
+// list as it was. Every reload is recorded in the audit log.
+func NewLists(cfg map[string]config.IPListConfig, auditLogger *audit.Logger, logger *slog.Logger) (*Lists, error) {
+	l := &Lists{
+		audit:  auditLogger,
+		logger: logger,
+		lists:  make(map[string]*List, len(cfg)),
+		done:   make(chan struct{}),
+	}
+
+	for name, lc := range cfg {
+		static, err := ParsePrefixes(lc.CIDRs)
+		if err != nil {
+			return nil, fmt.Errorf("ip list %s: %w", name, err)
+		}
+		src := &source{list: NewList(name, nil), static: static}
+		if lc.File != "" {
+			src.file = filepath.Clean(lc.File)
+		}
+		if err := src.load(); err != nil {
+			return nil, err
+		}
+		l.lists[name] = src.list
+		l.sources = append(l.sources, src)
+	}
+
+	if err := l.watch(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// watch watches the directories of the list files, rather than the files,
+// so that files replaced by a rename, as editors and config management do,
+// are still seen.
+func (l *Lists) watch() error {
+	var dirs []string
+	for _, src := range l.sources {
+		if src.file != "" && !slices.Contains(dirs, filepath.Dir(src.file)) {
+			dirs = append(dirs, filepath.Dir(src.file))
+		}
+	}
+	if len(dirs) == 0 {
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch ip lists: %w", err)
+	}
+	for _, dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			_ = watcher.Close()
+			return fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+	l.watcher = watcher
+
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		l.run()
+	}()
+	return nil
+}
+
+func (l *Lists) run() {
+	// Changes are reloaded once the file has been quiet for reloadDelay,
+	// so that a file being written is not read half-way.
+	pending := make(map[*source]bool)
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-l.done:
+			return
+		case event, ok := <-l.watcher.Events:
+			if !ok {
+				return
+			}
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Rename) {
+				continue
+			}
+			for _, src := range l.sources {
+				if src.changedBy(filepath.Clean(event.Name)) {
+					pending[src] = true
+					timer.Reset(reloadDelay)
+				}
+			}
+		case <-timer.C:
+			for src := range pending {
+				l.reload(src)
+			}
+			clear(pending)
+		case err, ok := <-l.watcher.Errors:
+			if !ok {
+				return
+			}
+			l.logger.Error("IP list watcher failed", "error", err)
+		}
+	}
+}
+
+func (l *Lists) reload(src *source) {
+	event := audit.Event{
+		Type:     audit.EventConfigReload,
+		Outcome:  audit.OutcomeSuccess,
+		Action:   "ip_list.reload",
+		Resource: src.file,
+		Details:  map[string]any{"list": src.list.Name()},
+	}
+	if err := src.load(); err != nil {
+		l.logger.Error("Failed to reload IP list, keeping the previous one", "list", src.list.Name(), "error", err)
+		event.Outcome = audit.OutcomeFailure
+		event.Details["error"] = err.Error()
+	} else {
+		l.logger.Info("IP list reloaded", "list", src.list.Name(), "entries", src.list.Len())
+		event.Details["entries"] = src.list.Len()
+	}
+	if err := l.audit.Log(context.Background(), event); err != nil {
+		l.logger.Error("Failed to write audit event", "error", err)
+	}
+}
+
+// Rule resolves the list names in cfg.
+func (l *Lists) Rule(cfg config.IPFilterRule) (Rule, error) {
+	var rule Rule
+	var errs []error
+	for _, name := range cfg.Allow {
+		if list, ok := l.lists[name]; ok {
+			rule.Allow = append(rule.Allow, list)
+		} else {
+			errs = append(errs, fmt.Errorf("unknown ip list %q", name))
+		}
+	}
+	for _, name := range cfg.Deny {
+		if list, ok := l.lists[name]; ok {
+			rule.Deny = append(rule.Deny, list)
+		} else {
+			errs = append(errs, fmt.Errorf("unknown ip list %q", name))
+		}
+	}
+	return rule, errors.Join(errs...)
+}
+
+// Close stops watching the list files.
+func (l *Lists) Close() error {
+	if l.watcher == nil {
+		return nil
+	}
+	close(l.done)
+	err := l.watcher.Close()
+	l.wg.Wait()
+	return err
+}
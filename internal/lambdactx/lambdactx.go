@@ -0,0 +1,233 @@
+// Package lambdactx gives handlers typed access to the request metadata API
+// Gateway and ALB attach to Lambda events, such as the request ID, stage and
+// authorizer identity. Requests served by the HTTP server get the same
+// fields derived from headers, so handlers need not know which entrypoint
+// received the request.
+package lambdactx
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Source identifies what a RequestContext was derived from.
+type Source string
+
+const (
+	SourceAPIGatewayV1 Source = "apigateway-v1"
+	SourceAPIGatewayV2 Source = "apigateway-v2"
+	SourceALB          Source = "alb"
+	SourceHTTP         Source = "http"
+)
+
+// Identity is the caller as established by an authorizer.
+type Identity struct {
+	// Claims holds the authorizer's claims or context values as strings.
+	Claims map[string]string
+	// Subject is the stable caller ID, e.g. the JWT "sub" claim.
+	Subject string
+	// Authorizer names the mechanism, e.g. "jwt", "lambda" or "iam".
+	Authorizer string
+	Scopes     []string
+}
+
+// RequestContext is the per-request metadata available to handlers.
+type RequestContext struct {
+	// Identity is nil for unauthenticated requests.
+	Identity   *Identity
+	RequestID  string
+	Stage      string
+	DomainName string
+	SourceIP   string
+	UserAgent  string
+	Source     Source
+	// Tenant is the ID of the tenant the request is for, once resolved.
+	Tenant string
+}
+
+type ctxKey struct{}
+
+// NewContext returns a copy of ctx carrying rc.
+func NewContext(ctx context.Context, rc RequestContext) context.Context {
+	return context.WithValue(ctx, ctxKey{}, &rc)
+}
+
+// FromContext returns the RequestContext stored in ctx by NewContext or
+// Middleware.
+func FromContext(ctx context.Context) (RequestContext, bool) {
+	rc, ok := ctx.Value(ctxKey{}).(*RequestContext)
+	if !ok {
+		return RequestContext{}, false
+	}
+	return *rc, true
+}
+
+// WithTenant records the tenant ID on the RequestContext in ctx, in place
+// like WithIdentity.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	if rc, ok := ctx.Value(ctxKey{}).(*RequestContext); ok {
+		rc.Tenant = tenant
+		return ctx
+	}
+	return NewContext(ctx, RequestContext{Tenant: tenant})
+}
+
+// WithIdentity records identity on the RequestContext in ctx. Auth
+// middleware uses it to record a caller it has authenticated itself. The
+// stored context is updated in place, so middleware earlier in the chain,
+// such as the access logger, sees the caller too.
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	if rc, ok := ctx.Value(ctxKey{}).(*RequestContext); ok {
+		rc.Identity = identity
+		return ctx
+	}
+	return NewContext(ctx, RequestContext{Identity: identity})
+}
+
+// FromRequest returns the RequestContext for r, deriving it from the Lambda
+// event when r was built by the Lambda proxy and from headers otherwise.
+func FromRequest(r *http.Request) RequestContext {
+	ctx := r.Context()
+
+	if rc, ok := FromContext(ctx); ok {
+		return rc
+	}
+	if gw, ok := core.GetAPIGatewayV2ContextFromContext(ctx); ok {
+		return fromAPIGatewayV2(gw)
+	}
+	if gw, ok := core.GetAPIGatewayContextFromContext(ctx); ok {
+		return fromAPIGatewayV1(gw)
+	}
+	if _, ok := core.GetTargetGroupRequetFromContextALB(ctx); ok {
+		// ALB events carry no request context beyond the target group, but
+		// the load balancer adds a trace ID and the client address to every
+		// request.
+		rc := fromHeaders(r)
+		rc.Source = SourceALB
+		rc.SourceIP = albClientIP(r)
+		if traceID := r.Header.Get("X-Amzn-Trace-Id"); traceID != "" {
+			rc.RequestID = traceID
+		}
+		return rc
+	}
+	return fromHeaders(r)
+}
+
+func fromAPIGatewayV2(gw events.APIGatewayV2HTTPRequestContext) RequestContext {
+	rc := RequestContext{
+		RequestID:  gw.RequestID,
+		Stage:      gw.Stage,
+		DomainName: gw.DomainName,
+		SourceIP:   gw.HTTP.SourceIP,
+		UserAgent:  gw.HTTP.UserAgent,
+		Source:     SourceAPIGatewayV2,
+	}
+
+	if auth := gw.Authorizer; auth != nil {
+		switch {
+		case auth.JWT != nil:
+			rc.Identity = &Identity{
+				Claims:     auth.JWT.Claims,
+				Subject:    auth.JWT.Claims["sub"],
+				Scopes:     auth.JWT.Scopes,
+				Authorizer: "jwt",
+			}
+		case auth.Lambda != nil:
+			rc.Identity = lambdaAuthorizerIdentity(auth.Lambda)
+		case auth.IAM != nil:
+			subject := auth.IAM.UserARN
+			if subject == "" {
+				subject = auth.IAM.UserID
+			}
+			rc.Identity = &Identity{Subject: subject, Authorizer: "iam"}
+		}
+	}
+
+	return rc
+}
+
+func fromAPIGatewayV1(gw events.APIGatewayProxyRequestContext) RequestContext {
+	rc := RequestContext{
+		RequestID:  gw.RequestID,
+		Stage:      gw.Stage,
+		DomainName: gw.DomainName,
+		SourceIP:   gw.Identity.SourceIP,
+		UserAgent:  gw.Identity.UserAgent,
+		Source:     SourceAPIGatewayV1,
+	}
+
+	// Cognito user pool authorizers nest their claims; Lambda authorizers
+	// put the principal and context values at the top level.
+	if claims, ok := gw.Authorizer["claims"].(map[string]any); ok {
+		identity := lambdaAuthorizerIdentity(claims)
+		identity.Authorizer = "cognito"
+		rc.Identity = identity
+	} else if len(gw.Authorizer) > 0 {
+		rc.Identity = lambdaAuthorizerIdentity(gw.Authorizer)
+	}
+
+	return rc
+}
+
+func lambdaAuthorizerIdentity(values map[string]any) *Identity {
+	claims := make(map[string]string, len(values))
+	for key, value := range values {
+		claims[key] = fmt.Sprint(value)
+	}
+
+	subject := claims["sub"]
+	if subject == "" {
+		subject = claims["principalId"]
+	}
+
+	return &Identity{Claims: claims, Subject: subject, Authorizer: "lambda"}
+}
+
+// albClientIP returns the right-most X-Forwarded-For entry, which the ALB
+// appends itself; entries to its left come from the client and are not
+// trusted. It returns "" when that entry is not an IP address.
+func albClientIP(r *http.Request) string {
+	values := r.Header.Values("X-Forwarded-For")
+	if len(values) == 0 {
+		return ""
+	}
+	entries := strings.Split(values[len(values)-1], ",")
+	addr, err := netip.ParseAddr(strings.TrimSpace(entries[len(entries)-1]))
+	if err != nil {
+		return ""
+	}
+	return addr.String()
+}
+
+// fromHeaders derives a RequestContext from what a proxy in front of the
+// HTTP server reports. The domain and source IP are taken from r.Host and
+// r.RemoteAddr, which the RealIP middleware has already set from the
+// forwarding headers of trusted proxies; the headers themselves are not
+// read here, as any client can send them.
+func fromHeaders(r *http.Request) RequestContext {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	sourceIP := r.RemoteAddr
+	if ip, _, err := net.SplitHostPort(sourceIP); err == nil {
+		sourceIP = ip
+	}
+
+	return RequestContext{
+		RequestID:  middleware.GetReqID(r.Context()),
+		DomainName: host,
+		SourceIP:   sourceIP,
+		UserAgent:  r.UserAgent(),
+		Source:     SourceHTTP,
+	}
+}
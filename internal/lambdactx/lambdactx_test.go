@@ -0,0 +1,213 @@
+package lambdactx_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/internal/lambdactx"
+	"github.com/savisec/hello-go/internal/lambdaproxy"
+)
+
+// capture serves events through the Lambda proxy and returns the
+// RequestContext a handler behind lambdactx.Middleware observed.
+func capture(t *testing.T, event any) lambdactx.RequestContext {
+	t.Helper()
+
+	var got lambdactx.RequestContext
+	r := chi.NewRouter()
+	r.Use(lambdactx.Middleware)
+	r.Get("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		var ok bool
+		got, ok = lambdactx.FromContext(r.Context())
+		require.True(t, ok)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	payload, err := json.Marshal(event)
+	require.NoError(t, err)
+
+	_, err = lambdaproxy.New(r).Invoke(context.Background(), payload)
+	require.NoError(t, err)
+
+	return got
+}
+
+func TestFromRequest_APIGatewayV2JWT(t *testing.T) {
+	rc := capture(t, events.APIGatewayV2HTTPRequest{
+		Version: "2.0",
+		RawPath: "/whoami",
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			RequestID:  "v2-req",
+			Stage:      "prod",
+			DomainName: "api.example.com",
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method:   http.MethodGet,
+				Path:     "/whoami",
+				SourceIP: "203.0.113.7",
+			},
+			Authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+				JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{
+					Claims: map[string]string{"sub": "user-42"},
+					Scopes: []string{"echo:write"},
+				},
+			},
+		},
+	})
+
+	assert.Equal(t, lambdactx.SourceAPIGatewayV2, rc.Source)
+	assert.Equal(t, "v2-req", rc.RequestID)
+	assert.Equal(t, "prod", rc.Stage)
+	assert.Equal(t, "api.example.com", rc.DomainName)
+	assert.Equal(t, "203.0.113.7", rc.SourceIP)
+	require.NotNil(t, rc.Identity)
+	assert.Equal(t, "user-42", rc.Identity.Subject)
+	assert.Equal(t, "jwt", rc.Identity.Authorizer)
+	assert.Equal(t, []string{"echo:write"}, rc.Identity.Scopes)
+}
+
+func TestFromRequest_APIGatewayV1Authorizers(t *testing.T) {
+	tests := []struct {
+		authorizer     map[string]any
+		name           string
+		wantSubject    string
+		wantAuthorizer string
+	}{
+		{
+			name:           "cognito",
+			authorizer:     map[string]any{"claims": map[string]any{"sub": "cognito-user"}},
+			wantSubject:    "cognito-user",
+			wantAuthorizer: "cognito",
+		},
+		{
+			name:           "lambda",
+			authorizer:     map[string]any{"principalId": "lambda-user", "tier": "gold"},
+			wantSubject:    "lambda-user",
+			wantAuthorizer: "lambda",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := capture(t, events.APIGatewayProxyRequest{
+				HTTPMethod: http.MethodGet,
+				Path:       "/whoami",
+				RequestContext: events.APIGatewayProxyRequestContext{
+					RequestID:  "v1-req",
+					Stage:      "dev",
+					Identity:   events.APIGatewayRequestIdentity{SourceIP: "198.51.100.1"},
+					Authorizer: tt.authorizer,
+				},
+			})
+
+			assert.Equal(t, lambdactx.SourceAPIGatewayV1, rc.Source)
+			assert.Equal(t, "v1-req", rc.RequestID)
+			assert.Equal(t, "198.51.100.1", rc.SourceIP)
+			require.NotNil(t, rc.Identity)
+			assert.Equal(t, tt.wantSubject, rc.Identity.Subject)
+			assert.Equal(t, tt.wantAuthorizer, rc.Identity.Authorizer)
+		})
+	}
+}
+
+func TestFromRequest_ALBUsesTraceID(t *testing.T) {
+	rc := capture(t, events.ALBTargetGroupRequest{
+		HTTPMethod: http.MethodGet,
+		Path:       "/whoami",
+		Headers:    map[string]string{"x-amzn-trace-id": "Root=1-abc", "host": "alb.example.com"},
+		RequestContext: events.ALBTargetGroupRequestContext{
+			ELB: events.ELBContext{TargetGroupArn: "arn:aws:elasticloadbalancing:us-west-2:123456789012:targetgroup/hello-go/abc"},
+		},
+	})
+
+	assert.Equal(t, lambdactx.SourceALB, rc.Source)
+	assert.Equal(t, "Root=1-abc", rc.RequestID)
+	assert.Nil(t, rc.Identity)
+}
+
+func TestFromRequest_ALBSourceIP(t *testing.T) {
+	tests := []struct {
+		event events.ALBTargetGroupRequest
+		name  string
+		want  string
+	}{
+		{
+			name:  "client address appended by the ALB",
+			event: events.ALBTargetGroupRequest{Headers: map[string]string{"x-forwarded-for": "192.0.2.10"}},
+			want:  "192.0.2.10",
+		},
+		{
+			name:  "spoofed entries are ignored",
+			event: events.ALBTargetGroupRequest{Headers: map[string]string{"x-forwarded-for": "10.0.0.1, 198.51.100.7"}},
+			want:  "198.51.100.7",
+		},
+		{
+			name: "multi-value headers",
+			event: events.ALBTargetGroupRequest{MultiValueHeaders: map[string][]string{
+				"x-forwarded-for": {"10.0.0.1", "2001:db8::7"},
+			}},
+			want: "2001:db8::7",
+		},
+		{
+			name:  "not an address",
+			event: events.ALBTargetGroupRequest{Headers: map[string]string{"x-forwarded-for": "unknown"}},
+		},
+		{name: "missing header"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.event.HTTPMethod = http.MethodGet
+			tt.event.Path = "/whoami"
+			tt.event.RequestContext.ELB.TargetGroupArn = "arn:aws:elasticloadbalancing:us-west-2:123456789012:targetgroup/hello-go/abc"
+
+			rc := capture(t, tt.event)
+
+			assert.Equal(t, lambdactx.SourceALB, rc.Source)
+			assert.Equal(t, tt.want, rc.SourceIP)
+		})
+	}
+}
+
+func TestFromRequest_HTTPHeaders(t *testing.T) {
+	var got lambdactx.RequestContext
+	handler := middleware.RequestID(middleware.RealIP(lambdactx.Middleware(
+		http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			got = lambdactx.FromRequest(r)
+		}),
+	)))
+
+	req := httptest.NewRequest(http.MethodGet, "http://hello.example.com:8080/whoami", nil)
+	req.Header.Set("X-Request-Id", "from-proxy")
+	req.Header.Set("X-Forwarded-For", "192.0.2.10")
+	// Only RealIP may believe it, by rewriting the request's host.
+	req.Header.Set("X-Forwarded-Host", "other.example.com")
+	req.Header.Set("User-Agent", "curl/8")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, lambdactx.RequestContext{
+		RequestID:  "from-proxy",
+		DomainName: "hello.example.com",
+		SourceIP:   "192.0.2.10",
+		UserAgent:  "curl/8",
+		Source:     lambdactx.SourceHTTP,
+	}, got)
+}
+
+func TestWithIdentity(t *testing.T) {
+	ctx := lambdactx.NewContext(context.Background(), lambdactx.RequestContext{RequestID: "req-1"})
+	ctx = lambdactx.WithIdentity(ctx, &lambdactx.Identity{Subject: "key-1", Authorizer: "api-key"})
+
+	rc, ok := lambdactx.FromContext(ctx)
+	require.True(t, ok)
+	assert.Equal(t, "req-1", rc.RequestID)
+	assert.Equal(t, "key-1", rc.Identity.Subject)
+}
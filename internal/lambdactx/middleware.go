@@ -0,0 +1,49 @@
+package lambdactx
+
+import (
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware resolves the RequestContext once per request, stores it in the
+// request context and records it on the active span.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := FromRequest(r)
+		trace.SpanFromContext(r.Context()).SetAttributes(rc.Attributes()...)
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), rc)))
+	})
+}
+
+// Attributes returns the span attributes describing rc.
+func (rc RequestContext) Attributes() []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("request.source", string(rc.Source)),
+	}
+	if rc.RequestID != "" {
+		attrs = append(attrs, attribute.String("request.id", rc.RequestID))
+	}
+	if rc.Stage != "" {
+		attrs = append(attrs, attribute.String("apigateway.stage", rc.Stage))
+	}
+	if rc.DomainName != "" {
+		attrs = append(attrs, semconv.ServerAddress(rc.DomainName))
+	}
+	if rc.SourceIP != "" {
+		attrs = append(attrs, semconv.ClientAddress(rc.SourceIP))
+	}
+	if rc.Tenant != "" {
+		attrs = append(attrs, attribute.String("tenant.id", rc.Tenant))
+	}
+	if rc.Identity != nil {
+		attrs = append(attrs, semconv.EnduserID(rc.Identity.Subject))
+		if len(rc.Identity.Scopes) > 0 {
+			attrs = append(attrs, semconv.EnduserScope(strings.Join(rc.Identity.Scopes, " ")))
+		}
+	}
+	return attrs
+}
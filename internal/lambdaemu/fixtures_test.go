@@ -0,0 +1,53 @@
+package lambdaemu_test
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/api"
+	"github.com/savisec/hello-go/internal/audit"
+	"github.com/savisec/hello-go/internal/config"
+	"github.com/savisec/hello-go/internal/lambdaemu"
+	"github.com/savisec/hello-go/internal/lambdaproxy"
+	"github.com/savisec/hello-go/internal/logging"
+	"github.com/savisec/hello-go/internal/router"
+)
+
+func TestGenerateFixtures(t *testing.T) {
+	fixtures, err := lambdaemu.GenerateFixtures(api.OpenAPISpec)
+	require.NoError(t, err)
+
+	var ids []string
+	for _, f := range fixtures {
+		ids = append(ids, f.OperationID)
+	}
+	assert.Equal(t, []string{"echo", "healthz", "readyz"}, ids)
+
+	// Every generated event must be accepted by the real handler.
+	r, err := router.BuildRouter(&config.Config{}, slog.New(slog.DiscardHandler), audit.NewLogger(nil, nil), logging.NewFingerprinter(""))
+	require.NoError(t, err)
+	handler := lambdaproxy.New(r)
+	for _, f := range fixtures {
+		payload, err := json.Marshal(f.Event)
+		require.NoError(t, err)
+
+		out, err := handler.Invoke(context.Background(), payload)
+		require.NoError(t, err)
+
+		var resp events.APIGatewayV2HTTPResponse
+		require.NoError(t, json.Unmarshal(out, &resp))
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "%s: %s", f.OperationID, resp.Body)
+	}
+}
+
+func TestGenerateFixtures_InvalidSpec(t *testing.T) {
+	_, err := lambdaemu.GenerateFixtures([]byte("paths: ["))
+	assert.Error(t, err)
+}
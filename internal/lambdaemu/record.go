@@ -0,0 +1,168 @@
+package lambdaemu
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+
+	"github.com/savisec/hello-go/internal/logging"
+)
+
+// maxRecordingLine bounds a single JSONL line; it leaves room for a
+// maximum-size event and its response.
+const maxRecordingLine = 4 * maxPayloadBytes
+
+// Recording is one captured invocation, stored as a line of JSONL.
+type Recording struct {
+	Time     time.Time       `json:"time"`
+	Event    json.RawMessage `json:"event"`
+	Response json.RawMessage `json:"response,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// Recorder is a lambda.Handler that writes every invocation it passes on
+// to w as a redacted Recording.
+type Recorder struct {
+	next     lambda.Handler
+	w        io.Writer
+	redactor *logging.Redactor
+	logger   *slog.Logger
+	mu       sync.Mutex
+}
+
+// NewRecorder wraps next so each event and its response are appended to w.
+func NewRecorder(next lambda.Handler, w io.Writer, redactor *logging.Redactor, logger *slog.Logger) *Recorder {
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	return &Recorder{next: next, w: w, redactor: redactor, logger: logger}
+}
+
+func (r *Recorder) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	resp, err := r.next.Invoke(ctx, payload)
+
+	// A recording failure must never fail the invocation itself.
+	if recErr := r.record(payload, resp, err); recErr != nil {
+		r.logger.Warn("Failed to record Lambda invocation", "error", recErr)
+	}
+
+	return resp, err
+}
+
+func (r *Recorder) record(payload, resp []byte, invokeErr error) error {
+	rec := Recording{Time: time.Now().UTC()}
+
+	event, err := r.redactor.JSON(payload)
+	if err != nil {
+		return fmt.Errorf("failed to redact event: %w", err)
+	}
+	rec.Event = event
+
+	if invokeErr != nil {
+		rec.Error = r.redactor.String(invokeErr.Error())
+	} else if len(resp) > 0 {
+		if rec.Response, err = r.redactor.JSON(resp); err != nil {
+			return fmt.Errorf("failed to redact response: %w", err)
+		}
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode recording: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	return nil
+}
+
+// ReplayResult is the outcome of replaying one Recording.
+type ReplayResult struct {
+	Recording Recording
+	Response  json.RawMessage
+	Err       error
+	Line      int
+}
+
+// Match reports whether the replayed invocation produced the recorded
+// outcome. Responses are compared as JSON values, not bytes, and so are
+// JSON documents embedded in strings such as the response body, since
+// redaction re-encodes them.
+func (r ReplayResult) Match() bool {
+	if r.Recording.Error != "" || r.Err != nil {
+		return r.Err != nil && r.Err.Error() == r.Recording.Error
+	}
+
+	var want, got any
+	if err := json.Unmarshal(r.Recording.Response, &want); err != nil {
+		return false
+	}
+	if err := json.Unmarshal(r.Response, &got); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(decodeEmbedded(want), decodeEmbedded(got))
+}
+
+// decodeEmbedded replaces string values holding a JSON object or array with
+// their decoded form.
+func decodeEmbedded(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for key, field := range val {
+			val[key] = decodeEmbedded(field)
+		}
+	case []any:
+		for i, item := range val {
+			val[i] = decodeEmbedded(item)
+		}
+	case string:
+		trimmed := strings.TrimSpace(val)
+		if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			var nested any
+			if json.Unmarshal([]byte(trimmed), &nested) == nil {
+				return decodeEmbedded(nested)
+			}
+		}
+	}
+	return v
+}
+
+// Replay runs every Recording read from src through handler.
+func Replay(ctx context.Context, handler lambda.Handler, src io.Reader) ([]ReplayResult, error) {
+	scanner := bufio.NewScanner(src)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordingLine)
+
+	var results []ReplayResult
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var rec Recording
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("failed to decode recording on line %d: %w", line, err)
+		}
+
+		resp, err := handler.Invoke(NewContext(ctx), rec.Event)
+		results = append(results, ReplayResult{Recording: rec, Response: resp, Err: err, Line: line})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recordings: %w", err)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,86 @@
+package lambdaemu_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/internal/audit"
+	"github.com/savisec/hello-go/internal/config"
+	"github.com/savisec/hello-go/internal/lambdaemu"
+	"github.com/savisec/hello-go/internal/lambdaproxy"
+	"github.com/savisec/hello-go/internal/logging"
+	"github.com/savisec/hello-go/internal/router"
+)
+
+func TestRecorder_WritesRedactedJSONL(t *testing.T) {
+	redactor, err := logging.NewRedactor(config.RedactionConfig{
+		Fields:   []string{"authorization"},
+		Patterns: []string{"email"},
+	}, logging.NewFingerprinter(""))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	recorder := lambdaemu.NewRecorder(handlerFunc(func(_ context.Context, payload []byte) ([]byte, error) {
+		if strings.Contains(string(payload), "fail") {
+			return nil, errors.New("failed for a@example.com")
+		}
+		return []byte(`{"statusCode":200,"body":"sent to a@example.com"}`), nil
+	}), &buf, redactor, nil)
+
+	resp, err := recorder.Invoke(context.Background(), []byte(`{"headers":{"authorization":"Bearer abc"}}`))
+	require.NoError(t, err)
+	assert.Contains(t, string(resp), "a@example.com", "the caller gets the unredacted response")
+
+	_, err = recorder.Invoke(context.Background(), []byte(`{"rawPath":"/fail"}`))
+	require.Error(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var first, second lambdaemu.Recording
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+
+	assert.JSONEq(t, `{"headers":{"authorization":"[REDACTED]"}}`, string(first.Event))
+	assert.JSONEq(t, `{"statusCode":200,"body":"sent to [REDACTED:email]"}`, string(first.Response))
+	assert.Equal(t, "failed for [REDACTED:email]", second.Error)
+}
+
+// TestReplay_Golden replays recorded invocations through the real router,
+// so any change in the Lambda response to a recorded event fails here.
+func TestReplay_Golden(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "recorded.jsonl"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = f.Close() })
+
+	r, err := router.BuildRouter(&config.Config{}, slog.New(slog.DiscardHandler), audit.NewLogger(nil, nil), logging.NewFingerprinter(""))
+	require.NoError(t, err)
+	handler := lambdaproxy.New(r)
+
+	results, err := lambdaemu.Replay(context.Background(), handler, f)
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+
+	for _, result := range results {
+		assert.True(t, result.Match(), "line %d: want %s, got %s", result.Line, result.Recording.Response, result.Response)
+	}
+}
+
+func TestReplayResult_Mismatch(t *testing.T) {
+	result := lambdaemu.ReplayResult{
+		Recording: lambdaemu.Recording{Response: json.RawMessage(`{"statusCode":200,"body":"{\"a\":1}"}`)},
+		Response:  json.RawMessage(`{"statusCode":200,"body":"{\"a\":2}\n"}`),
+	}
+
+	assert.False(t, result.Match())
+}
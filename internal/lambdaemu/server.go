@@ -0,0 +1,383 @@
+package lambdaemu
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+// InvocationsPath is the Lambda Invoke API path the runtime interface
+// emulator exposes; raw event payloads POSTed here bypass HTTP translation.
+const InvocationsPath = "/2015-03-31/functions/function/invocations"
+
+const (
+	functionName = "hello-go"
+	functionARN  = "arn:aws:lambda:local:000000000000:function:" + functionName
+
+	// maxPayloadBytes mirrors the synchronous invocation payload limit.
+	maxPayloadBytes = 6 << 20
+
+	defaultTimeout = 30 * time.Second
+)
+
+// streamChunkBytes bounds each read from a streamed body before it is
+// flushed to the client.
+const streamChunkBytes = 32 << 10
+
+// StreamingFunc is the signature of a Lambda response streaming handler.
+type StreamingFunc = func(context.Context, events.LambdaFunctionURLRequest) (*events.LambdaFunctionURLStreamingResponse, error)
+
+// Server runs a Lambda handler in-process behind a plain HTTP server.
+type Server struct {
+	handler lambda.Handler
+	stream  StreamingFunc
+	logger  *slog.Logger
+	timeout time.Duration
+}
+
+// New creates a Server that invokes handler with the given per-invocation
+// timeout. A zero timeout uses the Lambda default of 30 seconds.
+func New(handler lambda.Handler, timeout time.Duration, logger *slog.Logger) *Server {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	return &Server{handler: handler, logger: logger, timeout: timeout}
+}
+
+// NewStreaming creates a Server that sends HTTP requests to stream as
+// Function URL events in RESPONSE_STREAM mode, flushing the body to the
+// client as it is produced. Raw events POSTed to InvocationsPath still go
+// to handler.
+func NewStreaming(handler lambda.Handler, stream StreamingFunc, timeout time.Duration, logger *slog.Logger) *Server {
+	s := New(handler, timeout, logger)
+	s.stream = stream
+	return s
+}
+
+// ServeHTTP serves the Invoke API on InvocationsPath and translates every
+// other request into an API Gateway v2 event.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPayloadBytes))
+	if err != nil {
+		http.Error(w, "Request payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if r.URL.Path == InvocationsPath && r.Method == http.MethodPost {
+		s.serveInvoke(w, r, body)
+		return
+	}
+	if s.stream != nil {
+		s.serveStreamingEvent(w, r, body)
+		return
+	}
+	s.serveHTTPEvent(w, r, body)
+}
+
+func (s *Server) serveInvoke(w http.ResponseWriter, r *http.Request, payload []byte) {
+	resp, err := s.invoke(r.Context(), payload)
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		// Like the Invoke API, function errors are reported in the body with
+		// a 200 status; the header tells callers the payload is an error.
+		w.Header().Set("X-Amz-Function-Error", "Unhandled")
+		_ = json.NewEncoder(w).Encode(errorResponse(err))
+		return
+	}
+	_, _ = w.Write(resp)
+}
+
+func (s *Server) serveHTTPEvent(w http.ResponseWriter, r *http.Request, body []byte) {
+	payload, err := json.Marshal(NewAPIGatewayV2Request(r, body))
+	if err != nil {
+		http.Error(w, "Failed to encode event", http.StatusInternalServerError)
+		return
+	}
+
+	out, err := s.invoke(r.Context(), payload)
+	if err != nil {
+		// API Gateway hides function errors behind a generic 500.
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var resp events.APIGatewayV2HTTPResponse
+	if err := json.Unmarshal(out, &resp); err != nil {
+		s.logger.Error("Lambda returned an invalid API Gateway response", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := writeResponse(w, resp); err != nil {
+		s.logger.Error("Failed to decode Lambda response body", "error", err)
+	}
+}
+
+func (s *Server) serveStreamingEvent(w http.ResponseWriter, r *http.Request, body []byte) {
+	ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+	defer cancel()
+
+	ctx = NewContext(ctx)
+	lc, _ := lambdacontext.FromContext(ctx)
+
+	resp, err := s.stream(ctx, NewFunctionURLRequest(r, body))
+	if err != nil {
+		s.logger.Error("Lambda invocation failed", "request_id", lc.AwsRequestID, "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	for name, value := range resp.Headers {
+		w.Header().Set(name, value)
+	}
+	for _, cookie := range resp.Cookies {
+		w.Header().Add("Set-Cookie", cookie)
+	}
+	status := resp.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+
+	if resp.Body == nil {
+		return
+	}
+	if closer, ok := resp.Body.(io.Closer); ok {
+		//nolint:errcheck
+		//goland:noinspection GoUnhandledErrorResult
+		defer closer.Close()
+	}
+
+	// Without a Content-Length, every flush goes out as its own chunk.
+	rc := http.NewResponseController(w)
+	buf := make([]byte, streamChunkBytes)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return
+			}
+			_ = rc.Flush()
+		}
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			s.logger.Error("Lambda response stream failed", "request_id", lc.AwsRequestID, "error", err)
+			return
+		}
+	}
+}
+
+func (s *Server) invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	ctx = NewContext(ctx)
+
+	resp, err := s.handler.Invoke(ctx, payload)
+	if err != nil {
+		lc, _ := lambdacontext.FromContext(ctx)
+		s.logger.Error("Lambda invocation failed", "request_id", lc.AwsRequestID, "error", err)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// NewContext returns a copy of ctx carrying the Lambda context the runtime
+// would attach to an invocation, with a fresh request ID.
+func NewContext(ctx context.Context) context.Context {
+	return lambdacontext.NewContext(ctx, &lambdacontext.LambdaContext{
+		AwsRequestID:       newRequestID(),
+		InvokedFunctionArn: functionARN,
+	})
+}
+
+// NewAPIGatewayV2Request builds the payload API Gateway would send for r.
+func NewAPIGatewayV2Request(r *http.Request, body []byte) events.APIGatewayV2HTTPRequest {
+	now := time.Now().UTC()
+
+	headers := make(map[string]string, len(r.Header))
+	for name, values := range r.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+
+	// HTTP APIs move cookies out of the headers into their own field.
+	var cookies []string
+	if cookie, ok := headers["cookie"]; ok {
+		delete(headers, "cookie")
+		for c := range strings.SplitSeq(cookie, ";") {
+			if c = strings.TrimSpace(c); c != "" {
+				cookies = append(cookies, c)
+			}
+		}
+	}
+
+	var query map[string]string
+	if values := r.URL.Query(); len(values) > 0 {
+		query = make(map[string]string, len(values))
+		for name, v := range values {
+			query[name] = strings.Join(v, ",")
+		}
+	}
+
+	sourceIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		sourceIP = r.RemoteAddr
+	}
+
+	domainName, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		domainName = r.Host
+	}
+
+	event := events.APIGatewayV2HTTPRequest{
+		Version:               "2.0",
+		RouteKey:              "$default",
+		RawPath:               r.URL.Path,
+		RawQueryString:        r.URL.RawQuery,
+		Cookies:               cookies,
+		Headers:               headers,
+		QueryStringParameters: query,
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			RouteKey:     "$default",
+			AccountID:    "000000000000",
+			Stage:        "$default",
+			RequestID:    newRequestID(),
+			APIID:        "local",
+			DomainName:   domainName,
+			DomainPrefix: strings.SplitN(domainName, ".", 2)[0],
+			Time:         now.Format("02/Jan/2006:15:04:05 -0700"),
+			TimeEpoch:    now.UnixMilli(),
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Protocol:  r.Proto,
+				SourceIP:  sourceIP,
+				UserAgent: r.UserAgent(),
+			},
+		},
+	}
+
+	if utf8.Valid(body) {
+		event.Body = string(body)
+	} else {
+		event.Body = base64.StdEncoding.EncodeToString(body)
+		event.IsBase64Encoded = true
+	}
+
+	return event
+}
+
+// NewFunctionURLRequest builds the payload a Lambda function URL would send
+// for r. It shares the API Gateway v2 payload format, minus the route and
+// stage.
+func NewFunctionURLRequest(r *http.Request, body []byte) events.LambdaFunctionURLRequest {
+	v2 := NewAPIGatewayV2Request(r, body)
+	rc := v2.RequestContext
+
+	return events.LambdaFunctionURLRequest{
+		Version:               v2.Version,
+		RawPath:               v2.RawPath,
+		RawQueryString:        v2.RawQueryString,
+		Cookies:               v2.Cookies,
+		Headers:               v2.Headers,
+		QueryStringParameters: v2.QueryStringParameters,
+		Body:                  v2.Body,
+		IsBase64Encoded:       v2.IsBase64Encoded,
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			AccountID:    rc.AccountID,
+			RequestID:    rc.RequestID,
+			APIID:        rc.APIID,
+			DomainName:   rc.DomainName,
+			DomainPrefix: rc.DomainPrefix,
+			Time:         rc.Time,
+			TimeEpoch:    rc.TimeEpoch,
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{
+				Method:    rc.HTTP.Method,
+				Path:      rc.HTTP.Path,
+				Protocol:  rc.HTTP.Protocol,
+				SourceIP:  rc.HTTP.SourceIP,
+				UserAgent: rc.HTTP.UserAgent,
+			},
+		},
+	}
+}
+
+func writeResponse(w http.ResponseWriter, resp events.APIGatewayV2HTTPResponse) error {
+	for name, value := range resp.Headers {
+		w.Header().Set(name, value)
+	}
+	for name, values := range resp.MultiValueHeaders {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+	for _, cookie := range resp.Cookies {
+		w.Header().Add("Set-Cookie", cookie)
+	}
+
+	body := []byte(resp.Body)
+	if resp.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(resp.Body)
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return err
+		}
+		body = decoded
+	}
+
+	status := resp.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	_, err := w.Write(body)
+	return err
+}
+
+// invokeError matches the error payload the Lambda runtime reports.
+type invokeError struct {
+	Message string `json:"errorMessage"`
+	Type    string `json:"errorType"`
+}
+
+func errorResponse(err error) invokeError {
+	errorType := reflect.TypeOf(err)
+	if errorType.Kind() == reflect.Pointer {
+		errorType = errorType.Elem()
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return invokeError{Message: "Task timed out", Type: "Runtime.Timeout"}
+	}
+	return invokeError{Message: err.Error(), Type: errorType.Name()}
+}
+
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	// Format as a version 4 UUID, the shape real request IDs take.
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	s := hex.EncodeToString(b[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
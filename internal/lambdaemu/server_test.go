@@ -0,0 +1,168 @@
+package lambdaemu_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/internal/lambdaemu"
+	"github.com/savisec/hello-go/internal/lambdaproxy"
+)
+
+type handlerFunc func(ctx context.Context, payload []byte) ([]byte, error)
+
+func (f handlerFunc) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	return f(ctx, payload)
+}
+
+func TestServer_TranslatesHTTPRequests(t *testing.T) {
+	r := chi.NewRouter()
+	r.Post("/v1/echo", func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("session")
+		require.NoError(t, err)
+		body, _ := io.ReadAll(r.Body)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Query", r.URL.Query().Get("q"))
+		http.SetCookie(w, &http.Cookie{Name: "seen", Value: cookie.Value})
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(body)
+	})
+
+	server := httptest.NewServer(lambdaemu.New(lambdaproxy.New(r), 0, nil))
+	t.Cleanup(server.Close)
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/echo?q=hi", strings.NewReader(`{"message":"hi"}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.JSONEq(t, `{"message":"hi"}`, string(body))
+	assert.Equal(t, "hi", resp.Header.Get("X-Query"))
+	assert.Equal(t, "seen=abc", resp.Header.Get("Set-Cookie"))
+}
+
+func TestServer_StreamsResponses(t *testing.T) {
+	proceed := make(chan struct{})
+
+	r := chi.NewRouter()
+	r.Get("/stream", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("X-Query", r.URL.Query().Get("q"))
+		_, _ = w.Write([]byte("first;"))
+		w.(http.Flusher).Flush()
+
+		<-proceed
+		_, _ = w.Write([]byte("second"))
+	})
+
+	server := httptest.NewServer(lambdaemu.NewStreaming(lambdaproxy.New(r), lambdaproxy.NewStreaming(r).Handle, time.Second, nil))
+	t.Cleanup(server.Close)
+
+	resp, err := http.Get(server.URL + "/stream?q=hi")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"chunked"}, resp.TransferEncoding)
+	assert.Equal(t, "hi", resp.Header.Get("X-Query"))
+
+	// The first chunk arrives while the handler is still blocked.
+	first := make(chan string, 1)
+	go func() {
+		buf := make([]byte, len("first;"))
+		_, _ = io.ReadFull(resp.Body, buf)
+		first <- string(buf)
+	}()
+	select {
+	case chunk := <-first:
+		assert.Equal(t, "first;", chunk)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the first chunk")
+	}
+
+	close(proceed)
+	rest, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(rest))
+}
+
+func TestServer_InvokeAPI(t *testing.T) {
+	var lc *lambdacontext.LambdaContext
+	handler := handlerFunc(func(ctx context.Context, payload []byte) ([]byte, error) {
+		lc, _ = lambdacontext.FromContext(ctx)
+		return payload, nil
+	})
+
+	server := httptest.NewServer(lambdaemu.New(handler, time.Second, nil))
+	t.Cleanup(server.Close)
+
+	resp, err := http.Post(server.URL+lambdaemu.InvocationsPath, "application/json", bytes.NewBufferString(`{"Records":[]}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"Records":[]}`, string(body))
+	require.NotNil(t, lc)
+	assert.Len(t, lc.AwsRequestID, 36)
+	assert.Contains(t, lc.InvokedFunctionArn, ":function:hello-go")
+}
+
+func TestServer_InvokeAPIReportsFunctionErrors(t *testing.T) {
+	handler := handlerFunc(func(context.Context, []byte) ([]byte, error) {
+		return nil, errors.New("boom")
+	})
+
+	server := httptest.NewServer(lambdaemu.New(handler, time.Second, nil))
+	t.Cleanup(server.Close)
+
+	resp, err := http.Post(server.URL+lambdaemu.InvocationsPath, "application/json", bytes.NewBufferString(`{}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var payload map[string]string
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&payload))
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "Unhandled", resp.Header.Get("X-Amz-Function-Error"))
+	assert.Equal(t, "boom", payload["errorMessage"])
+}
+
+func TestNewAPIGatewayV2Request_BinaryBody(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/upload", nil)
+
+	event := lambdaemu.NewAPIGatewayV2Request(req, []byte{0xff, 0xfe})
+
+	assert.True(t, event.IsBase64Encoded)
+	assert.Equal(t, "//4=", event.Body)
+	assert.Equal(t, events.APIGatewayV2HTTPRequestContextHTTPDescription{
+		Method:   http.MethodPut,
+		Path:     "/upload",
+		Protocol: "HTTP/1.1",
+		SourceIP: "192.0.2.1",
+	}, event.RequestContext.HTTP)
+}
@@ -0,0 +1,146 @@
+package lambdaproxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+)
+
+// Handler is a lambda.Handler that accepts API Gateway REST (v1), API Gateway
+// HTTP (v2), ALB target group and Function URL events, serves them with an
+// http.Handler, and replies in the format matching the incoming event.
+type Handler struct {
+	handler http.Handler
+	v1      core.RequestAccessor
+	v2      core.RequestAccessorV2
+	alb     core.RequestAccessorALB
+}
+
+// New creates a Handler that serves events with h.
+func New(h http.Handler) *Handler {
+	return &Handler{
+		handler: h,
+	}
+}
+
+// Invoke implements lambda.Handler.
+func (h *Handler) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	kind, err := DetectEventKind(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp any
+	switch kind {
+	case EventAPIGatewayV1:
+		resp, err = h.proxyV1(ctx, payload)
+	case EventAPIGatewayV2:
+		resp, err = h.proxyV2(ctx, payload)
+	case EventFunctionURL:
+		resp, err = h.proxyFunctionURL(ctx, payload)
+	case EventALB:
+		resp, err = h.proxyALB(ctx, payload)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(resp)
+}
+
+func (h *Handler) proxyV1(ctx context.Context, payload []byte) (events.APIGatewayProxyResponse, error) {
+	var event events.APIGatewayProxyRequest
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return events.APIGatewayProxyResponse{}, fmt.Errorf("failed to decode API Gateway v1 event: %w", err)
+	}
+
+	req, err := h.v1.EventToRequestWithContext(ctx, event)
+	if err != nil {
+		return core.GatewayTimeout(), fmt.Errorf("could not convert proxy event to request: %w", err)
+	}
+
+	w := core.NewProxyResponseWriter()
+	h.handler.ServeHTTP(w, req)
+
+	resp, err := w.GetProxyResponse()
+	if err != nil {
+		return core.GatewayTimeout(), fmt.Errorf("error while generating proxy response: %w", err)
+	}
+	return resp, nil
+}
+
+func (h *Handler) proxyV2(ctx context.Context, payload []byte) (events.APIGatewayV2HTTPResponse, error) {
+	var event events.APIGatewayV2HTTPRequest
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return events.APIGatewayV2HTTPResponse{}, fmt.Errorf("failed to decode API Gateway v2 event: %w", err)
+	}
+
+	req, err := h.v2.EventToRequestWithContext(ctx, event)
+	if err != nil {
+		return core.GatewayTimeoutV2(), fmt.Errorf("could not convert proxy event to request: %w", err)
+	}
+
+	w := core.NewProxyResponseWriterV2()
+	h.handler.ServeHTTP(w, req)
+
+	resp, err := w.GetProxyResponse()
+	if err != nil {
+		return core.GatewayTimeoutV2(), fmt.Errorf("error while generating proxy response: %w", err)
+	}
+	return resp, nil
+}
+
+// proxyFunctionURL serves a Function URL event. Function URLs use the API
+// Gateway v2 payload format, so the v2 path does the conversion.
+func (h *Handler) proxyFunctionURL(ctx context.Context, payload []byte) (events.LambdaFunctionURLResponse, error) {
+	resp, err := h.proxyV2(ctx, payload)
+	return events.LambdaFunctionURLResponse{
+		StatusCode:      resp.StatusCode,
+		Headers:         resp.Headers,
+		Body:            resp.Body,
+		IsBase64Encoded: resp.IsBase64Encoded,
+		Cookies:         resp.Cookies,
+	}, err
+}
+
+func (h *Handler) proxyALB(ctx context.Context, payload []byte) (events.ALBTargetGroupResponse, error) {
+	var event events.ALBTargetGroupRequest
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return events.ALBTargetGroupResponse{}, fmt.Errorf("failed to decode ALB event: %w", err)
+	}
+
+	req, err := h.alb.EventToRequestWithContext(ctx, event)
+	if err != nil {
+		return core.GatewayTimeoutALB(), fmt.Errorf("could not convert ALB event to request: %w", err)
+	}
+
+	w := core.NewProxyResponseWriterALB()
+	h.handler.ServeHTTP(w, req)
+
+	resp, err := w.GetProxyResponse()
+	if err != nil {
+		return core.GatewayTimeoutALB(), fmt.Errorf("error while generating proxy response: %w", err)
+	}
+
+	// ALB expects the status line form, e.g. "200 OK".
+	resp.StatusDescription = fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+
+	// An ALB only accepts multiValueHeaders in the response when multi-value
+	// headers are enabled on the target group, which is signalled by the
+	// request carrying multiValueHeaders.
+	if event.MultiValueHeaders == nil {
+		resp.Headers = make(map[string]string, len(resp.MultiValueHeaders))
+		for key, values := range resp.MultiValueHeaders {
+			if len(values) > 0 {
+				resp.Headers[key] = values[len(values)-1]
+			}
+		}
+		resp.MultiValueHeaders = nil
+	}
+
+	return resp, nil
+}
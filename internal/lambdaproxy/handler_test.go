@@ -0,0 +1,159 @@
+package lambdaproxy_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/internal/lambdaproxy"
+)
+
+const echoBody = `{"message":"hi","author":"tester"}`
+
+func newTestHandler() *lambdaproxy.Handler {
+	r := chi.NewRouter()
+	r.Post("/v1/echo", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("X-Multi", "a")
+		w.Header().Add("X-Multi", "b")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(echoBody))
+	})
+	return lambdaproxy.New(r)
+}
+
+func invoke(t *testing.T, h *lambdaproxy.Handler, event any, out any) {
+	t.Helper()
+
+	payload, err := json.Marshal(event)
+	require.NoError(t, err)
+
+	resp, err := h.Invoke(context.Background(), payload)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(resp, out))
+}
+
+func TestDetectEventKind(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    lambdaproxy.EventKind
+		wantErr bool
+	}{
+		{name: "api gateway v1", payload: `{"httpMethod":"GET","path":"/","requestContext":{"stage":"prod"}}`, want: lambdaproxy.EventAPIGatewayV1},
+		{name: "api gateway v1 versioned", payload: `{"version":"1.0","httpMethod":"GET"}`, want: lambdaproxy.EventAPIGatewayV1},
+		{name: "api gateway v2", payload: `{"version":"2.0","requestContext":{"domainName":"abc.execute-api.us-west-2.amazonaws.com"}}`, want: lambdaproxy.EventAPIGatewayV2},
+		{name: "function url", payload: `{"version":"2.0","requestContext":{"domainName":"abc.lambda-url.us-west-2.on.aws"}}`, want: lambdaproxy.EventFunctionURL},
+		{name: "alb", payload: `{"httpMethod":"GET","requestContext":{"elb":{"targetGroupArn":"arn"}}}`, want: lambdaproxy.EventALB},
+		{name: "unknown", payload: `{"Records":[]}`, wantErr: true},
+		{name: "not json", payload: `nope`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := lambdaproxy.DetectEventKind([]byte(tt.payload))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestHandler_APIGatewayV1(t *testing.T) {
+	var resp events.APIGatewayProxyResponse
+	invoke(t, newTestHandler(), events.APIGatewayProxyRequest{
+		HTTPMethod: http.MethodPost,
+		Path:       "/v1/echo",
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       echoBody,
+	}, &resp)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, echoBody, resp.Body)
+	assert.Equal(t, []string{"a", "b"}, resp.MultiValueHeaders["X-Multi"])
+}
+
+func TestHandler_APIGatewayV2(t *testing.T) {
+	var resp events.APIGatewayV2HTTPResponse
+	invoke(t, newTestHandler(), events.APIGatewayV2HTTPRequest{
+		Version: "2.0",
+		RawPath: "/v1/echo",
+		Headers: map[string]string{"content-type": "application/json"},
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			DomainName: "abc.execute-api.us-west-2.amazonaws.com",
+			HTTP:       events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodPost, Path: "/v1/echo"},
+		},
+		Body: echoBody,
+	}, &resp)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, echoBody, resp.Body)
+	assert.Equal(t, "a,b", resp.Headers["X-Multi"])
+}
+
+func TestHandler_FunctionURL(t *testing.T) {
+	var resp events.LambdaFunctionURLResponse
+	invoke(t, newTestHandler(), events.LambdaFunctionURLRequest{
+		Version: "2.0",
+		RawPath: "/v1/echo",
+		Headers: map[string]string{"content-type": "application/json"},
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			DomainName: "abc.lambda-url.us-west-2.on.aws",
+			HTTP:       events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost, Path: "/v1/echo"},
+		},
+		Body: echoBody,
+	}, &resp)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, echoBody, resp.Body)
+}
+
+func TestHandler_ALB(t *testing.T) {
+	albContext := events.ALBTargetGroupRequestContext{
+		ELB: events.ELBContext{TargetGroupArn: "arn:aws:elasticloadbalancing:us-west-2:123456789012:targetgroup/hello-go/abc"},
+	}
+
+	t.Run("single value headers", func(t *testing.T) {
+		var resp events.ALBTargetGroupResponse
+		invoke(t, newTestHandler(), events.ALBTargetGroupRequest{
+			HTTPMethod:     http.MethodPost,
+			Path:           "/v1/echo",
+			Headers:        map[string]string{"host": "example.com", "content-type": "application/json"},
+			RequestContext: albContext,
+			Body:           echoBody,
+		}, &resp)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "200 OK", resp.StatusDescription)
+		assert.JSONEq(t, echoBody, resp.Body)
+		assert.Equal(t, "b", resp.Headers["X-Multi"])
+		assert.Nil(t, resp.MultiValueHeaders)
+	})
+
+	t.Run("multi value headers", func(t *testing.T) {
+		var resp events.ALBTargetGroupResponse
+		invoke(t, newTestHandler(), events.ALBTargetGroupRequest{
+			HTTPMethod: http.MethodPost,
+			Path:       "/v1/echo",
+			MultiValueHeaders: map[string][]string{
+				"host":         {"example.com"},
+				"content-type": {"application/json"},
+			},
+			RequestContext: albContext,
+			Body:           echoBody,
+		}, &resp)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []string{"a", "b"}, resp.MultiValueHeaders["X-Multi"])
+		assert.Empty(t, resp.Headers)
+	})
+}
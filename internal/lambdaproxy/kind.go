@@ -0,0 +1,49 @@
+package lambdaproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// EventKind identifies which HTTP front door produced a Lambda event.
+type EventKind string
+
+const (
+	EventAPIGatewayV1 EventKind = "apigateway-v1"
+	EventAPIGatewayV2 EventKind = "apigateway-v2"
+	EventALB          EventKind = "alb"
+	EventFunctionURL  EventKind = "function-url"
+)
+
+// eventProbe holds just enough of an event to tell the shapes apart.
+type eventProbe struct {
+	Version        string `json:"version"`
+	HTTPMethod     string `json:"httpMethod"`
+	RequestContext struct {
+		ELB        json.RawMessage `json:"elb"`
+		DomainName string          `json:"domainName"`
+	} `json:"requestContext"`
+}
+
+// DetectEventKind inspects a raw Lambda payload and reports which HTTP event
+// shape it is.
+func DetectEventKind(payload []byte) (EventKind, error) {
+	var probe eventProbe
+	if err := json.Unmarshal(payload, &probe); err != nil {
+		return "", fmt.Errorf("failed to decode event: %w", err)
+	}
+
+	switch {
+	case len(probe.RequestContext.ELB) > 0:
+		return EventALB, nil
+	case probe.Version == "2.0" && strings.Contains(probe.RequestContext.DomainName, ".lambda-url."):
+		return EventFunctionURL, nil
+	case probe.Version == "2.0":
+		return EventAPIGatewayV2, nil
+	case probe.HTTPMethod != "":
+		return EventAPIGatewayV1, nil
+	default:
+		return "", fmt.Errorf("unsupported event shape")
+	}
+}
@@ -0,0 +1,140 @@
+package lambdaproxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+)
+
+// StreamingHandler serves Function URL events for a URL configured with the
+// RESPONSE_STREAM invoke mode. Unlike Handler, it does not buffer the
+// response: the status and headers are returned as soon as the http.Handler
+// writes or flushes, and the body is streamed as it is produced.
+type StreamingHandler struct {
+	handler http.Handler
+	v2      core.RequestAccessorV2
+}
+
+// NewStreaming creates a StreamingHandler that serves events with h.
+func NewStreaming(h http.Handler) *StreamingHandler {
+	return &StreamingHandler{
+		handler: h,
+	}
+}
+
+// Handle is passed to lambda.Start. Streaming responses require the
+// provided.al2 or provided.al2023 runtime, or building with -tags lambda.norpc.
+func (h *StreamingHandler) Handle(ctx context.Context, event events.LambdaFunctionURLRequest) (*events.LambdaFunctionURLStreamingResponse, error) {
+	// Function URL events use the API Gateway v2 payload format.
+	raw, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode Function URL event: %w", err)
+	}
+	var v2Event events.APIGatewayV2HTTPRequest
+	if err := json.Unmarshal(raw, &v2Event); err != nil {
+		return nil, fmt.Errorf("failed to decode Function URL event: %w", err)
+	}
+
+	req, err := h.v2.EventToRequestWithContext(ctx, v2Event)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert Function URL event to request: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+	w := newStreamingResponseWriter(pw)
+
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				w.start(http.StatusInternalServerError)
+				_ = pw.CloseWithError(fmt.Errorf("panic while streaming response: %v", rec))
+				return
+			}
+			w.start(http.StatusOK)
+			_ = pw.Close()
+		}()
+		h.handler.ServeHTTP(w, req)
+	}()
+
+	select {
+	case <-w.ready:
+	case <-ctx.Done():
+		_ = pr.CloseWithError(ctx.Err())
+		return nil, ctx.Err()
+	}
+
+	headers := make(map[string]string, len(w.snapshot))
+	var cookies []string
+	for key, values := range w.snapshot {
+		if strings.EqualFold(key, "Set-Cookie") {
+			cookies = append(cookies, values...)
+			continue
+		}
+		headers[key] = strings.Join(values, ",")
+	}
+
+	return &events.LambdaFunctionURLStreamingResponse{
+		StatusCode: w.status,
+		Headers:    headers,
+		Cookies:    cookies,
+		Body:       pr,
+	}, nil
+}
+
+// streamingResponseWriter writes the body straight into a pipe. The pipe is
+// unbuffered, so every Write reaches the Lambda runtime as it is made.
+type streamingResponseWriter struct {
+	header   http.Header
+	snapshot http.Header
+	body     *io.PipeWriter
+	ready    chan struct{}
+	once     sync.Once
+	status   int
+}
+
+func newStreamingResponseWriter(body *io.PipeWriter) *streamingResponseWriter {
+	return &streamingResponseWriter{
+		header: make(http.Header),
+		body:   body,
+		ready:  make(chan struct{}),
+	}
+}
+
+// start fixes the status and headers and releases the waiting handler. Only
+// the first call has any effect.
+func (w *streamingResponseWriter) start(status int) {
+	w.once.Do(func() {
+		w.status = status
+		w.snapshot = w.header.Clone()
+		close(w.ready)
+	})
+}
+
+func (w *streamingResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *streamingResponseWriter) WriteHeader(status int) {
+	if status >= 100 && status < 200 {
+		return
+	}
+	w.start(status)
+}
+
+func (w *streamingResponseWriter) Write(p []byte) (int, error) {
+	w.start(http.StatusOK)
+	return w.body.Write(p)
+}
+
+// Flush implements http.Flusher. Writes are never buffered, so it only
+// commits the headers.
+func (w *streamingResponseWriter) Flush() {
+	w.start(http.StatusOK)
+}
@@ -0,0 +1,105 @@
+package lambdaproxy_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/internal/lambdaproxy"
+)
+
+func streamEvent(path string) events.LambdaFunctionURLRequest {
+	return events.LambdaFunctionURLRequest{
+		Version: "2.0",
+		RawPath: path,
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			DomainName: "abc.lambda-url.us-west-2.on.aws",
+			HTTP:       events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodGet, Path: path},
+		},
+	}
+}
+
+func readWithTimeout(t *testing.T, r io.Reader, n int) []byte {
+	t.Helper()
+
+	done := make(chan []byte, 1)
+	go func() {
+		buf := make([]byte, n)
+		_, _ = io.ReadFull(r, buf)
+		done <- buf
+	}()
+
+	select {
+	case buf := <-done:
+		return buf
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for streamed chunk")
+		return nil
+	}
+}
+
+func TestStreamingHandler_DeliversChunksIncrementally(t *testing.T) {
+	proceed := make(chan struct{})
+
+	r := chi.NewRouter()
+	r.Get("/stream", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+		w.WriteHeader(http.StatusAccepted)
+
+		_, _ = w.Write([]byte("first;"))
+		w.(http.Flusher).Flush()
+
+		<-proceed
+		_, _ = w.Write([]byte("second"))
+	})
+
+	resp, err := lambdaproxy.NewStreaming(r).Handle(context.Background(), streamEvent("/stream"))
+	require.NoError(t, err)
+
+	// Status and headers are available before the handler has finished.
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, "text/plain", resp.Headers["Content-Type"])
+	assert.Equal(t, []string{"session=abc"}, resp.Cookies)
+
+	// The response starts with the JSON prelude and eight NUL separator bytes.
+	var prelude bytes.Buffer
+	for !bytes.HasSuffix(prelude.Bytes(), make([]byte, 8)) {
+		prelude.Write(readWithTimeout(t, resp, 1))
+	}
+	var meta struct {
+		StatusCode int `json:"statusCode"`
+	}
+	require.NoError(t, json.Unmarshal(bytes.TrimRight(prelude.Bytes(), "\x00"), &meta))
+	assert.Equal(t, http.StatusAccepted, meta.StatusCode)
+
+	// The first chunk arrives while the handler is still blocked.
+	assert.Equal(t, "first;", string(readWithTimeout(t, resp, len("first;"))))
+
+	close(proceed)
+	rest, err := io.ReadAll(resp)
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(rest))
+}
+
+func TestStreamingHandler_ImplicitStatus(t *testing.T) {
+	r := chi.NewRouter()
+	r.Get("/empty", func(http.ResponseWriter, *http.Request) {})
+
+	resp, err := lambdaproxy.NewStreaming(r).Handle(context.Background(), streamEvent("/empty"))
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Empty(t, body)
+}
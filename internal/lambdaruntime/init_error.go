@@ -0,0 +1,83 @@
+// Package lambdaruntime talks to the parts of the Lambda Runtime API that
+// aws-lambda-go does not cover.
+package lambdaruntime
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	runtimeAPIEnv = "AWS_LAMBDA_RUNTIME_API"
+
+	defaultErrorType = "Runtime.InitError"
+)
+
+// ErrNoRuntimeAPI is returned when the process is not running under a
+// Lambda runtime, e.g. during local development.
+var ErrNoRuntimeAPI = errors.New(runtimeAPIEnv + " is not set")
+
+// errorTyper is implemented by errors that name their own error type.
+type errorTyper interface {
+	ErrorType() string
+}
+
+type errorPayload struct {
+	ErrorMessage string   `json:"errorMessage"`
+	ErrorType    string   `json:"errorType"`
+	StackTrace   []string `json:"stackTrace"`
+}
+
+// ReportInitError tells the Lambda runtime that initialization failed, so
+// the failure is reported with err's message and type instead of as an
+// unexplained exit. The process should exit afterwards.
+func ReportInitError(ctx context.Context, err error) error {
+	api := os.Getenv(runtimeAPIEnv)
+	if api == "" {
+		return ErrNoRuntimeAPI
+	}
+
+	errorType := defaultErrorType
+	var typer errorTyper
+	if errors.As(err, &typer) {
+		errorType = typer.ErrorType()
+	}
+
+	body, marshalErr := json.Marshal(errorPayload{
+		ErrorMessage: err.Error(),
+		ErrorType:    errorType,
+		StackTrace:   []string{},
+	})
+	if marshalErr != nil {
+		return fmt.Errorf("failed to encode init error: %w", marshalErr)
+	}
+
+	url := fmt.Sprintf("http://%s/2018-06-01/runtime/init/error", api)
+	req, reqErr := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if reqErr != nil {
+		return fmt.Errorf("failed to build init error request: %w", reqErr)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Lambda-Runtime-Function-Error-Type", errorType)
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, doErr := client.Do(req)
+	if doErr != nil {
+		return fmt.Errorf("failed to report init error: %w", doErr)
+	}
+
+	//nolint:errcheck
+	//goland:noinspection GoUnhandledErrorResult
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("runtime API rejected init error with status %d", resp.StatusCode)
+	}
+	return nil
+}
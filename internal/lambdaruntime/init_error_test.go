@@ -0,0 +1,68 @@
+package lambdaruntime_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/internal/lambdaruntime"
+)
+
+type phaseError struct{}
+
+func (phaseError) Error() string     { return "bad config" }
+func (phaseError) ErrorType() string { return "InitError.config" }
+
+func TestReportInitError(t *testing.T) {
+	var (
+		path      string
+		errorType string
+		payload   map[string]any
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		errorType = r.Header.Get("Lambda-Runtime-Function-Error-Type")
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("AWS_LAMBDA_RUNTIME_API", strings.TrimPrefix(server.URL, "http://"))
+
+	err := lambdaruntime.ReportInitError(context.Background(), phaseError{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "/2018-06-01/runtime/init/error", path)
+	assert.Equal(t, "InitError.config", errorType)
+	assert.Equal(t, "bad config", payload["errorMessage"])
+	assert.Equal(t, "InitError.config", payload["errorType"])
+}
+
+func TestReportInitError_DefaultTypeAndRejection(t *testing.T) {
+	var errorType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		errorType = r.Header.Get("Lambda-Runtime-Function-Error-Type")
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("AWS_LAMBDA_RUNTIME_API", strings.TrimPrefix(server.URL, "http://"))
+
+	err := lambdaruntime.ReportInitError(context.Background(), errors.New("boom"))
+
+	require.Error(t, err)
+	assert.Equal(t, "Runtime.InitError", errorType)
+}
+
+func TestReportInitError_NoRuntime(t *testing.T) {
+	t.Setenv("AWS_LAMBDA_RUNTIME_API", "")
+
+	err := lambdaruntime.ReportInitError(context.Background(), errors.New("boom"))
+
+	assert.ErrorIs(t, err, lambdaruntime.ErrNoRuntimeAPI)
+}
@@ -0,0 +1,127 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+const formatLambdaJSON = "lambda-json"
+
+// LambdaJSONHandler writes JSON records shaped like those of the Lambda
+// Powertools loggers: "timestamp" and "message" keys, and every record
+// logged with an invocation context is tagged with function_name,
+// cold_start, aws_request_id and xray_trace_id. Records must be logged
+// with a *Context method for the invocation fields to be found.
+type LambdaJSONHandler struct {
+	next      slog.Handler
+	coldStart *coldStart
+}
+
+// coldStart remembers the first invocation seen, shared by every handler
+// derived from a LambdaJSONHandler.
+type coldStart struct {
+	requestID string
+	mu        sync.Mutex
+}
+
+// is reports whether requestID belongs to the first invocation.
+func (c *coldStart) is(requestID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.requestID == "" {
+		c.requestID = requestID
+	}
+	return c.requestID == requestID
+}
+
+// NewLambdaJSONHandler creates a LambdaJSONHandler writing to w.
+func NewLambdaJSONHandler(w io.Writer, opts *slog.HandlerOptions) *LambdaJSONHandler {
+	jsonOpts := &slog.HandlerOptions{
+		ReplaceAttr: renameLambdaKeys,
+	}
+	if opts != nil {
+		jsonOpts.AddSource = opts.AddSource
+		jsonOpts.Level = opts.Level
+		if replace := opts.ReplaceAttr; replace != nil {
+			jsonOpts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+				return renameLambdaKeys(groups, replace(groups, a))
+			}
+		}
+	}
+
+	return &LambdaJSONHandler{
+		next:      slog.NewJSONHandler(w, jsonOpts),
+		coldStart: &coldStart{},
+	}
+}
+
+func renameLambdaKeys(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.TimeKey:
+		a.Key = "timestamp"
+	case slog.MessageKey:
+		a.Key = "message"
+	}
+	return a
+}
+
+func (h *LambdaJSONHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *LambdaJSONHandler) Handle(ctx context.Context, record slog.Record) error {
+	attrs := make([]slog.Attr, 0, 4)
+	if lambdacontext.FunctionName != "" {
+		attrs = append(attrs, slog.String("function_name", lambdacontext.FunctionName))
+	}
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		attrs = append(attrs,
+			slog.Bool("cold_start", h.coldStart.is(lc.AwsRequestID)),
+			slog.String("aws_request_id", lc.AwsRequestID),
+		)
+		if traceID := xrayTraceID(ctx); traceID != "" {
+			attrs = append(attrs, slog.String("xray_trace_id", traceID))
+		}
+	}
+
+	if len(attrs) > 0 {
+		record = record.Clone()
+		record.AddAttrs(attrs...)
+	}
+	return h.next.Handle(ctx, record)
+}
+
+func (h *LambdaJSONHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &LambdaJSONHandler{next: h.next.WithAttrs(attrs), coldStart: h.coldStart}
+}
+
+func (h *LambdaJSONHandler) WithGroup(name string) slog.Handler {
+	return &LambdaJSONHandler{next: h.next.WithGroup(name), coldStart: h.coldStart}
+}
+
+// xrayTraceID returns the root trace ID, e.g. "1-5759e988-bd862e3fe1be46a994272793",
+// from the trace header aws-lambda-go stores in the invocation context under
+// a plain string key.
+func xrayTraceID(ctx context.Context) string {
+	header, _ := ctx.Value("x-amzn-trace-id").(string)
+	if header == "" {
+		header = os.Getenv("_X_AMZN_TRACE_ID")
+	}
+
+	for part := range strings.SplitSeq(header, ";") {
+		if root, ok := strings.CutPrefix(part, "Root="); ok {
+			return root
+		}
+	}
+	return ""
+}
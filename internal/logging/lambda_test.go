@@ -0,0 +1,61 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var records []map[string]any
+	for line := range strings.SplitSeq(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestLambdaJSONHandler(t *testing.T) {
+	lambdacontext.FunctionName = "hello-go"
+	t.Cleanup(func() { lambdacontext.FunctionName = "" })
+
+	var buf bytes.Buffer
+	logger := slog.New(NewLambdaJSONHandler(&buf, nil)).With("component", "test")
+
+	invocation := func(requestID string) context.Context {
+		ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: requestID})
+		//nolint:staticcheck // aws-lambda-go stores the trace header under a plain string key.
+		return context.WithValue(ctx, "x-amzn-trace-id", "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1")
+	}
+
+	logger.Info("init")
+	logger.InfoContext(invocation("req-1"), "first")
+	logger.InfoContext(invocation("req-1"), "first again")
+	logger.InfoContext(invocation("req-2"), "second")
+
+	records := decodeLines(t, &buf)
+	require.Len(t, records, 4)
+
+	assert.Equal(t, "init", records[0]["message"])
+	assert.Contains(t, records[0], "timestamp")
+	assert.Equal(t, "hello-go", records[0]["function_name"])
+	assert.NotContains(t, records[0], "aws_request_id")
+	assert.NotContains(t, records[0], "cold_start")
+
+	assert.Equal(t, "req-1", records[1]["aws_request_id"])
+	assert.Equal(t, "1-5759e988-bd862e3fe1be46a994272793", records[1]["xray_trace_id"])
+	assert.Equal(t, "test", records[1]["component"])
+	assert.Equal(t, true, records[1]["cold_start"])
+	assert.Equal(t, true, records[2]["cold_start"])
+	assert.Equal(t, false, records[3]["cold_start"])
+}
@@ -1,45 +1,105 @@
 package logging
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
-	"os"
 	"strings"
 
 	"github.com/savisec/hello-go/internal/config"
 )
 
-func Setup(cfg config.LoggingConfig) *slog.Logger {
-	level := parseLevel(cfg.Level)
+// Setup builds the logger described by cfg and makes it the default.
+// Redaction in hash mode fingerprints values with fingerprinter.
+func Setup(cfg config.LoggingConfig, fingerprinter *Fingerprinter) (*slog.Logger, error) {
+	handler, err := newSinksHandler(cfg)
+	if err != nil {
+		_ = Close()
+		return nil, err
+	}
+	handler = NewTenantHandler(handler)
 
-	var handler slog.Handler
-	opts := &slog.HandlerOptions{
-		Level: level,
+	if cfg.Redaction.Enabled {
+		redactor, err := NewRedactor(cfg.Redaction, fingerprinter)
+		if err != nil {
+			_ = Close()
+			return nil, fmt.Errorf("failed to configure log redaction: %w", err)
+		}
+		handler = NewRedactingHandler(handler, redactor)
 	}
 
-	switch strings.ToLower(cfg.Format) {
-	case "json":
-		handler = slog.NewJSONHandler(os.Stdout, opts)
-	default:
-		handler = slog.NewTextHandler(os.Stdout, opts)
+	// Sampling runs before redaction so dropped records are never scrubbed.
+	if cfg.Sampling.Enabled {
+		sampling, err := NewSamplingHandler(handler, cfg.Sampling)
+		if err != nil {
+			_ = Close()
+			return nil, fmt.Errorf("failed to configure log sampling: %w", err)
+		}
+		handler = sampling
+		setSampling(sampling)
 	}
 
 	logger := slog.New(handler)
 	slog.SetDefault(logger)
 
-	return logger
+	return logger, nil
+}
+
+// Flush writes the summaries of records sampled out so far, which are
+// otherwise only written once a record arrives after the interval ends. It
+// is called on shutdown and, under Lambda, after every invocation, before
+// the execution environment may be frozen.
+func Flush(ctx context.Context) {
+	sinksMu.Lock()
+	h := activeSampling
+	sinksMu.Unlock()
+
+	if h != nil {
+		h.Flush(ctx)
+	}
+}
+
+// newSinksHandler builds one handler per configured sink and fans out to
+// them. Without any sinks configured, logs go to stdout.
+func newSinksHandler(cfg config.LoggingConfig) (slog.Handler, error) {
+	sinks := cfg.Sinks
+	if len(sinks) == 0 {
+		sinks = []config.SinkConfig{{Type: sinkStdout}}
+	}
+
+	handlers := make([]slog.Handler, 0, len(sinks))
+	for i, sink := range sinks {
+		handler, err := newSinkHandler(sink, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure log sink %d: %w", i, err)
+		}
+		handlers = append(handlers, handler)
+	}
+
+	if len(handlers) == 1 {
+		return handlers[0], nil
+	}
+	return NewFanoutHandler(handlers...), nil
 }
 
 func parseLevel(levelStr string) slog.Level {
+	if level, ok := lookupLevel(levelStr); ok {
+		return level
+	}
+	return slog.LevelInfo
+}
+
+func lookupLevel(levelStr string) (slog.Level, bool) {
 	switch strings.ToLower(levelStr) {
 	case "debug":
-		return slog.LevelDebug
+		return slog.LevelDebug, true
 	case "info":
-		return slog.LevelInfo
+		return slog.LevelInfo, true
 	case "warn", "warning":
-		return slog.LevelWarn
+		return slog.LevelWarn, true
 	case "error":
-		return slog.LevelError
+		return slog.LevelError, true
 	default:
-		return slog.LevelInfo
+		return slog.LevelInfo, false
 	}
 }
@@ -0,0 +1,249 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// prettyMessageWidth pads messages so attributes line up in a column.
+	prettyMessageWidth = 36
+
+	// prettyMaxValueWidth caps how wide an attribute's column may grow, so
+	// one long value does not push every later line out of the terminal.
+	prettyMaxValueWidth = 32
+
+	// prettyFileTimeFormat stamps records written somewhere other than a
+	// terminal, where they are read later and a relative time means nothing.
+	prettyFileTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiBlue   = "\x1b[34m"
+	ansiCyan   = "\x1b[36m"
+)
+
+// PrettyHandler is a slog.Handler for local development. It renders one
+// line per record, with messages padded and each attribute key kept in the
+// column it first appeared in, and prints errors and multiline values such
+// as stack traces as indented blocks underneath.
+//
+// On a terminal, levels and keys are colored (unless NO_COLOR is set) and
+// each record shows the time since startup. Elsewhere, such as when output
+// is redirected to a file, records are plain and carry a full timestamp.
+type PrettyHandler struct {
+	w        io.Writer
+	mu       *sync.Mutex
+	columns  *prettyColumns
+	start    time.Time
+	level    slog.Leveler
+	prefix   string
+	attrs    []slog.Attr
+	terminal bool
+	color    bool
+}
+
+// prettyColumns remembers the widest value seen for each attribute key, so
+// that keys recurring across records, such as those of access logs, line
+// up. It is shared by a handler and those derived from it.
+type prettyColumns struct {
+	mu     sync.Mutex
+	widths map[string]int
+}
+
+// width records a value of n characters for key and returns the width the
+// value should be padded to.
+func (c *prettyColumns) width(key string, n int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if n > c.widths[key] && n <= prettyMaxValueWidth {
+		c.widths[key] = n
+	}
+	return c.widths[key]
+}
+
+// NewPrettyHandler creates a PrettyHandler writing to w.
+func NewPrettyHandler(w io.Writer, opts *slog.HandlerOptions) *PrettyHandler {
+	var level slog.Leveler = slog.LevelInfo
+	if opts != nil && opts.Level != nil {
+		level = opts.Level
+	}
+
+	terminal := isTerminal(w)
+	return &PrettyHandler{
+		w:        w,
+		mu:       &sync.Mutex{},
+		columns:  &prettyColumns{widths: make(map[string]int)},
+		start:    time.Now(),
+		level:    level,
+		terminal: terminal,
+		color:    terminal && os.Getenv("NO_COLOR") == "",
+	}
+}
+
+// isTerminal reports whether w is a character device such as a TTY.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func (h *PrettyHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *PrettyHandler) Handle(_ context.Context, record slog.Record) error {
+	var line, blocks bytes.Buffer
+
+	if h.terminal {
+		elapsed := record.Time.Sub(h.start).Round(time.Millisecond)
+		h.paint(&line, ansiDim, fmt.Sprintf("%s +%-8s", record.Time.Format("15:04:05.000"), elapsed))
+	} else {
+		line.WriteString(record.Time.Format(prettyFileTimeFormat))
+	}
+	line.WriteByte(' ')
+	h.paint(&line, levelColor(record.Level), fmt.Sprintf("%-5s", record.Level.String()))
+	line.WriteByte(' ')
+	h.paint(&line, ansiBold, fmt.Sprintf("%-*s", prettyMessageWidth, record.Message))
+
+	for _, a := range h.attrs {
+		h.writeAttr(&line, &blocks, "", a)
+	}
+	record.Attrs(func(a slog.Attr) bool {
+		h.writeAttr(&line, &blocks, h.prefix, a)
+		return true
+	})
+
+	out := bytes.TrimRight(line.Bytes(), " ")
+	out = append(out, '\n')
+	out = append(out, blocks.Bytes()...)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(out)
+	return err
+}
+
+func (h *PrettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append([]slog.Attr{}, h.attrs...)
+	for _, a := range attrs {
+		clone.attrs = append(clone.attrs, slog.Attr{Key: h.prefix + a.Key, Value: a.Value})
+	}
+	return &clone
+}
+
+func (h *PrettyHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.prefix = h.prefix + name + "."
+	return &clone
+}
+
+// writeAttr renders a as key=value on the main line, padded to the key's
+// column, or as an indented block when the value is an error or spans
+// multiple lines.
+func (h *PrettyHandler) writeAttr(line, blocks *bytes.Buffer, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	key := prefix + a.Key
+	if v.Kind() == slog.KindGroup {
+		groupPrefix := key + "."
+		if a.Key == "" {
+			groupPrefix = prefix
+		}
+		for _, ga := range v.Group() {
+			h.writeAttr(line, blocks, groupPrefix, ga)
+		}
+		return
+	}
+
+	text := v.String()
+	_, isErr := v.Any().(error)
+	if isErr || strings.Contains(text, "\n") {
+		color := ansiDim
+		if isErr {
+			color = ansiRed
+		}
+		blocks.WriteString("    ")
+		h.paint(blocks, ansiCyan, key+":")
+		blocks.WriteByte('\n')
+		for _, l := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+			blocks.WriteString("      ")
+			h.paint(blocks, color, l)
+			blocks.WriteByte('\n')
+		}
+		return
+	}
+
+	value := quoteIfNeeded(text)
+	n := utf8.RuneCountInString(value)
+	line.WriteByte(' ')
+	h.paint(line, ansiCyan, key)
+	line.WriteByte('=')
+	line.WriteString(value)
+	if pad := h.columns.width(key, n) - n; pad > 0 {
+		line.WriteString(strings.Repeat(" ", pad))
+	}
+}
+
+func (h *PrettyHandler) paint(buf *bytes.Buffer, color, s string) {
+	if !h.color {
+		buf.WriteString(s)
+		return
+	}
+	buf.WriteString(color)
+	buf.WriteString(s)
+	buf.WriteString(ansiReset)
+}
+
+func levelColor(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return ansiRed
+	case level >= slog.LevelWarn:
+		return ansiYellow
+	case level >= slog.LevelInfo:
+		return ansiGreen
+	default:
+		return ansiBlue
+	}
+}
+
+func quoteIfNeeded(s string) string {
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if unicode.IsSpace(r) || r == '"' || r == '=' || !unicode.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
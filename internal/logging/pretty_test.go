@@ -0,0 +1,95 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrettyHandler_Layout(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewPrettyHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	logger.With("component", "echo").WithGroup("req").Info("short", "id", 7, "path", "/v1/echo")
+	logger.Warn("a somewhat longer message", "note", "has spaces")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	assert.NotContains(t, buf.String(), "\x1b[", "colors are disabled for non-terminal writers")
+	assert.Regexp(t, `^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.\d{3}\S+ INFO  short\s+component=echo req\.id=7 req\.path=/v1/echo$`, lines[0])
+	assert.Contains(t, lines[1], `WARN  a somewhat longer message`)
+	assert.Contains(t, lines[1], `note="has spaces"`)
+	assert.Equal(t, strings.Index(lines[0], "component="), strings.Index(lines[1], "note="), "attributes are aligned")
+}
+
+func TestPrettyHandler_AlignsAttributeColumns(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewPrettyHandler(&buf, nil))
+
+	logger.Info("HTTP request", "status", 200, "path", "/v1/echo", "duration", "3ms")
+	logger.With("tenant", "acme").Info("HTTP request", "status", 4, "path", "/v1/echo/batch", "duration", "12ms")
+	logger.Info("HTTP request", "status", 500, "path", "/v1/echo", "duration", "1ms")
+	logger.Info("HTTP request", "status", 201, "path", "/v1/echo/batch", "duration", "250ms")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+
+	// Columns widen to the widest value seen so far, so later lines align.
+	assert.Equal(t, strings.Index(lines[2], "path="), strings.Index(lines[3], "path="))
+	assert.Equal(t, strings.Index(lines[2], "duration="), strings.Index(lines[3], "duration="))
+	assert.Contains(t, lines[1], "tenant=acme status=4   path=/v1/echo/batch duration=12ms")
+	assert.Contains(t, lines[2], "status=500 path=/v1/echo       duration=1ms")
+}
+
+func TestPrettyHandler_Terminal(t *testing.T) {
+	var buf bytes.Buffer
+	handler := NewPrettyHandler(&buf, nil)
+	handler.terminal = true
+	handler.color = true
+
+	slog.New(handler).Warn("careful", "key", "value")
+
+	assert.Regexp(t, `^\x1b\[2m\d{2}:\d{2}:\d{2}\.\d{3} \+\S+\s*\x1b\[0m \x1b\[33mWARN `, buf.String(),
+		"terminals get colors and the time since startup")
+	assert.Contains(t, buf.String(), "\x1b[36mkey\x1b[0m=value")
+}
+
+func TestPrettyHandler_MultilineValues(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewPrettyHandler(&buf, nil))
+
+	logger.Error("request failed",
+		"error", errors.Join(errors.New("first"), errors.New("second")),
+		"stack", "main.go:10\nserver.go:42\n",
+		"status", 500,
+	)
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 7)
+
+	assert.Contains(t, lines[0], "status=500")
+	assert.NotContains(t, lines[0], "first")
+	assert.Equal(t, []string{
+		"    error:",
+		"      first",
+		"      second",
+		"    stack:",
+		"      main.go:10",
+		"      server.go:42",
+	}, lines[1:])
+}
+
+func TestPrettyHandler_Level(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewPrettyHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	logger.Info("hidden")
+
+	assert.Empty(t, buf.String())
+}
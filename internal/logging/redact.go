@@ -0,0 +1,325 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/savisec/hello-go/internal/config"
+)
+
+const (
+	redactionModeMask = "mask"
+	redactionModeHash = "hash"
+
+	redactedValue = "[REDACTED]"
+)
+
+// builtinPatterns are the detectors that can be enabled by name in
+// logging.redaction.patterns.
+var builtinPatterns = map[string]*regexp.Regexp{
+	"email": regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	"token": regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/=-]+|\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`),
+	"card":  regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+}
+
+type redactionPattern struct {
+	re   *regexp.Regexp
+	name string
+}
+
+// Redactor scrubs sensitive values from log attributes.
+type Redactor struct {
+	fields      map[string]struct{}
+	fingerprint *Fingerprinter
+	patterns    []redactionPattern
+	hash        bool
+}
+
+// NewRedactor builds a Redactor from the redaction config. Hash mode
+// replaces values with their fingerprint from fingerprinter.
+func NewRedactor(cfg config.RedactionConfig, fingerprinter *Fingerprinter) (*Redactor, error) {
+	r := &Redactor{
+		fields:      make(map[string]struct{}, len(cfg.Fields)),
+		fingerprint: fingerprinter,
+	}
+
+	switch strings.ToLower(cfg.Mode) {
+	case "", redactionModeMask:
+	case redactionModeHash:
+		r.hash = true
+	default:
+		return nil, fmt.Errorf("unknown redaction mode %q", cfg.Mode)
+	}
+
+	for _, field := range cfg.Fields {
+		r.fields[strings.ToLower(field)] = struct{}{}
+	}
+
+	for _, name := range cfg.Patterns {
+		re, ok := builtinPatterns[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown redaction pattern %q", name)
+		}
+		r.patterns = append(r.patterns, redactionPattern{name: strings.ToLower(name), re: re})
+	}
+
+	// Sort custom pattern names so redaction order is deterministic.
+	names := make([]string, 0, len(cfg.CustomPatterns))
+	for name := range cfg.CustomPatterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		re, err := regexp.Compile(cfg.CustomPatterns[name])
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", name, err)
+		}
+		r.patterns = append(r.patterns, redactionPattern{name: name, re: re})
+	}
+
+	return r, nil
+}
+
+// Attr returns a copy of the attribute with sensitive values replaced.
+// Maps, structs and slices are scrubbed through their JSON encoding, so
+// field names are matched against their JSON keys and the value is logged
+// in that decoded form.
+func (r *Redactor) Attr(a slog.Attr) slog.Attr {
+	if _, ok := r.fields[strings.ToLower(a.Key)]; ok {
+		return slog.String(a.Key, r.replace("", a.Value.Resolve().String()))
+	}
+
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindGroup:
+		attrs := v.Group()
+		scrubbed := make([]any, len(attrs))
+		for i, ga := range attrs {
+			scrubbed[i] = r.Attr(ga)
+		}
+		return slog.Group(a.Key, scrubbed...)
+	case slog.KindString:
+		return slog.String(a.Key, r.String(v.String()))
+	case slog.KindAny:
+		switch val := v.Any().(type) {
+		case error:
+			return slog.String(a.Key, r.String(val.Error()))
+		case fmt.Stringer:
+			return slog.String(a.Key, r.String(val.String()))
+		case []byte:
+			return slog.String(a.Key, r.String(string(val)))
+		}
+		if scrubbed, ok := r.composite(v.Any()); ok {
+			return slog.Any(a.Key, scrubbed)
+		}
+	}
+
+	return slog.Attr{Key: a.Key, Value: v}
+}
+
+// composite scrubs a map, struct, slice or array, or a pointer to one, by
+// way of its JSON encoding. Values that cannot be encoded are formatted
+// with fmt and scrubbed as a string instead.
+func (r *Redactor) composite(v any) (any, bool) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array:
+	default:
+		return nil, false
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return r.String(fmt.Sprint(v)), true
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var decoded any
+	if err := decoder.Decode(&decoded); err != nil {
+		return r.String(fmt.Sprint(v)), true
+	}
+	return r.value(decoded), true
+}
+
+// String replaces every pattern match in s.
+func (r *Redactor) String(s string) string {
+	for _, p := range r.patterns {
+		if p.name == "card" {
+			s = p.re.ReplaceAllStringFunc(s, func(match string) string {
+				if !luhnValid(match) {
+					return match
+				}
+				return r.replace(p.name, match)
+			})
+			continue
+		}
+		s = p.re.ReplaceAllStringFunc(s, func(match string) string {
+			return r.replace(p.name, match)
+		})
+	}
+	return s
+}
+
+// JSON returns a copy of a JSON document with sensitive values replaced.
+// Object keys are matched against the redacted field names, and string
+// values that themselves hold JSON, such as an event body, are scrubbed in
+// place so the document keeps its shape.
+func (r *Redactor) JSON(data []byte) ([]byte, error) {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON: %w", err)
+	}
+	return json.Marshal(r.value(v))
+}
+
+func (r *Redactor) value(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for key, field := range val {
+			if _, ok := r.fields[strings.ToLower(key)]; ok && field != nil {
+				val[key] = r.replace("", fmt.Sprint(field))
+				continue
+			}
+			val[key] = r.value(field)
+		}
+		return val
+	case []any:
+		for i, item := range val {
+			val[i] = r.value(item)
+		}
+		return val
+	case string:
+		var nested any
+		if trimmed := strings.TrimSpace(val); strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			if json.Unmarshal([]byte(trimmed), &nested) == nil {
+				if out, err := json.Marshal(r.value(nested)); err == nil {
+					return string(out)
+				}
+			}
+		}
+		return r.String(val)
+	default:
+		return v
+	}
+}
+
+// replace masks or hashes a single sensitive value.
+func (r *Redactor) replace(kind, value string) string {
+	if r.hash {
+		return r.fingerprint.Fingerprint(value)
+	}
+	if kind == "" {
+		return redactedValue
+	}
+	return "[REDACTED:" + kind + "]"
+}
+
+// Fingerprinter derives short, stable pseudonyms for values so they can be
+// correlated across log lines without being logged verbatim. The pseudonym
+// is a keyed HMAC, so without the key it cannot be reversed by hashing
+// guesses.
+type Fingerprinter struct {
+	key []byte
+}
+
+// NewFingerprinter returns a Fingerprinter keyed by key, normally
+// logging.redaction.hash_salt. When key is empty a random key is used, so
+// fingerprints only correlate within the running process, and only between
+// users of the same Fingerprinter; build one per process and share it.
+func NewFingerprinter(key string) *Fingerprinter {
+	if key == "" {
+		random := make([]byte, 32)
+		_, _ = rand.Read(random)
+		return &Fingerprinter{key: random}
+	}
+	return &Fingerprinter{key: []byte(key)}
+}
+
+// Fingerprint returns the pseudonym of value.
+func (f *Fingerprinter) Fingerprint(value string) string {
+	mac := hmac.New(sha256.New, f.key)
+	mac.Write([]byte(value))
+	return "sha256:" + hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// luhnValid reports whether the digits in s pass the Luhn checksum, which
+// filters out most long numbers that are not card numbers.
+func luhnValid(s string) bool {
+	sum := 0
+	double := false
+	digits := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+		digits++
+	}
+	return digits >= 13 && sum%10 == 0
+}
+
+// RedactingHandler is a slog.Handler that scrubs attributes before passing
+// records on to the wrapped handler.
+type RedactingHandler struct {
+	next     slog.Handler
+	redactor *Redactor
+}
+
+// NewRedactingHandler wraps next so that every record is scrubbed by redactor.
+func NewRedactingHandler(next slog.Handler, redactor *Redactor) *RedactingHandler {
+	return &RedactingHandler{
+		next:     next,
+		redactor: redactor,
+	}
+}
+
+func (h *RedactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *RedactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	scrubbed := slog.NewRecord(record.Time, record.Level, h.redactor.String(record.Message), record.PC)
+	record.Attrs(func(a slog.Attr) bool {
+		scrubbed.AddAttrs(h.redactor.Attr(a))
+		return true
+	})
+	return h.next.Handle(ctx, scrubbed)
+}
+
+func (h *RedactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	scrubbed := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		scrubbed[i] = h.redactor.Attr(a)
+	}
+	return NewRedactingHandler(h.next.WithAttrs(scrubbed), h.redactor)
+}
+
+func (h *RedactingHandler) WithGroup(name string) slog.Handler {
+	return NewRedactingHandler(h.next.WithGroup(name), h.redactor)
+}
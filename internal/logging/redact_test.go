@@ -0,0 +1,176 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/internal/config"
+)
+
+func newTestRedactingLogger(t *testing.T, cfg config.RedactionConfig) (*slog.Logger, *bytes.Buffer) {
+	t.Helper()
+
+	redactor, err := NewRedactor(cfg, NewFingerprinter(cfg.HashSalt))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	handler := NewRedactingHandler(slog.NewJSONHandler(&buf, nil), redactor)
+
+	return slog.New(handler), &buf
+}
+
+func decodeRecord(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+
+	return record
+}
+
+func TestRedactingHandler_Mask(t *testing.T) {
+	logger, buf := newTestRedactingLogger(t, config.RedactionConfig{
+		Fields:   []string{"password"},
+		Patterns: []string{"email", "token", "card"},
+		Mode:     "mask",
+	})
+
+	logger.With("Password", "hunter2").Info("login attempt",
+		"user", "alice@example.com",
+		"header", "Bearer abc.def-123",
+		"card", "4111 1111 1111 1111",
+		"order", "1234567890123456",
+		"error", errors.New("lookup failed for bob@example.com"),
+		slog.Group("nested", "password", "secret"),
+	)
+
+	record := decodeRecord(t, buf)
+
+	assert.Equal(t, "[REDACTED]", record["Password"])
+	assert.Equal(t, "[REDACTED:email]", record["user"])
+	assert.Equal(t, "[REDACTED:token]", record["header"])
+	assert.Equal(t, "[REDACTED:card]", record["card"])
+	assert.Equal(t, "1234567890123456", record["order"], "numbers failing the Luhn check are kept")
+	assert.Equal(t, "lookup failed for [REDACTED:email]", record["error"])
+	assert.Equal(t, map[string]any{"password": "[REDACTED]"}, record["nested"])
+}
+
+func TestRedactingHandler_Hash(t *testing.T) {
+	logger, buf := newTestRedactingLogger(t, config.RedactionConfig{
+		Patterns: []string{"email"},
+		Mode:     "hash",
+		HashSalt: "pepper",
+	})
+
+	logger.Info("hello", "user", "alice@example.com")
+	first := decodeRecord(t, buf)["user"]
+	buf.Reset()
+
+	logger.Info("hello", "user", "alice@example.com")
+	second := decodeRecord(t, buf)["user"]
+
+	assert.Regexp(t, `^sha256:[0-9a-f]{16}$`, first)
+	assert.Equal(t, first, second, "hashes must be stable for correlation")
+}
+
+func TestRedactingHandler_Composite(t *testing.T) {
+	type credentials struct {
+		User     string `json:"user"`
+		Password string `json:"password"`
+	}
+
+	logger, buf := newTestRedactingLogger(t, config.RedactionConfig{
+		Fields:   []string{"password"},
+		Patterns: []string{"email"},
+	})
+
+	logger.Info("login attempt",
+		"map", map[string]any{"password": "hunter2", "note": "from a@example.com"},
+		"struct", &credentials{User: "a@example.com", Password: "hunter2"},
+		"slice", []string{"a@example.com", "ok"},
+		"bytes", []byte("to a@example.com"),
+		"count", 3,
+	)
+
+	record := decodeRecord(t, buf)
+
+	assert.Equal(t, map[string]any{"password": "[REDACTED]", "note": "from [REDACTED:email]"}, record["map"])
+	assert.Equal(t, map[string]any{"user": "[REDACTED:email]", "password": "[REDACTED]"}, record["struct"])
+	assert.Equal(t, []any{"[REDACTED:email]", "ok"}, record["slice"])
+	assert.Equal(t, "to [REDACTED:email]", record["bytes"])
+	assert.InDelta(t, 3, record["count"], 0)
+}
+
+func TestFingerprinter(t *testing.T) {
+	keyed := NewFingerprinter("pepper")
+
+	assert.Regexp(t, `^sha256:[0-9a-f]{16}$`, keyed.Fingerprint("alice"))
+	assert.Equal(t, keyed.Fingerprint("alice"), NewFingerprinter("pepper").Fingerprint("alice"))
+	assert.NotEqual(t, keyed.Fingerprint("alice"), NewFingerprinter("salt").Fingerprint("alice"))
+	assert.NotEqual(t, NewFingerprinter("").Fingerprint("alice"), NewFingerprinter("").Fingerprint("alice"),
+		"an unset key must not fall back to a fixed one")
+}
+
+func TestRedactor_SharesFingerprinter(t *testing.T) {
+	fingerprinter := NewFingerprinter("")
+	redactor, err := NewRedactor(config.RedactionConfig{Fields: []string{"author"}, Mode: "hash"}, fingerprinter)
+	require.NoError(t, err)
+
+	assert.Equal(t, fingerprinter.Fingerprint("alice"), redactor.Attr(slog.String("author", "alice")).Value.String(),
+		"redacted values correlate with fingerprints logged elsewhere")
+}
+
+func TestRedactingHandler_CustomPattern(t *testing.T) {
+	logger, buf := newTestRedactingLogger(t, config.RedactionConfig{
+		CustomPatterns: map[string]string{"ssn": `\d{3}-\d{2}-\d{4}`},
+	})
+
+	logger.Info("submitted 123-45-6789")
+
+	assert.Equal(t, "submitted [REDACTED:ssn]", decodeRecord(t, buf)["msg"])
+}
+
+func TestRedactor_JSON(t *testing.T) {
+	redactor, err := NewRedactor(config.RedactionConfig{
+		Fields:   []string{"authorization"},
+		Patterns: []string{"email"},
+	}, NewFingerprinter(""))
+	require.NoError(t, err)
+
+	out, err := redactor.JSON([]byte(`{
+		"headers": {"Authorization": "Bearer abc", "host": "localhost"},
+		"body": "{\"message\":\"mail me at a@example.com\"}",
+		"cookies": ["id=a@example.com"]
+	}`))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"headers": {"Authorization": "[REDACTED]", "host": "localhost"},
+		"body": "{\"message\":\"mail me at [REDACTED:email]\"}",
+		"cookies": ["id=[REDACTED:email]"]
+	}`, string(out))
+}
+
+func TestNewRedactor_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.RedactionConfig
+	}{
+		{name: "unknown mode", cfg: config.RedactionConfig{Mode: "shred"}},
+		{name: "unknown pattern", cfg: config.RedactionConfig{Patterns: []string{"phone"}}},
+		{name: "invalid regex", cfg: config.RedactionConfig{CustomPatterns: map[string]string{"bad": "("}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRedactor(tt.cfg, NewFingerprinter(""))
+			assert.Error(t, err)
+		})
+	}
+}
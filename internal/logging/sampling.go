@@ -0,0 +1,186 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/savisec/hello-go/internal/config"
+)
+
+const defaultSamplingInterval = time.Second
+
+// samplingKey identifies "identical" records. Attributes are deliberately
+// ignored so that a storm of the same error with different details
+// collapses. Records whose every occurrence matters, such as access logs,
+// are logged with a context from WithoutSampling instead.
+type samplingKey struct {
+	message string
+	level   slog.Level
+}
+
+type samplingCounter struct {
+	seen       int
+	suppressed int
+}
+
+// sampler holds the state shared by a SamplingHandler and every handler
+// derived from it through WithAttrs or WithGroup.
+type sampler struct {
+	windowStart time.Time
+	now         func() time.Time
+	next        slog.Handler
+	rules       map[slog.Level]config.SamplingRule
+	counters    map[samplingKey]*samplingCounter
+	fallback    config.SamplingRule
+	interval    time.Duration
+	mu          sync.Mutex
+}
+
+// SamplingHandler is a slog.Handler that keeps the first N identical records
+// per interval, then every Mth, and reports how many it dropped.
+type SamplingHandler struct {
+	next    slog.Handler
+	sampler *sampler
+}
+
+type noSamplingKey struct{}
+
+// WithoutSampling returns a copy of ctx under which records are never
+// sampled out, whatever their level.
+func WithoutSampling(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noSamplingKey{}, true)
+}
+
+// NewSamplingHandler wraps next with the sampling rules from cfg.
+func NewSamplingHandler(next slog.Handler, cfg config.SamplingConfig) (*SamplingHandler, error) {
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = defaultSamplingInterval
+	}
+
+	rules := make(map[slog.Level]config.SamplingRule, len(cfg.Levels))
+	for name, rule := range cfg.Levels {
+		level, ok := lookupLevel(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown sampling level %q", name)
+		}
+		rules[level] = rule
+	}
+
+	return &SamplingHandler{
+		next: next,
+		sampler: &sampler{
+			next:     next,
+			now:      time.Now,
+			interval: interval,
+			rules:    rules,
+			fallback: config.SamplingRule{First: cfg.First, Thereafter: cfg.Thereafter},
+			counters: make(map[samplingKey]*samplingCounter),
+		},
+	}, nil
+}
+
+func (h *SamplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *SamplingHandler) Handle(ctx context.Context, record slog.Record) error {
+	if !h.sampler.allow(ctx, record) {
+		return nil
+	}
+	return h.next.Handle(ctx, record)
+}
+
+func (h *SamplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &SamplingHandler{next: h.next.WithAttrs(attrs), sampler: h.sampler}
+}
+
+func (h *SamplingHandler) WithGroup(name string) slog.Handler {
+	return &SamplingHandler{next: h.next.WithGroup(name), sampler: h.sampler}
+}
+
+// Flush emits summary records for the current interval and resets it. The
+// package-level Flush calls it for the handler Setup installed.
+func (h *SamplingHandler) Flush(ctx context.Context) {
+	h.sampler.mu.Lock()
+	summaries := h.sampler.rollover(h.sampler.now())
+	h.sampler.mu.Unlock()
+
+	h.sampler.emit(ctx, summaries)
+}
+
+// allow decides whether record is written, rolling the interval over first
+// when it has expired.
+func (s *sampler) allow(ctx context.Context, record slog.Record) bool {
+	rule := s.rule(record.Level)
+	if rule.Exempt || ctx.Value(noSamplingKey{}) != nil {
+		return true
+	}
+
+	s.mu.Lock()
+	var summaries []slog.Record
+	if now := s.now(); now.Sub(s.windowStart) >= s.interval {
+		summaries = s.rollover(now)
+	}
+
+	key := samplingKey{level: record.Level, message: record.Message}
+	counter, ok := s.counters[key]
+	if !ok {
+		counter = &samplingCounter{}
+		s.counters[key] = counter
+	}
+	counter.seen++
+
+	keep := counter.seen <= rule.First ||
+		(rule.Thereafter > 0 && (counter.seen-rule.First)%rule.Thereafter == 0)
+	if !keep {
+		counter.suppressed++
+	}
+	s.mu.Unlock()
+
+	s.emit(ctx, summaries)
+
+	return keep
+}
+
+func (s *sampler) rule(level slog.Level) config.SamplingRule {
+	if rule, ok := s.rules[level]; ok {
+		return rule
+	}
+	return s.fallback
+}
+
+// rollover starts a new interval at now and returns one summary record per
+// key that had records suppressed. Callers must hold s.mu.
+func (s *sampler) rollover(now time.Time) []slog.Record {
+	var summaries []slog.Record
+	for key, counter := range s.counters {
+		if counter.suppressed == 0 {
+			continue
+		}
+		summary := slog.NewRecord(now, slog.LevelWarn, "Log records suppressed by sampling", 0)
+		summary.AddAttrs(
+			slog.String("sampled_message", key.message),
+			slog.String("sampled_level", key.level.String()),
+			slog.Int("suppressed", counter.suppressed),
+			slog.Duration("interval", s.interval),
+		)
+		summaries = append(summaries, summary)
+	}
+
+	s.counters = make(map[samplingKey]*samplingCounter)
+	s.windowStart = now
+
+	return summaries
+}
+
+func (s *sampler) emit(ctx context.Context, summaries []slog.Record) {
+	for _, summary := range summaries {
+		if s.next.Enabled(ctx, summary.Level) {
+			_ = s.next.Handle(ctx, summary)
+		}
+	}
+}
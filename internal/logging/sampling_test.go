@@ -0,0 +1,184 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/internal/config"
+)
+
+func newTestSamplingLogger(t *testing.T, cfg config.SamplingConfig) (*slog.Logger, *SamplingHandler, *time.Time, *bytes.Buffer) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	handler, err := NewSamplingHandler(slog.NewJSONHandler(&buf, nil), cfg)
+	require.NoError(t, err)
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	handler.sampler.now = func() time.Time { return now }
+
+	return slog.New(handler), handler, &now, &buf
+}
+
+func decodeRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func TestSamplingHandler_FirstThenEveryMth(t *testing.T) {
+	logger, _, _, buf := newTestSamplingLogger(t, config.SamplingConfig{
+		Interval:   time.Second,
+		First:      2,
+		Thereafter: 3,
+	})
+
+	for i := 0; i < 10; i++ {
+		logger.Info("bad request", "attempt", i)
+	}
+
+	var attempts []float64
+	for _, record := range decodeRecords(t, buf) {
+		attempts = append(attempts, record["attempt"].(float64))
+	}
+
+	// Records 1, 2 then every 3rd after that: 5 and 8.
+	assert.Equal(t, []float64{0, 1, 4, 7}, attempts)
+}
+
+func TestSamplingHandler_SummaryOnRollover(t *testing.T) {
+	logger, _, now, buf := newTestSamplingLogger(t, config.SamplingConfig{
+		Interval: time.Second,
+		First:    1,
+	})
+
+	for i := 0; i < 5; i++ {
+		logger.Info("bad request")
+	}
+	buf.Reset()
+
+	*now = now.Add(time.Second)
+	logger.Info("bad request")
+
+	records := decodeRecords(t, buf)
+	require.Len(t, records, 2)
+
+	assert.Equal(t, "Log records suppressed by sampling", records[0]["msg"])
+	assert.Equal(t, "bad request", records[0]["sampled_message"])
+	assert.InDelta(t, 4, records[0]["suppressed"], 0)
+	assert.Equal(t, "bad request", records[1]["msg"], "the new interval starts fresh")
+}
+
+func TestSamplingHandler_Flush(t *testing.T) {
+	logger, handler, _, buf := newTestSamplingLogger(t, config.SamplingConfig{
+		Interval: time.Minute,
+		First:    1,
+	})
+
+	logger.Info("noisy")
+	logger.Info("noisy")
+	buf.Reset()
+
+	handler.Flush(context.Background())
+
+	records := decodeRecords(t, buf)
+	require.Len(t, records, 1)
+	assert.InDelta(t, 1, records[0]["suppressed"], 0)
+}
+
+func TestFlush(t *testing.T) {
+	logger, handler, _, buf := newTestSamplingLogger(t, config.SamplingConfig{
+		Interval: time.Minute,
+		First:    1,
+	})
+	setSampling(handler)
+	t.Cleanup(func() { _ = Close() })
+
+	logger.Info("noisy")
+	logger.Info("noisy")
+	buf.Reset()
+
+	Flush(context.Background())
+
+	records := decodeRecords(t, buf)
+	require.Len(t, records, 1)
+	assert.InDelta(t, 1, records[0]["suppressed"], 0)
+}
+
+func TestSamplingHandler_WithoutSampling(t *testing.T) {
+	logger, _, _, buf := newTestSamplingLogger(t, config.SamplingConfig{
+		Interval: time.Minute,
+		First:    1,
+	})
+
+	ctx := WithoutSampling(context.Background())
+	for i := 0; i < 5; i++ {
+		logger.InfoContext(ctx, "HTTP request", "path", i)
+	}
+	logger.Info("HTTP request")
+
+	assert.Len(t, decodeRecords(t, buf), 6)
+}
+
+func TestSamplingHandler_LevelRules(t *testing.T) {
+	logger, _, _, buf := newTestSamplingLogger(t, config.SamplingConfig{
+		Interval: time.Second,
+		First:    1,
+		Levels: map[string]config.SamplingRule{
+			"warn":  {Exempt: true},
+			"error": {First: 3},
+		},
+	})
+
+	for i := 0; i < 5; i++ {
+		logger.Info("info")
+		logger.Warn("warn")
+		logger.Error("error")
+	}
+
+	counts := map[string]int{}
+	for _, record := range decodeRecords(t, buf) {
+		counts[record["msg"].(string)]++
+	}
+
+	assert.Equal(t, map[string]int{"info": 1, "warn": 5, "error": 3}, counts)
+}
+
+func TestSamplingHandler_SharedAcrossDerivedLoggers(t *testing.T) {
+	logger, _, _, buf := newTestSamplingLogger(t, config.SamplingConfig{
+		Interval: time.Second,
+		First:    1,
+	})
+
+	logger.Info("same")
+	logger.With("component", "echo").Info("same")
+	logger.WithGroup("req").Info("same")
+
+	assert.Len(t, decodeRecords(t, buf), 1)
+}
+
+func TestNewSamplingHandler_UnknownLevel(t *testing.T) {
+	_, err := NewSamplingHandler(slog.DiscardHandler, config.SamplingConfig{
+		Levels: map[string]config.SamplingRule{"fatal": {}},
+	})
+
+	assert.Error(t, err)
+}
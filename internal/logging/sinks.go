@@ -0,0 +1,167 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/savisec/hello-go/internal/config"
+)
+
+const (
+	sinkStdout = "stdout"
+	sinkStderr = "stderr"
+	sinkFile   = "file"
+	sinkSyslog = "syslog"
+)
+
+// reopener is implemented by sinks backed by files that external tools such
+// as logrotate may move out from under us.
+type reopener interface {
+	Reopen() error
+}
+
+var (
+	sinksMu   sync.Mutex
+	openSinks []io.Closer
+	// activeSampling is the sampling handler installed by Setup, if any.
+	activeSampling *SamplingHandler
+)
+
+// Reopen closes and reopens every file sink so writes go to a fresh file
+// after an external rotation. It is typically called on SIGHUP.
+func Reopen() error {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+
+	var errs []error
+	for _, sink := range openSinks {
+		if r, ok := sink.(reopener); ok {
+			if err := r.Reopen(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close releases every sink opened by Setup.
+func Close() error {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+
+	var errs []error
+	for _, sink := range openSinks {
+		if err := sink.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	openSinks = nil
+	activeSampling = nil
+	return errors.Join(errs...)
+}
+
+func setSampling(h *SamplingHandler) {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+
+	activeSampling = h
+}
+
+func registerSink(sink io.Closer) {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+
+	openSinks = append(openSinks, sink)
+}
+
+// newSinkHandler builds the formatting handler for one sink.
+func newSinkHandler(sink config.SinkConfig, defaults config.LoggingConfig) (slog.Handler, error) {
+	format := sink.Format
+	if format == "" {
+		format = defaults.Format
+	}
+
+	levelStr := sink.Level
+	if levelStr == "" {
+		levelStr = defaults.Level
+	}
+	opts := &slog.HandlerOptions{
+		Level: parseLevel(levelStr),
+	}
+
+	switch strings.ToLower(sink.Type) {
+	case "", sinkStdout:
+		return newFormatHandler(os.Stdout, format, opts), nil
+	case sinkStderr:
+		return newFormatHandler(os.Stderr, format, opts), nil
+	case sinkFile:
+		if sink.File.Path == "" {
+			return nil, fmt.Errorf("file sink requires a path")
+		}
+		w := newFileSink(sink.File)
+		registerSink(w)
+		return newFormatHandler(w, format, opts), nil
+	case sinkSyslog:
+		w, err := newSyslogWriter(sink.Syslog)
+		if err != nil {
+			return nil, err
+		}
+		registerSink(w)
+		return newSyslogHandler(w, func(out io.Writer) slog.Handler {
+			return newFormatHandler(out, format, opts)
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown log sink type %q", sink.Type)
+	}
+}
+
+// newFormatHandler returns the slog handler for the named format.
+func newFormatHandler(w io.Writer, format string, opts *slog.HandlerOptions) slog.Handler {
+	switch strings.ToLower(format) {
+	case "json":
+		return slog.NewJSONHandler(w, opts)
+	case "pretty":
+		return NewPrettyHandler(w, opts)
+	case formatLambdaJSON:
+		return NewLambdaJSONHandler(w, opts)
+	default:
+		return slog.NewTextHandler(w, opts)
+	}
+}
+
+// fileSink writes to a file with size and age based rotation.
+type fileSink struct {
+	*lumberjack.Logger
+}
+
+func newFileSink(cfg config.FileSinkConfig) *fileSink {
+	maxAgeDays := 0
+	if cfg.MaxAge > 0 {
+		// lumberjack counts age in whole days; round up so we never delete early.
+		maxAgeDays = int((cfg.MaxAge + 24*time.Hour - 1) / (24 * time.Hour))
+	}
+
+	return &fileSink{
+		Logger: &lumberjack.Logger{
+			Filename:   cfg.Path,
+			MaxSize:    cfg.MaxSizeMB,
+			MaxAge:     maxAgeDays,
+			MaxBackups: cfg.MaxBackups,
+			Compress:   cfg.Compress,
+		},
+	}
+}
+
+// Reopen closes the current file; the next write opens cfg.Path again,
+// creating it if logrotate moved it away.
+func (f *fileSink) Reopen() error {
+	return f.Close()
+}
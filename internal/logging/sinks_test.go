@@ -0,0 +1,104 @@
+package logging
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/internal/config"
+)
+
+func TestFanoutHandler_PerSinkLevels(t *testing.T) {
+	var debugBuf, errorBuf bytes.Buffer
+	logger := slog.New(NewFanoutHandler(
+		slog.NewTextHandler(&debugBuf, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		slog.NewJSONHandler(&errorBuf, &slog.HandlerOptions{Level: slog.LevelError}),
+	))
+
+	logger.With("component", "test").Debug("details")
+	logger.Error("boom")
+
+	assert.Contains(t, debugBuf.String(), "msg=details component=test")
+	assert.Contains(t, debugBuf.String(), "msg=boom")
+	assert.NotContains(t, errorBuf.String(), "details")
+	assert.Contains(t, errorBuf.String(), `"msg":"boom"`)
+}
+
+func TestFileSink_ReopenAfterExternalRotation(t *testing.T) {
+	t.Cleanup(func() { _ = Close() })
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	handler, err := newSinkHandler(config.SinkConfig{
+		Type:   sinkFile,
+		Format: "json",
+		File:   config.FileSinkConfig{Path: path},
+	}, config.LoggingConfig{Level: "info"})
+	require.NoError(t, err)
+	logger := slog.New(handler)
+
+	logger.Info("before rotation")
+	require.NoError(t, os.Rename(path, path+".1"))
+
+	require.NoError(t, Reopen())
+	logger.Info("after rotation")
+
+	rotated, err := os.ReadFile(path + ".1")
+	require.NoError(t, err)
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(rotated), "before rotation")
+	assert.NotContains(t, string(rotated), "after rotation")
+	assert.Contains(t, string(current), "after rotation")
+}
+
+type fakeSyslogWriter struct {
+	messages map[string][]string
+}
+
+func (f *fakeSyslogWriter) record(severity, m string) error {
+	if f.messages == nil {
+		f.messages = map[string][]string{}
+	}
+	f.messages[severity] = append(f.messages[severity], m)
+	return nil
+}
+
+func (f *fakeSyslogWriter) Debug(m string) error   { return f.record("debug", m) }
+func (f *fakeSyslogWriter) Info(m string) error    { return f.record("info", m) }
+func (f *fakeSyslogWriter) Warning(m string) error { return f.record("warning", m) }
+func (f *fakeSyslogWriter) Err(m string) error     { return f.record("err", m) }
+func (f *fakeSyslogWriter) Close() error           { return nil }
+
+func TestSyslogHandler_SeverityMapping(t *testing.T) {
+	writer := &fakeSyslogWriter{}
+	logger := slog.New(newSyslogHandler(writer, func(w io.Writer) slog.Handler {
+		return slog.NewTextHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug})
+	}))
+
+	logger.Debug("d")
+	logger.Info("i")
+	logger.With("k", "v").Warn("w")
+	logger.Error("e")
+
+	require.Len(t, writer.messages["warning"], 1)
+	assert.Contains(t, writer.messages["warning"][0], "msg=w k=v")
+	assert.NotContains(t, writer.messages["warning"][0], "\n")
+	assert.Len(t, writer.messages["debug"], 1)
+	assert.Len(t, writer.messages["info"], 1)
+	assert.Len(t, writer.messages["err"], 1)
+}
+
+func TestNewSinkHandler_Invalid(t *testing.T) {
+	_, err := newSinkHandler(config.SinkConfig{Type: "kafka"}, config.LoggingConfig{})
+	require.Error(t, err)
+
+	_, err = newSinkHandler(config.SinkConfig{Type: sinkFile}, config.LoggingConfig{})
+	require.Error(t, err)
+}
@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// syslogWriter is the subset of *syslog.Writer used by syslogHandler.
+type syslogWriter interface {
+	io.Closer
+	Debug(m string) error
+	Info(m string) error
+	Warning(m string) error
+	Err(m string) error
+}
+
+// syslogHandler formats records with an inner handler and forwards them to
+// syslog at the matching severity.
+type syslogHandler struct {
+	writer syslogWriter
+	inner  slog.Handler
+	state  *syslogState
+}
+
+// syslogState is shared by a syslogHandler and its derived handlers so they
+// reuse one buffer.
+type syslogState struct {
+	buf bytes.Buffer
+	mu  sync.Mutex
+}
+
+func newSyslogHandler(w syslogWriter, newInner func(io.Writer) slog.Handler) *syslogHandler {
+	state := &syslogState{}
+	return &syslogHandler{
+		writer: w,
+		inner:  newInner(&state.buf),
+		state:  state,
+	}
+}
+
+func (h *syslogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+func (h *syslogHandler) Handle(ctx context.Context, record slog.Record) error {
+	h.state.mu.Lock()
+	defer h.state.mu.Unlock()
+
+	h.state.buf.Reset()
+	if err := h.inner.Handle(ctx, record); err != nil {
+		return err
+	}
+	msg := strings.TrimSuffix(h.state.buf.String(), "\n")
+
+	switch {
+	case record.Level >= slog.LevelError:
+		return h.writer.Err(msg)
+	case record.Level >= slog.LevelWarn:
+		return h.writer.Warning(msg)
+	case record.Level >= slog.LevelInfo:
+		return h.writer.Info(msg)
+	default:
+		return h.writer.Debug(msg)
+	}
+}
+
+func (h *syslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &syslogHandler{writer: h.writer, inner: h.inner.WithAttrs(attrs), state: h.state}
+}
+
+func (h *syslogHandler) WithGroup(name string) slog.Handler {
+	return &syslogHandler{writer: h.writer, inner: h.inner.WithGroup(name), state: h.state}
+}
@@ -0,0 +1,13 @@
+//go:build windows || plan9
+
+package logging
+
+import (
+	"errors"
+
+	"github.com/savisec/hello-go/internal/config"
+)
+
+func newSyslogWriter(config.SyslogSinkConfig) (syslogWriter, error) {
+	return nil, errors.New("syslog sinks are not supported on this platform")
+}
@@ -0,0 +1,30 @@
+//go:build !windows && !plan9
+
+package logging
+
+import (
+	"fmt"
+	"log/syslog"
+
+	"github.com/savisec/hello-go/internal/config"
+)
+
+const defaultSyslogSocket = "/dev/log"
+
+func newSyslogWriter(cfg config.SyslogSinkConfig) (syslogWriter, error) {
+	socket := cfg.Socket
+	if socket == "" {
+		socket = defaultSyslogSocket
+	}
+
+	tag := cfg.Tag
+	if tag == "" {
+		tag = "hello-go"
+	}
+
+	w, err := syslog.Dial("unixgram", socket, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog at %s: %w", socket, err)
+	}
+	return w, nil
+}
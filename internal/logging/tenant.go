@@ -0,0 +1,40 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/savisec/hello-go/internal/lambdactx"
+)
+
+// TenantHandler is a slog.Handler that adds the tenant of the request a
+// record is logged for, so that every record logged with a request's
+// context can be attributed without each call site adding it.
+type TenantHandler struct {
+	next slog.Handler
+}
+
+// NewTenantHandler wraps next.
+func NewTenantHandler(next slog.Handler) *TenantHandler {
+	return &TenantHandler{next: next}
+}
+
+func (h *TenantHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *TenantHandler) Handle(ctx context.Context, record slog.Record) error {
+	if rc, ok := lambdactx.FromContext(ctx); ok && rc.Tenant != "" {
+		record = record.Clone()
+		record.AddAttrs(slog.String("tenant", rc.Tenant))
+	}
+	return h.next.Handle(ctx, record)
+}
+
+func (h *TenantHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &TenantHandler{next: h.next.WithAttrs(attrs)}
+}
+
+func (h *TenantHandler) WithGroup(name string) slog.Handler {
+	return &TenantHandler{next: h.next.WithGroup(name)}
+}
@@ -0,0 +1,29 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/savisec/hello-go/internal/lambdactx"
+)
+
+func TestTenantHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewTenantHandler(slog.NewTextHandler(&buf, nil))).With("component", "echo")
+
+	ctx := lambdactx.WithTenant(lambdactx.NewContext(context.Background(), lambdactx.RequestContext{}), "team-a")
+	logger.InfoContext(ctx, "served")
+	assert.Contains(t, buf.String(), "component=echo tenant=team-a")
+
+	buf.Reset()
+	logger.InfoContext(lambdactx.NewContext(context.Background(), lambdactx.RequestContext{}), "served")
+	assert.NotContains(t, buf.String(), "tenant=")
+
+	buf.Reset()
+	logger.Info("served")
+	assert.NotContains(t, buf.String(), "tenant=")
+}
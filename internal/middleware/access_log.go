@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/savisec/hello-go/internal/lambdactx"
+	"github.com/savisec/hello-go/internal/logging"
+)
+
+// AccessLogger writes one structured log record per request. Request
+// metadata comes from lambdactx, so records look the same whether the
+// request arrived through API Gateway or the HTTP server. Access records
+// all share a message, so they are exempt from log sampling.
+type AccessLogger struct {
+	Logger *slog.Logger
+}
+
+func NewAccessLogger(logger *slog.Logger) *AccessLogger {
+	return &AccessLogger{
+		Logger: logger,
+	}
+}
+
+func (al *AccessLogger) ServeHTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		rc := lambdactx.FromRequest(r)
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Int("bytes", ww.BytesWritten()),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("request_id", rc.RequestID),
+			slog.String("source_ip", rc.SourceIP),
+			slog.String("source", string(rc.Source)),
+		}
+		if rc.Stage != "" {
+			attrs = append(attrs, slog.String("stage", rc.Stage))
+		}
+		if rc.Identity != nil {
+			attrs = append(attrs, slog.String("principal", rc.Identity.Subject))
+		}
+
+		al.Logger.LogAttrs(logging.WithoutSampling(r.Context()), slog.LevelInfo, "HTTP request", attrs...)
+	})
+}
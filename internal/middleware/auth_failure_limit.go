@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/savisec/hello-go/internal/lambdactx"
+	"github.com/savisec/hello-go/internal/ratelimit"
+)
+
+// AuthFailureLimit throttles guessing of credentials: every 401 counts
+// against the client's source IP, and a source IP that has used up its
+// failed attempts gets a 429 before its credentials are checked. It must
+// run before Authentication. If the backend fails, requests are let
+// through.
+type AuthFailureLimit struct {
+	Limiter *ratelimit.Limiter
+	Logger  *slog.Logger
+}
+
+func NewAuthFailureLimit(limiter *ratelimit.Limiter, logger *slog.Logger) *AuthFailureLimit {
+	return &AuthFailureLimit{
+		Limiter: limiter,
+		Logger:  logger,
+	}
+}
+
+func (al *AuthFailureLimit) ServeHTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sourceIP := lambdactx.FromRequest(r).SourceIP
+
+		res, err := al.Limiter.CheckFailures(r.Context(), sourceIP)
+		if err != nil {
+			al.Logger.ErrorContext(r.Context(), "Auth failure limiting unavailable, allowing request", "error", err)
+		} else if !res.Allowed {
+			al.Logger.WarnContext(r.Context(), "Request rate limited after failed authentication", "source_ip", sourceIP)
+			w.Header().Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(res.RetryAfter))))
+			writeErrorResponse(w, http.StatusTooManyRequests, "Too many failed authentication attempts", al.Logger)
+			return
+		}
+
+		ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		if ww.Status() == http.StatusUnauthorized {
+			if _, err := al.Limiter.TakeFailure(r.Context(), sourceIP); err != nil {
+				al.Logger.ErrorContext(r.Context(), "Failed to count failed authentication", "error", err)
+			}
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package middleware_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/internal/config"
+	"github.com/savisec/hello-go/internal/lambdactx"
+	"github.com/savisec/hello-go/internal/middleware"
+	"github.com/savisec/hello-go/internal/ratelimit"
+)
+
+func TestAuthFailureLimit(t *testing.T) {
+	limiter, err := ratelimit.NewLimiter(config.RateLimitConfig{
+		Default:      config.RateLimitRule{Requests: 600, Period: time.Minute},
+		AuthFailures: config.RateLimitRule{Requests: 2, Period: time.Minute},
+	}, ratelimit.NewMemoryStore())
+	require.NoError(t, err)
+	require.True(t, limiter.LimitsFailures())
+
+	var checked int
+	handler := lambdactx.Middleware(middleware.NewAuthFailureLimit(limiter, slog.New(slog.DiscardHandler)).ServeHTTP(
+		// Stands in for Authentication.
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			checked++
+			if r.Header.Get("X-API-Key") != "good" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}),
+	))
+
+	send := func(remoteAddr, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/echo", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-API-Key", key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for range 5 {
+		assert.Equal(t, http.StatusOK, send("192.0.2.1:1234", "good").Code, "successes are not counted")
+	}
+	assert.Equal(t, http.StatusUnauthorized, send("192.0.2.1:1234", "bad").Code)
+	assert.Equal(t, http.StatusUnauthorized, send("192.0.2.1:1234", "bad").Code)
+
+	checked = 0
+	rec := send("192.0.2.1:1234", "good")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "30", rec.Header().Get("Retry-After"))
+	assert.Zero(t, checked, "credentials are not checked once throttled")
+
+	assert.Equal(t, http.StatusOK, send("192.0.2.2:1234", "good").Code, "source IPs are limited separately")
+}
@@ -0,0 +1,233 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/savisec/hello-go/internal/audit"
+	"github.com/savisec/hello-go/internal/auth"
+	"github.com/savisec/hello-go/internal/lambdactx"
+	"github.com/savisec/hello-go/internal/operation"
+	"github.com/savisec/hello-go/internal/signing"
+)
+
+const (
+	apiKeyHeader = "X-API-Key"
+
+	// The api/openapi.yml security schemes credentials arrive by. An API
+	// Gateway authorizer stands in for bearerAuth, whose Authorization
+	// header it validates.
+	bearerScheme    = "bearerAuth"
+	apiKeyScheme    = "apiKeyAuth"
+	signatureScheme = "hmacSignature"
+)
+
+// Authentication requires an API key, JWT or HMAC request signature on
+// every request whose operation is not public, and checks the credential
+// arrived by a security scheme the operation lists and grants the scopes
+// it declares for that scheme. Requests that resolve to no operation need
+// valid credentials, other than a signature, but no scopes. Every
+// accepted credential and every rejection is recorded in the audit log.
+// It must run after operation.Middleware.
+type Authentication struct {
+	Authenticator *auth.Authenticator
+	// Signatures verifies HMAC signed requests; nil disables them.
+	Signatures *signing.Verifier
+	Audit      *audit.Logger
+	Logger     *slog.Logger
+	// Gateway accepts the caller an API Gateway authorizer established for
+	// a Lambda event in place of the request's own credentials.
+	Gateway bool
+}
+
+func NewAuthentication(authenticator *auth.Authenticator, signatures *signing.Verifier, gateway bool, auditLogger *audit.Logger, logger *slog.Logger) *Authentication {
+	return &Authentication{
+		Authenticator: authenticator,
+		Signatures:    signatures,
+		Gateway:       gateway,
+		Audit:         auditLogger,
+		Logger:        logger,
+	}
+}
+
+func (a *Authentication) ServeHTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op, ok := operation.FromContext(r.Context())
+		if ok && op.Public() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var identity *lambdactx.Identity
+		var scheme string
+		var err error
+		if gw, fromGateway := a.gatewayIdentity(r); fromGateway {
+			identity, scheme = gw, bearerScheme
+		} else if signing.IsSigned(r) {
+			if a.Signatures == nil || !ok || !acceptsScheme(op, signatureScheme) {
+				a.reject(w, r, http.StatusUnauthorized, "Request signatures are not accepted here", `Bearer realm="hello-go"`, "unexpected signature")
+				return
+			}
+			body, bodyErr := bufferBody(r, maxBufferedBodyBytes)
+			if bodyErr != nil {
+				writeBodyError(w, bodyErr, a.Logger)
+				return
+			}
+			identity, err = a.verifySignature(r, body)
+			scheme = signatureScheme
+		} else if token, hasToken := bearerToken(r); hasToken {
+			if ok && !acceptsScheme(op, bearerScheme) {
+				a.reject(w, r, http.StatusUnauthorized, "Bearer tokens are not accepted here", `Bearer realm="hello-go"`, "unexpected bearer token")
+				return
+			}
+			identity, err = a.Authenticator.AuthenticateBearer(r.Context(), token)
+			scheme = bearerScheme
+		} else if key := strings.TrimSpace(r.Header.Get(apiKeyHeader)); key != "" {
+			if ok && !acceptsScheme(op, apiKeyScheme) {
+				a.reject(w, r, http.StatusUnauthorized, "API keys are not accepted here", `Bearer realm="hello-go"`, "unexpected api key")
+				return
+			}
+			identity, err = a.Authenticator.Authenticate(r.Context(), key)
+			scheme = apiKeyScheme
+		} else {
+			a.reject(w, r, http.StatusUnauthorized, "Missing credentials", `Bearer realm="hello-go"`, "missing credentials")
+			return
+		}
+
+		if errors.Is(err, auth.ErrInvalidCredentials) || errors.Is(err, signing.ErrInvalidSignature) {
+			a.reject(w, r, http.StatusUnauthorized, "Invalid credentials", `Bearer realm="hello-go", error="invalid_token"`, err.Error())
+			return
+		}
+		if err != nil {
+			// The credentials could not be checked, e.g. the JWKS is unreachable.
+			a.Logger.ErrorContext(r.Context(), "Failed to authenticate request", "error", err)
+			writeErrorResponse(w, http.StatusServiceUnavailable, "Authentication unavailable", a.Logger)
+			return
+		}
+
+		ctx := lambdactx.WithIdentity(r.Context(), identity)
+		trace.SpanFromContext(ctx).SetAttributes(semconv.EnduserID(identity.Subject))
+
+		if ok {
+			if allowed, scopes := auth.Allowed(identity, scheme, op.Security); !allowed {
+				challenge := fmt.Sprintf(`Bearer realm="hello-go", error="insufficient_scope", scope=%q`, strings.Join(scopes, " "))
+				a.reject(w, r.WithContext(ctx), http.StatusForbidden, "Insufficient scope", challenge, "insufficient scope")
+				return
+			}
+		}
+
+		a.accept(r.WithContext(ctx), identity, scheme)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// accept records that r was authenticated as identity by scheme.
+func (a *Authentication) accept(r *http.Request, identity *lambdactx.Identity, scheme string) {
+	rc := lambdactx.FromRequest(r)
+	event := audit.Event{
+		Type:      audit.EventAuthSuccess,
+		Outcome:   audit.OutcomeSuccess,
+		Actor:     identity.Subject,
+		Resource:  r.Method + " " + r.URL.Path,
+		RequestID: rc.RequestID,
+		SourceIP:  rc.SourceIP,
+		Details:   map[string]any{"scheme": scheme, "authorizer": identity.Authorizer},
+	}
+	if op, ok := operation.FromContext(r.Context()); ok {
+		event.Action = op.ID
+	}
+	if err := a.Audit.Log(r.Context(), event); err != nil {
+		a.Logger.ErrorContext(r.Context(), "Failed to write audit event", "error", err)
+	}
+}
+
+func (a *Authentication) reject(w http.ResponseWriter, r *http.Request, status int, message, challenge, reason string) {
+	rc := lambdactx.FromRequest(r)
+	attrs := []any{"reason", reason, "path", r.URL.Path, "source_ip", rc.SourceIP}
+	if rc.Identity != nil {
+		attrs = append(attrs, "principal", rc.Identity.Subject)
+	}
+	a.Logger.WarnContext(r.Context(), "Request rejected by authentication", attrs...)
+
+	event := audit.Event{
+		Type:      audit.EventAuthFailure,
+		Outcome:   audit.OutcomeFailure,
+		Resource:  r.Method + " " + r.URL.Path,
+		RequestID: rc.RequestID,
+		SourceIP:  rc.SourceIP,
+		Details:   map[string]any{"reason": reason, "status": status},
+	}
+	if op, ok := operation.FromContext(r.Context()); ok {
+		event.Action = op.ID
+	}
+	if rc.Identity != nil {
+		event.Actor = rc.Identity.Subject
+	}
+	if err := a.Audit.Log(r.Context(), event); err != nil {
+		a.Logger.ErrorContext(r.Context(), "Failed to write audit event", "error", err)
+	}
+
+	w.Header().Set("WWW-Authenticate", challenge)
+	writeErrorResponse(w, status, message, a.Logger)
+}
+
+// gatewayIdentity returns the caller API Gateway's authorizer established,
+// when that is accepted. Only Lambda events carry one; requests to the
+// HTTP server never do.
+func (a *Authentication) gatewayIdentity(r *http.Request) (*lambdactx.Identity, bool) {
+	if !a.Gateway {
+		return nil, false
+	}
+	rc := lambdactx.FromRequest(r)
+	switch rc.Source {
+	case lambdactx.SourceAPIGatewayV1, lambdactx.SourceAPIGatewayV2:
+		return rc.Identity, rc.Identity != nil
+	}
+	return nil, false
+}
+
+// verifySignature checks the HMAC signature of r and returns the caller
+// it identifies.
+func (a *Authentication) verifySignature(r *http.Request, body []byte) (*lambdactx.Identity, error) {
+	key, err := a.Signatures.Verify(r.Context(), r, body)
+	if err != nil {
+		return nil, err
+	}
+	claims := map[string]string{"key_id": key.ID}
+	if key.Tenant != "" {
+		claims[auth.TenantClaim] = key.Tenant
+	}
+	return &lambdactx.Identity{
+		Subject:    key.Principal,
+		Scopes:     key.Scopes,
+		Authorizer: signing.AuthorizerHMAC,
+		Claims:     claims,
+	}, nil
+}
+
+// acceptsScheme reports whether any of op's requirements lists scheme.
+func acceptsScheme(op *operation.Operation, scheme string) bool {
+	for _, req := range op.Security {
+		if slices.Contains(req.Schemes, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
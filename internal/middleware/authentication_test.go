@@ -0,0 +1,363 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/api"
+	"github.com/savisec/hello-go/internal/audit"
+	"github.com/savisec/hello-go/internal/auth"
+	"github.com/savisec/hello-go/internal/config"
+	"github.com/savisec/hello-go/internal/lambdactx"
+	"github.com/savisec/hello-go/internal/middleware"
+	"github.com/savisec/hello-go/internal/operation"
+	"github.com/savisec/hello-go/internal/signing"
+)
+
+func TestAuthentication(t *testing.T) {
+	writer, writerKey, err := auth.GenerateKey("writer", "alice", []string{"echo:write"})
+	require.NoError(t, err)
+	reader, readerKey, err := auth.GenerateKey("reader", "bob", []string{"echo:read"})
+	require.NoError(t, err)
+
+	store, err := auth.NewConfigKeyStore([]config.APIKeyConfig{
+		{ID: writerKey.ID, Principal: writerKey.Principal, Scopes: writerKey.Scopes, Salt: writerKey.Salt, Hash: writerKey.Hash},
+		{ID: readerKey.ID, Principal: readerKey.Principal, Scopes: readerKey.Scopes, Salt: readerKey.Salt, Hash: readerKey.Hash},
+	})
+	require.NoError(t, err)
+
+	resolver, err := operation.NewResolver(api.OpenAPISpec)
+	require.NoError(t, err)
+
+	var logs, auditLog bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	r := chi.NewRouter()
+	r.Use(lambdactx.Middleware)
+	r.Use(middleware.NewAccessLogger(logger).ServeHTTP)
+	r.Use(operation.Middleware(resolver))
+	r.Use(middleware.NewAuthentication(auth.NewAuthenticator(store, nil), nil, false, audit.NewLogger(&auditLog, []byte("0123456789abcdef")), logger).ServeHTTP)
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		rc, _ := lambdactx.FromContext(r.Context())
+		if rc.Identity != nil {
+			w.Header().Set("X-Principal", rc.Identity.Subject)
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	r.Get("/healthz", ok)
+	r.Post("/v1/echo", ok)
+	r.Get("/internal", ok)
+
+	tests := []struct {
+		headers       map[string]string
+		name          string
+		method        string
+		path          string
+		wantPrincipal string
+		wantChallenge string
+		wantStatus    int
+	}{
+		{name: "public operation", method: http.MethodGet, path: "/healthz", wantStatus: http.StatusOK},
+		{
+			name: "missing key", method: http.MethodPost, path: "/v1/echo",
+			wantStatus: http.StatusUnauthorized, wantChallenge: `Bearer realm="hello-go"`,
+		},
+		{
+			name: "bearer key", method: http.MethodPost, path: "/v1/echo",
+			headers:    map[string]string{"Authorization": "Bearer " + writer},
+			wantStatus: http.StatusOK, wantPrincipal: "alice",
+		},
+		{
+			name: "x-api-key header", method: http.MethodPost, path: "/v1/echo",
+			headers:    map[string]string{"X-API-Key": writer},
+			wantStatus: http.StatusOK, wantPrincipal: "alice",
+		},
+		{
+			name: "wrong secret", method: http.MethodPost, path: "/v1/echo",
+			headers:    map[string]string{"X-API-Key": "writer.nope"},
+			wantStatus: http.StatusUnauthorized, wantChallenge: `Bearer realm="hello-go", error="invalid_token"`,
+		},
+		{
+			name: "missing scope", method: http.MethodPost, path: "/v1/echo",
+			headers:    map[string]string{"Authorization": "bearer " + reader},
+			wantStatus: http.StatusForbidden, wantChallenge: `Bearer realm="hello-go", error="insufficient_scope", scope="echo:write"`,
+		},
+		{
+			name: "route outside the spec needs a key", method: http.MethodGet, path: "/internal",
+			wantStatus: http.StatusUnauthorized, wantChallenge: `Bearer realm="hello-go"`,
+		},
+		{
+			name: "route outside the spec needs no scope", method: http.MethodGet, path: "/internal",
+			headers:    map[string]string{"X-API-Key": reader},
+			wantStatus: http.StatusOK, wantPrincipal: "bob",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+			auditLog.Reset()
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{}`))
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.wantChallenge, rec.Header().Get("WWW-Authenticate"))
+			assert.Equal(t, tt.wantPrincipal, rec.Header().Get("X-Principal"))
+			if tt.wantPrincipal != "" {
+				assert.Contains(t, logs.String(), "principal="+tt.wantPrincipal, "access log names the caller")
+			}
+			assert.NotContains(t, logs.String(), writer, "secrets are never logged")
+
+			if tt.wantPrincipal == "" && tt.wantStatus == http.StatusOK {
+				assert.Empty(t, auditLog.String(), "public operations are not audited")
+				return
+			}
+			var line struct {
+				Record audit.Record `json:"record"`
+			}
+			require.NoError(t, json.Unmarshal(auditLog.Bytes(), &line))
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, audit.EventAuthSuccess, line.Record.Type)
+				assert.Equal(t, audit.OutcomeSuccess, line.Record.Outcome)
+				assert.Equal(t, tt.wantPrincipal, line.Record.Actor)
+				assert.Equal(t, tt.method+" "+tt.path, line.Record.Resource)
+				return
+			}
+			assert.Equal(t, audit.EventAuthFailure, line.Record.Type)
+			assert.Equal(t, audit.OutcomeFailure, line.Record.Outcome)
+			assert.Equal(t, tt.method+" "+tt.path, line.Record.Resource)
+			assert.NotContains(t, auditLog.String(), writer, "secrets are never audited")
+		})
+	}
+}
+
+func TestAuthentication_Signatures(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	verifier, err := signing.New(config.SigningConfig{Keys: []config.SigningKeyConfig{{
+		ID:        "partner",
+		Principal: "acme",
+		Scopes:    []string{"echo:write"},
+		Secret:    hex.EncodeToString(secret),
+	}}})
+	require.NoError(t, err)
+
+	resolver, err := operation.NewResolver(api.OpenAPISpec)
+	require.NoError(t, err)
+
+	store, err := auth.NewConfigKeyStore(nil)
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Use(lambdactx.Middleware)
+	r.Use(operation.Middleware(resolver))
+	r.Use(middleware.NewAuthentication(auth.NewAuthenticator(store, nil), verifier, false, audit.NewLogger(nil, nil), slog.New(slog.DiscardHandler)).ServeHTTP)
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		rc, _ := lambdactx.FromContext(r.Context())
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Principal", rc.Identity.Subject)
+		_, _ = w.Write(body)
+	}
+	r.Post("/v1/echo", ok)
+	r.Post("/internal", ok)
+
+	tests := []struct {
+		tamper     func(r *http.Request)
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{name: "signed echo", path: "/v1/echo", wantStatus: http.StatusOK},
+		{
+			name: "tampered body", path: "/v1/echo", wantStatus: http.StatusUnauthorized,
+			tamper: func(r *http.Request) { r.Body = io.NopCloser(strings.NewReader(`{"message":"bye"}`)) },
+		},
+		{name: "operation without the signature scheme", path: "/internal", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := []byte(`{"message":"hi"}`)
+			req := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewReader(body))
+			require.NoError(t, signing.Sign(req, body, "partner", secret, time.Now()))
+			if tt.tamper != nil {
+				tt.tamper(req)
+			}
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, "acme", rec.Header().Get("X-Principal"))
+				assert.Equal(t, string(body), rec.Body.String(), "the handler still reads the body")
+			}
+		})
+	}
+}
+
+func TestAuthentication_GatewayAuthorizer(t *testing.T) {
+	resolver, err := operation.NewResolver(api.OpenAPISpec)
+	require.NoError(t, err)
+
+	store, err := auth.NewConfigKeyStore(nil)
+	require.NoError(t, err)
+
+	writer := &lambdactx.Identity{Subject: "alice", Scopes: []string{"echo:write"}, Authorizer: "jwt"}
+	reader := &lambdactx.Identity{Subject: "bob", Scopes: []string{"echo:read"}, Authorizer: "jwt"}
+
+	tests := []struct {
+		identity   *lambdactx.Identity
+		name       string
+		source     lambdactx.Source
+		wantStatus int
+		gateway    bool
+	}{
+		{name: "gateway caller", gateway: true, source: lambdactx.SourceAPIGatewayV2, identity: writer, wantStatus: http.StatusOK},
+		{name: "rest api caller", gateway: true, source: lambdactx.SourceAPIGatewayV1, identity: writer, wantStatus: http.StatusOK},
+		{name: "scopes still checked", gateway: true, source: lambdactx.SourceAPIGatewayV2, identity: reader, wantStatus: http.StatusForbidden},
+		{name: "no authorizer on the route", gateway: true, source: lambdactx.SourceAPIGatewayV2, wantStatus: http.StatusUnauthorized},
+		{name: "not a gateway event", gateway: true, source: lambdactx.SourceHTTP, identity: writer, wantStatus: http.StatusUnauthorized},
+		{name: "gateway authorizer not accepted", source: lambdactx.SourceAPIGatewayV2, identity: writer, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authentication := middleware.NewAuthentication(auth.NewAuthenticator(store, nil), nil, tt.gateway, audit.NewLogger(nil, nil), slog.New(slog.DiscardHandler))
+
+			r := chi.NewRouter()
+			r.Use(func(next http.Handler) http.Handler {
+				// Stands in for lambdactx.Middleware on a Lambda event.
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					rc := lambdactx.RequestContext{Source: tt.source, Identity: tt.identity}
+					next.ServeHTTP(w, r.WithContext(lambdactx.NewContext(r.Context(), rc)))
+				})
+			})
+			r.Use(operation.Middleware(resolver))
+			r.Use(authentication.ServeHTTP)
+			r.Post("/v1/echo", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Principal", lambdactx.FromRequest(r).Identity.Subject)
+			})
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/echo", strings.NewReader(`{}`)))
+
+			require.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, tt.identity.Subject, rec.Header().Get("X-Principal"))
+			}
+		})
+	}
+}
+
+// schemeSpec has one operation per security scheme, each accepting only
+// that scheme.
+const schemeSpec = `
+openapi: 3.0.3
+info: {title: schemes, version: "1"}
+paths:
+  /signed:
+    post:
+      operationId: signed
+      security: [{hmacSignature: [echo:write]}]
+      responses: {"200": {description: ok}}
+  /bearer:
+    post:
+      operationId: bearer
+      security: [{bearerAuth: [echo:write]}]
+      responses: {"200": {description: ok}}
+components:
+  securitySchemes:
+    bearerAuth: {type: http, scheme: bearer}
+    apiKeyAuth: {type: apiKey, in: header, name: X-API-Key}
+    hmacSignature: {type: apiKey, in: header, name: X-Signature}
+`
+
+func TestAuthentication_SchemeRestricted(t *testing.T) {
+	apiKey, keyConfig, err := auth.GenerateKey("writer", "alice", []string{"echo:write"})
+	require.NoError(t, err)
+	store, err := auth.NewConfigKeyStore([]config.APIKeyConfig{
+		{ID: keyConfig.ID, Principal: keyConfig.Principal, Scopes: keyConfig.Scopes, Salt: keyConfig.Salt, Hash: keyConfig.Hash},
+	})
+	require.NoError(t, err)
+
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	verifier, err := signing.New(config.SigningConfig{Keys: []config.SigningKeyConfig{{
+		ID:     "partner",
+		Scopes: []string{"echo:write"},
+		Secret: hex.EncodeToString(secret),
+	}}})
+	require.NoError(t, err)
+
+	resolver, err := operation.NewResolver([]byte(schemeSpec))
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Use(lambdactx.Middleware)
+	r.Use(operation.Middleware(resolver))
+	r.Use(middleware.NewAuthentication(auth.NewAuthenticator(store, nil), verifier, false, audit.NewLogger(nil, nil), slog.New(slog.DiscardHandler)).ServeHTTP)
+	r.Post("/signed", func(http.ResponseWriter, *http.Request) {})
+	r.Post("/bearer", func(http.ResponseWriter, *http.Request) {})
+
+	tests := []struct {
+		credential func(r *http.Request)
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{
+			name: "signature on signed operation", path: "/signed", wantStatus: http.StatusOK,
+			credential: func(r *http.Request) { require.NoError(t, signing.Sign(r, nil, "partner", secret, time.Now())) },
+		},
+		{
+			name: "api key on signed operation", path: "/signed", wantStatus: http.StatusUnauthorized,
+			credential: func(r *http.Request) { r.Header.Set("X-API-Key", apiKey) },
+		},
+		{
+			name: "bearer key on signed operation", path: "/signed", wantStatus: http.StatusUnauthorized,
+			credential: func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+apiKey) },
+		},
+		{
+			name: "bearer key on bearer operation", path: "/bearer", wantStatus: http.StatusOK,
+			credential: func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+apiKey) },
+		},
+		{
+			name: "api key header on bearer operation", path: "/bearer", wantStatus: http.StatusUnauthorized,
+			credential: func(r *http.Request) { r.Header.Set("X-API-Key", apiKey) },
+		},
+		{
+			name: "signature on bearer operation", path: "/bearer", wantStatus: http.StatusUnauthorized,
+			credential: func(r *http.Request) { require.NoError(t, signing.Sign(r, nil, "partner", secret, time.Now())) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			tt.credential(req)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/savisec/hello-go/internal/audit"
+	"github.com/savisec/hello-go/internal/authz"
+	"github.com/savisec/hello-go/internal/lambdactx"
+	"github.com/savisec/hello-go/internal/operation"
+)
+
+// Authorization checks every request against the authorization policy and
+// records each decision in the audit log. It must run after Authentication;
+// requests that resolve to no operation are matched by "*" rules only.
+type Authorization struct {
+	Engine *authz.Engine
+	Audit  *audit.Logger
+	Logger *slog.Logger
+}
+
+func NewAuthorization(engine *authz.Engine, auditLogger *audit.Logger, logger *slog.Logger) *Authorization {
+	return &Authorization{
+		Engine: engine,
+		Audit:  auditLogger,
+		Logger: logger,
+	}
+}
+
+func (a *Authorization) ServeHTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := lambdactx.FromRequest(r)
+		in := authz.Input{Identity: rc.Identity}
+		if op, ok := operation.FromContext(r.Context()); ok {
+			in.Operation = op.ID
+		}
+
+		if a.Engine.UsesBody(in.Operation) {
+			body, err := bufferBody(r, maxBufferedBodyBytes)
+			if err != nil {
+				writeBodyError(w, err, a.Logger)
+				return
+			}
+
+			// A body that is not a JSON object leaves body attributes absent,
+			// and the handler reports the malformed request. One the handler
+			// could read differently from the policy is rejected here.
+			in.Body, err = authz.DecodeBody(body)
+			if err != nil {
+				a.Logger.WarnContext(r.Context(), "Rejected ambiguous request body", "operation", in.Operation, "error", err)
+				writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON", a.Logger)
+				return
+			}
+		}
+
+		decision := a.Engine.Decide(in)
+		a.record(r, rc, in, decision)
+
+		if !decision.Allowed {
+			writeErrorResponse(w, http.StatusForbidden, "Forbidden", a.Logger)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *Authorization) record(r *http.Request, rc lambdactx.RequestContext, in authz.Input, decision authz.Decision) {
+	event := audit.Event{
+		Type:      audit.EventAuthDecision,
+		Outcome:   audit.OutcomeAllow,
+		Action:    in.Operation,
+		Resource:  r.Method + " " + r.URL.Path,
+		RequestID: rc.RequestID,
+		SourceIP:  rc.SourceIP,
+		Details: map[string]any{
+			"rule":   decision.Rule,
+			"reason": decision.Reason,
+			"roles":  decision.Roles,
+		},
+	}
+	if in.Identity != nil {
+		event.Actor = in.Identity.Subject
+	}
+	if !decision.Allowed {
+		event.Outcome = audit.OutcomeDeny
+		a.Logger.WarnContext(r.Context(), "Request denied by policy",
+			"operation", in.Operation, "principal", event.Actor, "rule", decision.Rule, "reason", decision.Reason)
+	}
+
+	if err := a.Audit.Log(r.Context(), event); err != nil {
+		a.Logger.ErrorContext(r.Context(), "Failed to write audit event", "error", err)
+	}
+}
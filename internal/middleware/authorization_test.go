@@ -0,0 +1,110 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/api"
+	"github.com/savisec/hello-go/internal/audit"
+	"github.com/savisec/hello-go/internal/authz"
+	"github.com/savisec/hello-go/internal/lambdactx"
+	"github.com/savisec/hello-go/internal/middleware"
+	"github.com/savisec/hello-go/internal/operation"
+)
+
+func TestAuthorization(t *testing.T) {
+	policy, err := authz.ParsePolicy([]byte(`
+roles:
+  writer:
+    scopes: [echo:write]
+rules:
+  - name: writers-as-themselves
+    effect: allow
+    operations: [echo]
+    roles: [writer]
+    conditions:
+      - {attribute: body.author, operator: eq, value_from: subject}
+`))
+	require.NoError(t, err)
+
+	resolver, err := operation.NewResolver(api.OpenAPISpec)
+	require.NoError(t, err)
+
+	var auditLog bytes.Buffer
+	logger := slog.New(slog.DiscardHandler)
+
+	r := chi.NewRouter()
+	r.Use(lambdactx.Middleware)
+	r.Use(operation.Middleware(resolver))
+	r.Use(func(next http.Handler) http.Handler {
+		// Stands in for Authentication.
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity := &lambdactx.Identity{Subject: r.Header.Get("X-Subject"), Scopes: []string{"echo:write"}}
+			next.ServeHTTP(w, r.WithContext(lambdactx.WithIdentity(r.Context(), identity)))
+		})
+	})
+	r.Use(middleware.NewAuthorization(authz.NewEngine(policy), audit.NewLogger(&auditLog, []byte("0123456789abcdef")), logger).ServeHTTP)
+	r.Post("/v1/echo", func(w http.ResponseWriter, r *http.Request) {
+		// The body read for the policy is still there for the handler.
+		_, _ = io.Copy(w, r.Body)
+	})
+
+	tests := []struct {
+		name        string
+		subject     string
+		body        string
+		wantOutcome audit.Outcome
+		wantStatus  int
+	}{
+		{name: "own author", subject: "alice", body: `{"message":"hi","author":"alice"}`, wantStatus: http.StatusOK, wantOutcome: audit.OutcomeAllow},
+		{name: "other author", subject: "alice", body: `{"message":"hi","author":"bob"}`, wantStatus: http.StatusForbidden, wantOutcome: audit.OutcomeDeny},
+		{name: "not json", subject: "alice", body: `hello`, wantStatus: http.StatusForbidden, wantOutcome: audit.OutcomeDeny},
+		// The handler decodes case-insensitively and keeps the last key, so
+		// it would echo bob as the author.
+		{name: "case-variant author", subject: "alice", body: `{"message":"x","author":"alice","Author":"bob"}`, wantStatus: http.StatusBadRequest},
+		{name: "duplicate author", subject: "alice", body: `{"message":"x","author":"alice","author":"bob"}`, wantStatus: http.StatusBadRequest},
+		{name: "author in another case", subject: "alice", body: `{"message":"x","AUTHOR":"alice"}`, wantStatus: http.StatusOK, wantOutcome: audit.OutcomeAllow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditLog.Reset()
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/echo", strings.NewReader(tt.body))
+			req.Header.Set("X-Subject", tt.subject)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, tt.body, rec.Body.String())
+			}
+
+			if tt.wantOutcome == "" {
+				assert.Empty(t, auditLog.String(), "rejected before a decision")
+				return
+			}
+
+			var line struct {
+				Record audit.Record `json:"record"`
+			}
+			require.NoError(t, json.Unmarshal(auditLog.Bytes(), &line))
+			assert.Equal(t, audit.EventAuthDecision, line.Record.Type)
+			assert.Equal(t, tt.wantOutcome, line.Record.Outcome)
+			assert.Equal(t, tt.subject, line.Record.Actor)
+			assert.Equal(t, "echo", line.Record.Action)
+			assert.Equal(t, "POST /v1/echo", line.Record.Resource)
+		})
+	}
+}
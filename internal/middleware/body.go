@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// maxBufferedBodyBytes bounds how much of a request body middleware reads
+// to check it before the handler runs.
+const maxBufferedBodyBytes = 1 << 20
+
+var errBodyTooLarge = errors.New("request body too large")
+
+// bufferBody reads r's body, up to limit bytes, and replaces it with an
+// in-memory copy so the handler can read it again.
+func bufferBody(r *http.Request, limit int64) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, errBodyTooLarge
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// writeBodyError responds to a body bufferBody failed to read, either
+// because of its own limit or one RequestLimits set.
+func writeBodyError(w http.ResponseWriter, err error, logger *slog.Logger) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.Is(err, errBodyTooLarge) || errors.As(err, &maxBytesErr) {
+		writeErrorResponse(w, http.StatusRequestEntityTooLarge, "Request body too large", logger)
+		return
+	}
+	writeErrorResponse(w, http.StatusBadRequest, "Failed to read request body", logger)
+}
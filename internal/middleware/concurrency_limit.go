@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/savisec/hello-go/internal/concurrency"
+)
+
+// ConcurrencyLimit sheds requests over the adaptive concurrency limit with
+// a 503 and Retry-After. Requests to CriticalPaths, such as health checks,
+// are never shed. Requests that time out, ending in a 504 or past their
+// context's deadline, count as dropped, so the limit backs off when
+// handlers cannot keep up. Other 503s, such as authentication failing
+// while an identity provider is down, say nothing about this server's
+// load and are not counted.
+type ConcurrencyLimit struct {
+	Limiter       *concurrency.Limiter
+	Logger        *slog.Logger
+	CriticalPaths []string
+	RetryAfter    time.Duration
+}
+
+func NewConcurrencyLimit(limiter *concurrency.Limiter, criticalPaths []string, retryAfter time.Duration, logger *slog.Logger) *ConcurrencyLimit {
+	return &ConcurrencyLimit{
+		Limiter:       limiter,
+		Logger:        logger,
+		CriticalPaths: criticalPaths,
+		RetryAfter:    retryAfter,
+	}
+}
+
+func (cl *ConcurrencyLimit) ServeHTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := cl.Limiter.Acquire(r.Context(), cl.priority(r.URL.Path))
+		if !ok {
+			limit, inflight := cl.Limiter.Stats()
+			cl.Logger.WarnContext(r.Context(), "Request shed by concurrency limit",
+				"path", r.URL.Path, "limit", limit, "in_flight", inflight)
+			w.Header().Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(cl.RetryAfter))))
+			writeErrorResponse(w, http.StatusServiceUnavailable, "Server overloaded", cl.Logger)
+			return
+		}
+
+		ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		dropped := true
+		defer func() {
+			// A panic counts as dropped, too.
+			token.Release(dropped)
+		}()
+
+		next.ServeHTTP(ww, r)
+
+		dropped = ww.Status() == http.StatusGatewayTimeout || errors.Is(r.Context().Err(), context.DeadlineExceeded)
+	})
+}
+
+func (cl *ConcurrencyLimit) priority(path string) concurrency.Priority {
+	for _, critical := range cl.CriticalPaths {
+		if path == critical || (strings.HasSuffix(critical, "/") && strings.HasPrefix(path, critical)) {
+			return concurrency.PriorityCritical
+		}
+	}
+	return concurrency.PriorityNormal
+}
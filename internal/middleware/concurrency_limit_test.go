@@ -0,0 +1,84 @@
+package middleware_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/internal/concurrency"
+	"github.com/savisec/hello-go/internal/middleware"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	limiter := concurrency.NewLimiter(&concurrency.AIMD{Threshold: time.Minute, Backoff: 0.9}, 1, 1, 1)
+	cl := middleware.NewConcurrencyLimit(limiter, []string{"/healthz", "/admin/"}, 1500*time.Millisecond, slog.New(slog.DiscardHandler))
+
+	var inner http.Handler
+	handler := cl.ServeHTTP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if inner != nil {
+			inner.ServeHTTP(w, r)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	// While one request holds the only slot, see what others get.
+	results := map[string]*httptest.ResponseRecorder{}
+	inner = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		inner = nil
+		for _, path := range []string{"/v1/echo", "/healthz", "/admin/keys", "/healthz/deep"} {
+			results[path] = send(path)
+		}
+	})
+	require.Equal(t, http.StatusOK, send("/v1/echo").Code)
+
+	shed := results["/v1/echo"]
+	assert.Equal(t, http.StatusServiceUnavailable, shed.Code)
+	assert.Equal(t, "2", shed.Header().Get("Retry-After"))
+	assert.Contains(t, shed.Body.String(), "Server overloaded")
+
+	assert.Equal(t, http.StatusOK, results["/healthz"].Code)
+	assert.Equal(t, http.StatusOK, results["/admin/keys"].Code, "prefix match")
+	assert.Equal(t, http.StatusServiceUnavailable, results["/healthz/deep"].Code, "exact match")
+
+	_, inflight := limiter.Stats()
+	assert.Zero(t, inflight)
+	assert.Equal(t, http.StatusOK, send("/v1/echo").Code)
+}
+
+func TestConcurrencyLimit_CountsOnlyTimeoutsAsDropped(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		wantLimit int
+	}{
+		{name: "success", status: http.StatusOK, wantLimit: 10},
+		{name: "upstream outage", status: http.StatusServiceUnavailable, wantLimit: 10},
+		{name: "timeout", status: http.StatusGatewayTimeout, wantLimit: 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := concurrency.NewLimiter(&concurrency.AIMD{Threshold: time.Minute, Backoff: 0.9}, 10, 1, 10)
+			cl := middleware.NewConcurrencyLimit(limiter, nil, time.Second, slog.New(slog.DiscardHandler))
+			handler := cl.ServeHTTP(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/echo", nil))
+
+			limit, _ := limiter.Stats()
+			assert.Equal(t, tt.wantLimit, limit)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/savisec/hello-go/internal/cors"
+	"github.com/savisec/hello-go/internal/operation"
+)
+
+// CORS answers preflight requests and adds CORS headers to responses for
+// allowed origins, with the policy of the operation a request is for. It
+// must run before routing and authentication, because preflights carry no
+// credentials and are for methods the router does not serve themselves.
+type CORS struct {
+	Policies *cors.Policies
+	Resolver *operation.Resolver
+	Logger   *slog.Logger
+}
+
+func NewCORS(policies *cors.Policies, resolver *operation.Resolver, logger *slog.Logger) *CORS {
+	return &CORS{
+		Policies: policies,
+		Resolver: resolver,
+		Logger:   logger,
+	}
+}
+
+func (c *CORS) ServeHTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+
+		requestMethod := r.Header.Get("Access-Control-Request-Method")
+		if r.Method == http.MethodOptions && requestMethod != "" {
+			c.preflight(w, r, origin, requestMethod)
+			return
+		}
+
+		policy := c.policy(r, r.Method)
+		if policy.AllowsOrigin(origin) {
+			policy.Actual(h, origin)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// preflight answers a preflight request. Disallowed preflights get no CORS
+// headers, which the browser reports as a CORS failure.
+func (c *CORS) preflight(w http.ResponseWriter, r *http.Request, origin, requestMethod string) {
+	requestHeaders := r.Header.Get("Access-Control-Request-Headers")
+	policy := c.policy(r, requestMethod)
+
+	switch {
+	case !policy.AllowsOrigin(origin):
+		c.Logger.DebugContext(r.Context(), "CORS preflight rejected", "reason", "origin not allowed", "origin", origin, "path", r.URL.Path)
+	case !policy.AllowsMethod(requestMethod):
+		c.Logger.DebugContext(r.Context(), "CORS preflight rejected", "reason", "method not allowed", "origin", origin, "method", requestMethod)
+	case !policy.AllowsHeaders(requestHeaders):
+		c.Logger.DebugContext(r.Context(), "CORS preflight rejected", "reason", "headers not allowed", "origin", origin, "headers", requestHeaders)
+	default:
+		policy.Preflight(w.Header(), origin, requestHeaders)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// policy returns the policy of the operation r is, or asks to be, a
+// request for; requests outside the spec get the default policy.
+func (c *CORS) policy(r *http.Request, method string) *cors.Policy {
+	var operationID string
+	if op, ok := c.Resolver.ResolveAs(r, method); ok {
+		operationID = op.ID
+	}
+	return c.Policies.For(operationID)
+}
@@ -0,0 +1,125 @@
+package middleware_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/api"
+	"github.com/savisec/hello-go/internal/config"
+	"github.com/savisec/hello-go/internal/cors"
+	"github.com/savisec/hello-go/internal/middleware"
+	"github.com/savisec/hello-go/internal/operation"
+)
+
+func TestCORS(t *testing.T) {
+	policies, err := cors.New(config.CORSConfig{
+		Default: config.CORSPolicy{
+			AllowedOrigins: []string{"https://app.example.com"},
+			AllowedMethods: []string{"GET"},
+		},
+		Routes: map[string]config.CORSPolicy{
+			"echo": {
+				AllowedOrigins: []string{"https://app.example.com"},
+				AllowedMethods: []string{"POST"},
+				AllowedHeaders: []string{"Content-Type", "X-API-Key"},
+				ExposedHeaders: []string{"Retry-After"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	resolver, err := operation.NewResolver(api.OpenAPISpec)
+	require.NoError(t, err)
+
+	var reached bool
+	r := chi.NewRouter()
+	r.Use(middleware.NewCORS(policies, resolver, slog.New(slog.DiscardHandler)).ServeHTTP)
+	r.Post("/v1/echo", func(w http.ResponseWriter, _ *http.Request) {
+		reached = true
+		w.WriteHeader(http.StatusOK)
+	})
+	r.Get("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		reached = true
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		headers     map[string]string
+		name        string
+		method      string
+		path        string
+		wantOrigin  string
+		wantMethods string
+		wantExpose  string
+		wantStatus  int
+		wantReached bool
+	}{
+		{
+			name: "preflight", method: http.MethodOptions, path: "/v1/echo",
+			headers: map[string]string{
+				"Origin": "https://app.example.com", "Access-Control-Request-Method": "POST",
+				"Access-Control-Request-Headers": "content-type, x-api-key",
+			},
+			wantStatus: http.StatusNoContent, wantOrigin: "https://app.example.com", wantMethods: "POST",
+		},
+		{
+			name: "preflight from another origin", method: http.MethodOptions, path: "/v1/echo",
+			headers:    map[string]string{"Origin": "https://evil.test", "Access-Control-Request-Method": "POST"},
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name: "preflight for a disallowed header", method: http.MethodOptions, path: "/v1/echo",
+			headers: map[string]string{
+				"Origin": "https://app.example.com", "Access-Control-Request-Method": "POST",
+				"Access-Control-Request-Headers": "x-other",
+			},
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name: "preflight uses the default policy elsewhere", method: http.MethodOptions, path: "/healthz",
+			headers:    map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "GET"},
+			wantStatus: http.StatusNoContent, wantOrigin: "https://app.example.com", wantMethods: "GET",
+		},
+		{
+			name: "actual request", method: http.MethodPost, path: "/v1/echo",
+			headers:    map[string]string{"Origin": "https://app.example.com"},
+			wantStatus: http.StatusOK, wantOrigin: "https://app.example.com", wantExpose: "Retry-After", wantReached: true,
+		},
+		{
+			name: "actual request from another origin", method: http.MethodPost, path: "/v1/echo",
+			headers:    map[string]string{"Origin": "https://evil.test"},
+			wantStatus: http.StatusOK, wantReached: true,
+		},
+		{
+			name: "same origin", method: http.MethodPost, path: "/v1/echo",
+			wantStatus: http.StatusOK, wantReached: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reached = false
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.wantReached, reached)
+			assert.Equal(t, tt.wantOrigin, rec.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, tt.wantMethods, rec.Header().Get("Access-Control-Allow-Methods"))
+			assert.Equal(t, tt.wantExpose, rec.Header().Get("Access-Control-Expose-Headers"))
+			if tt.headers["Origin"] != "" {
+				assert.Contains(t, rec.Header().Values("Vary"), "Origin")
+			}
+		})
+	}
+}
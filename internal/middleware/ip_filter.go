@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"net/netip"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+
+	"github.com/savisec/hello-go/internal/ipfilter"
+	"github.com/savisec/hello-go/internal/lambdactx"
+)
+
+// meterName scopes the metrics of this package's middleware.
+const meterName = "github.com/savisec/hello-go/internal/middleware"
+
+// IPFilter rejects requests from client addresses its rule denies with a
+// 403, and logs and counts them. The client address is the one lambdactx
+// resolved: from the event in Lambda, and from RealIP's trusted-proxy
+// resolution behind the HTTP server.
+type IPFilter struct {
+	Denied metric.Int64Counter
+	Logger *slog.Logger
+	Group  string
+	Rule   ipfilter.Rule
+}
+
+func NewIPFilter(group string, rule ipfilter.Rule, logger *slog.Logger) *IPFilter {
+	denied, err := otel.Meter(meterName).Int64Counter("http.server.ip_filter.denied",
+		metric.WithUnit("{request}"),
+		metric.WithDescription("Requests rejected by an IP allow or deny list"),
+	)
+	if err != nil {
+		otel.Handle(err)
+		denied, _ = noop.Meter{}.Int64Counter("")
+	}
+
+	return &IPFilter{
+		Denied: denied,
+		Logger: logger,
+		Group:  group,
+		Rule:   rule,
+	}
+}
+
+func (f *IPFilter) ServeHTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := lambdactx.FromRequest(r)
+		// An unparsable address is the zero Addr, which Check handles.
+		addr, _ := netip.ParseAddr(rc.SourceIP)
+
+		if ok, reason := f.Rule.Check(addr); !ok {
+			f.Logger.WarnContext(r.Context(), "Request denied by IP filter",
+				"group", f.Group, "reason", reason, "source_ip", rc.SourceIP, "path", r.URL.Path)
+			f.Denied.Add(r.Context(), 1, metric.WithAttributes(attribute.String("group", f.Group)))
+			writeErrorResponse(w, http.StatusForbidden, "Forbidden", f.Logger)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
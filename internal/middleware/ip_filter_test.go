@@ -0,0 +1,60 @@
+package middleware_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/savisec/hello-go/internal/ipfilter"
+	"github.com/savisec/hello-go/internal/lambdactx"
+	"github.com/savisec/hello-go/internal/middleware"
+)
+
+func TestIPFilter(t *testing.T) {
+	partners := ipfilter.NewList("partners", []netip.Prefix{netip.MustParsePrefix("203.0.113.0/24")})
+
+	var logs bytes.Buffer
+	filter := middleware.NewIPFilter("api", ipfilter.Rule{Allow: []*ipfilter.List{partners}}, slog.New(slog.NewTextHandler(&logs, nil)))
+
+	handler := middleware.NewRealIP([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}).ServeHTTP(
+		lambdactx.Middleware(filter.ServeHTTP(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))),
+	)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		wantStatus int
+	}{
+		{name: "partner", remoteAddr: "203.0.113.5:1234", wantStatus: http.StatusOK},
+		{name: "partner behind a trusted proxy", remoteAddr: "10.0.0.2:1234", forwarded: "203.0.113.5", wantStatus: http.StatusOK},
+		{name: "stranger", remoteAddr: "198.51.100.1:1234", wantStatus: http.StatusForbidden},
+		{name: "stranger claiming to be a partner", remoteAddr: "198.51.100.1:1234", forwarded: "203.0.113.5", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/v1/echo", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus == http.StatusForbidden {
+				assert.Contains(t, logs.String(), "Request denied by IP filter")
+				assert.Contains(t, logs.String(), "group=api")
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/savisec/hello-go/internal/lambdactx"
+	"github.com/savisec/hello-go/internal/operation"
+	"github.com/savisec/hello-go/internal/ratelimit"
+)
+
+// RateLimit throttles each client to the token bucket of the operation it
+// calls, and reports the bucket in RateLimit-* headers. It must run after
+// Authentication so that clients can be told apart by key or principal.
+// Clients are counted per tenant when Tenancy runs first. If the backend
+// fails, requests are let through.
+type RateLimit struct {
+	Limiter *ratelimit.Limiter
+	Logger  *slog.Logger
+}
+
+func NewRateLimit(limiter *ratelimit.Limiter, logger *slog.Logger) *RateLimit {
+	return &RateLimit{
+		Limiter: limiter,
+		Logger:  logger,
+	}
+}
+
+func (rl *RateLimit) ServeHTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var operationID string
+		if op, ok := operation.FromContext(r.Context()); ok {
+			operationID = op.ID
+		}
+
+		rc := lambdactx.FromRequest(r)
+		client := rl.Limiter.ClientKey(rc.Identity, rc.SourceIP)
+
+		res, rule, err := rl.Limiter.Take(r.Context(), rc.Tenant, operationID, client)
+		if err != nil {
+			rl.Logger.ErrorContext(r.Context(), "Rate limiting unavailable, allowing request", "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(rule.Burst))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+		h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d", rule.Requests, ceilSeconds(rule.Period), rule.Burst))
+
+		if !res.Allowed {
+			rl.Logger.DebugContext(r.Context(), "Request rate limited", "client", client, "operation", operationID)
+			h.Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(res.RetryAfter))))
+			writeErrorResponse(w, http.StatusTooManyRequests, "Too many requests", rl.Logger)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ceilSeconds rounds d up to whole seconds, as the headers require.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,69 @@
+package middleware_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/api"
+	"github.com/savisec/hello-go/internal/config"
+	"github.com/savisec/hello-go/internal/lambdactx"
+	"github.com/savisec/hello-go/internal/middleware"
+	"github.com/savisec/hello-go/internal/operation"
+	"github.com/savisec/hello-go/internal/ratelimit"
+)
+
+func TestRateLimit(t *testing.T) {
+	limiter, err := ratelimit.NewLimiter(config.RateLimitConfig{
+		Default:    config.RateLimitRule{Requests: 600, Period: time.Minute, Burst: 100},
+		Operations: map[string]config.RateLimitRule{"echo": {Requests: 60, Period: time.Minute, Burst: 2}},
+	}, ratelimit.NewMemoryStore())
+	require.NoError(t, err)
+
+	resolver, err := operation.NewResolver(api.OpenAPISpec)
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Use(lambdactx.Middleware)
+	r.Use(operation.Middleware(resolver))
+	r.Use(middleware.NewRateLimit(limiter, slog.New(slog.DiscardHandler)).ServeHTTP)
+	r.Post("/v1/echo", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	send := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/echo", strings.NewReader(`{}`))
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send("192.0.2.1:1234")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "60;w=60;burst=2", rec.Header().Get("RateLimit-Policy"))
+	assert.Empty(t, rec.Header().Get("Retry-After"))
+
+	rec = send("192.0.2.1:1234")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+
+	rec = send("192.0.2.1:1234")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	assert.Contains(t, rec.Body.String(), "Too many requests")
+
+	rec = send("192.0.2.2:1234")
+	assert.Equal(t, http.StatusOK, rec.Code, "clients are limited separately")
+}
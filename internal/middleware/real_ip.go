@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/savisec/hello-go/internal/ipfilter"
+)
+
+// RealIP sets r.RemoteAddr to the client address, and r.Host to the
+// host, reported by trusted proxies. X-Forwarded-For is read from the
+// right, skipping trusted proxies, so that addresses a client prepends
+// itself are never believed; X-Real-IP is used when there is no
+// X-Forwarded-For. X-Forwarded-Host is read the same way, taking the value
+// the nearest proxy added. Requests that did not come from a trusted proxy
+// keep their own address and host, and their X-Forwarded-Host is dropped
+// so that nothing later believes it.
+type RealIP struct {
+	TrustedProxies []netip.Prefix
+}
+
+func NewRealIP(trustedProxies []netip.Prefix) *RealIP {
+	return &RealIP{
+		TrustedProxies: trustedProxies,
+	}
+}
+
+func (ri *RealIP) ServeHTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ri.fromTrustedProxy(r) {
+			r.Header.Del("X-Forwarded-Host")
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if ip, ok := ri.clientIP(r); ok {
+			r.RemoteAddr = ip
+		}
+		if host, ok := forwardedHost(r); ok {
+			r.Host = host
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (ri *RealIP) fromTrustedProxy(r *http.Request) bool {
+	host := r.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	peer, err := netip.ParseAddr(host)
+	return err == nil && ipfilter.Contains(ri.TrustedProxies, peer)
+}
+
+// forwardedHost returns the last X-Forwarded-Host value, the one the
+// nearest proxy added.
+func forwardedHost(r *http.Request) (string, bool) {
+	values := r.Header.Values("X-Forwarded-Host")
+	if len(values) == 0 {
+		return "", false
+	}
+	hosts := strings.Split(values[len(values)-1], ",")
+	host := strings.TrimSpace(hosts[len(hosts)-1])
+	return host, host != ""
+}
+
+func (ri *RealIP) clientIP(r *http.Request) (string, bool) {
+
+	var hops []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		for hop := range strings.SplitSeq(value, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	if len(hops) == 0 {
+		if realIP, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+			return realIP.Unmap().String(), true
+		}
+		return "", false
+	}
+
+	client := ""
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(hops[i])
+		if err != nil {
+			// Whatever is left of a malformed hop cannot be trusted.
+			break
+		}
+		client = addr.Unmap().String()
+		if !ipfilter.Contains(ri.TrustedProxies, addr) {
+			break
+		}
+	}
+	return client, client != ""
+}
@@ -0,0 +1,92 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/savisec/hello-go/internal/middleware"
+)
+
+func TestRealIP(t *testing.T) {
+	ri := middleware.NewRealIP([]netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("fd00::/8"),
+	})
+
+	tests := []struct {
+		headers    map[string]string
+		name       string
+		remoteAddr string
+		want       string
+		wantHost   string
+	}{
+		{name: "direct client", remoteAddr: "198.51.100.1:1234", want: "198.51.100.1:1234"},
+		{
+			name: "untrusted peer cannot forward", remoteAddr: "198.51.100.1:1234", want: "198.51.100.1:1234",
+			headers: map[string]string{"X-Forwarded-For": "203.0.113.9"},
+		},
+		{
+			name: "trusted proxy", remoteAddr: "10.0.0.2:1234", want: "203.0.113.9",
+			headers: map[string]string{"X-Forwarded-For": "203.0.113.9"},
+		},
+		{
+			name: "spoofed hops are skipped", remoteAddr: "10.0.0.2:1234", want: "203.0.113.9",
+			headers: map[string]string{"X-Forwarded-For": "1.2.3.4, 203.0.113.9, 10.0.0.7"},
+		},
+		{
+			name: "ipv6 proxy", remoteAddr: "[fd00::2]:1234", want: "2001:db8::9",
+			headers: map[string]string{"X-Forwarded-For": "2001:db8::9"},
+		},
+		{
+			name: "x-real-ip", remoteAddr: "10.0.0.2:1234", want: "203.0.113.9",
+			headers: map[string]string{"X-Real-IP": "203.0.113.9"},
+		},
+		{
+			name: "malformed hop", remoteAddr: "10.0.0.2:1234", want: "10.0.0.2:1234",
+			headers: map[string]string{"X-Forwarded-For": "not-an-ip"},
+		},
+		{
+			name: "forwarded host from trusted proxy", remoteAddr: "10.0.0.2:1234", want: "10.0.0.2:1234",
+			headers: map[string]string{"X-Forwarded-Host": "team-a.api.example.com"}, wantHost: "team-a.api.example.com",
+		},
+		{
+			name: "forwarded host of the nearest proxy", remoteAddr: "10.0.0.2:1234", want: "10.0.0.2:1234",
+			headers: map[string]string{"X-Forwarded-Host": "team-b.api.example.com, team-a.api.example.com"}, wantHost: "team-a.api.example.com",
+		},
+		{
+			name: "untrusted peer cannot forward host", remoteAddr: "198.51.100.1:1234", want: "198.51.100.1:1234",
+			headers: map[string]string{"X-Forwarded-Host": "team-a.api.example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got, gotHost, gotForwardedHost string
+			handler := ri.ServeHTTP(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+				gotHost = r.Host
+				gotForwardedHost = r.Header.Get("X-Forwarded-Host")
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tt.want, got)
+			if tt.wantHost == "" {
+				tt.wantHost = "example.com"
+			}
+			assert.Equal(t, tt.wantHost, gotHost)
+			if gotHost == "example.com" {
+				assert.Empty(t, gotForwardedHost, "forwarded hosts not believed are dropped")
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+
+	"github.com/savisec/hello-go/internal/config"
+	"github.com/savisec/hello-go/internal/operation"
+)
+
+// RequestLimits rejects requests with too many or too large headers, with
+// bodies larger than the operation allows, or, when strict, with bodies of
+// a Content-Type the operation does not declare in api/openapi.yml. Bodies
+// of unknown length are cut off at the limit as they are read, which
+// handlers see as an *http.MaxBytesError.
+type RequestLimits struct {
+	Resolver *operation.Resolver
+	Logger   *slog.Logger
+	Config   config.RequestLimitsConfig
+}
+
+func NewRequestLimits(cfg config.RequestLimitsConfig, resolver *operation.Resolver, logger *slog.Logger) *RequestLimits {
+	return &RequestLimits{
+		Resolver: resolver,
+		Logger:   logger,
+		Config:   cfg,
+	}
+}
+
+func (rl *RequestLimits) ServeHTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var count, size int
+		for name, values := range r.Header {
+			count += len(values)
+			for _, value := range values {
+				size += len(name) + len(value)
+			}
+		}
+		if rl.Config.MaxHeaderCount > 0 && count > rl.Config.MaxHeaderCount {
+			rl.reject(w, r, http.StatusRequestHeaderFieldsTooLarge, "Too many request headers", "header_count", count)
+			return
+		}
+		if rl.Config.MaxHeaderBytes > 0 && size > rl.Config.MaxHeaderBytes {
+			rl.reject(w, r, http.StatusRequestHeaderFieldsTooLarge, "Request headers too large", "header_bytes", size)
+			return
+		}
+
+		op, _ := rl.Resolver.Resolve(r)
+
+		limit := rl.Config.MaxBodyBytes
+		if op != nil {
+			if ol, ok := rl.Config.Operations[op.ID]; ok && ol.MaxBodyBytes > 0 {
+				limit = ol.MaxBodyBytes
+			}
+		}
+		if limit > 0 && r.Body != nil {
+			if r.ContentLength > limit {
+				rl.reject(w, r, http.StatusRequestEntityTooLarge, "Request body too large", "content_length", r.ContentLength)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+
+		// ContentLength is -1 when the length is unknown, which may still
+		// be a body.
+		if rl.Config.StrictContentType && op != nil && len(op.ContentTypes) > 0 && r.ContentLength != 0 {
+			contentType := r.Header.Get("Content-Type")
+			mediaType, _, err := mime.ParseMediaType(contentType)
+			if err != nil || !slices.Contains(op.ContentTypes, mediaType) {
+				rl.reject(w, r, http.StatusUnsupportedMediaType, "Unsupported content type", "content_type", contentType)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (rl *RequestLimits) reject(w http.ResponseWriter, r *http.Request, status int, message string, attrs ...any) {
+	rl.Logger.WarnContext(r.Context(), "Request rejected by limits",
+		append([]any{"reason", message, "path", r.URL.Path}, attrs...)...)
+	writeErrorResponse(w, status, message, rl.Logger)
+}
@@ -0,0 +1,94 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/api"
+	"github.com/savisec/hello-go/internal/config"
+	"github.com/savisec/hello-go/internal/middleware"
+	"github.com/savisec/hello-go/internal/operation"
+)
+
+// unknownLength hides the length of a body, as a chunked request would.
+type unknownLength struct{ io.Reader }
+
+func TestRequestLimits(t *testing.T) {
+	resolver, err := operation.NewResolver(api.OpenAPISpec)
+	require.NoError(t, err)
+
+	rl := middleware.NewRequestLimits(config.RequestLimitsConfig{
+		MaxBodyBytes:      1024,
+		MaxHeaderCount:    5,
+		MaxHeaderBytes:    256,
+		StrictContentType: true,
+		Operations: map[string]config.OperationLimitsConfig{
+			"echo": {MaxBodyBytes: 32},
+		},
+	}, resolver, slog.New(slog.DiscardHandler))
+
+	handler := rl.ServeHTTP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var v any
+		if err := json.NewDecoder(r.Body).Decode(&v); err != nil && err != io.EOF {
+			var maxBytesErr *http.MaxBytesError
+			assert.ErrorAs(t, err, &maxBytesErr)
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	small := `{"message":"hi","author":"a"}`
+	large := `{"message":"` + strings.Repeat("a", 64) + `"}`
+
+	tests := []struct {
+		body        io.Reader
+		headers     map[string]string
+		name        string
+		method      string
+		path        string
+		contentType string
+		wantStatus  int
+	}{
+		{name: "within limits", method: http.MethodPost, path: "/v1/echo", body: strings.NewReader(small), contentType: "application/json", wantStatus: http.StatusOK},
+		{name: "content type parameters", method: http.MethodPost, path: "/v1/echo", body: strings.NewReader(small), contentType: "application/json; charset=utf-8", wantStatus: http.StatusOK},
+		{name: "operation body limit", method: http.MethodPost, path: "/v1/echo", body: strings.NewReader(large), contentType: "application/json", wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "operation body limit, unknown length", method: http.MethodPost, path: "/v1/echo", body: unknownLength{strings.NewReader(large)}, contentType: "application/json", wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "default body limit", method: http.MethodPost, path: "/other", body: strings.NewReader(strings.Repeat("a", 2048)), wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "wrong content type", method: http.MethodPost, path: "/v1/echo", body: strings.NewReader(small), contentType: "text/plain", wantStatus: http.StatusUnsupportedMediaType},
+		{name: "missing content type", method: http.MethodPost, path: "/v1/echo", body: strings.NewReader(small), wantStatus: http.StatusUnsupportedMediaType},
+		{name: "no body needs no content type", method: http.MethodGet, path: "/healthz", wantStatus: http.StatusOK},
+		{
+			name: "too many headers", method: http.MethodGet, path: "/healthz", wantStatus: http.StatusRequestHeaderFieldsTooLarge,
+			headers: map[string]string{"A": "1", "B": "2", "C": "3", "D": "4", "E": "5", "F": "6"},
+		},
+		{
+			name: "headers too large", method: http.MethodGet, path: "/healthz", wantStatus: http.StatusRequestHeaderFieldsTooLarge,
+			headers: map[string]string{"X-Large": strings.Repeat("a", 300)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, tt.body)
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+		})
+	}
+}
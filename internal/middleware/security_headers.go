@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/felixge/httpsnoop"
+
+	"github.com/savisec/hello-go/internal/config"
+)
+
+// SecurityHeaders sets security headers on every response and strips
+// headers that fingerprint the server. Handlers may override the headers
+// it sets, such as a docs UI relaxing the Content-Security-Policy.
+type SecurityHeaders struct {
+	Headers http.Header
+	Strip   []string
+}
+
+func NewSecurityHeaders(cfg config.SecurityHeadersConfig) *SecurityHeaders {
+	headers := http.Header{}
+	if cfg.HSTS.MaxAge > 0 {
+		hsts := fmt.Sprintf("max-age=%d", int(cfg.HSTS.MaxAge.Seconds()))
+		if cfg.HSTS.IncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if cfg.HSTS.Preload {
+			hsts += "; preload"
+		}
+		headers.Set("Strict-Transport-Security", hsts)
+	}
+	if cfg.NoSniff {
+		headers.Set("X-Content-Type-Options", "nosniff")
+	}
+	if cfg.ContentSecurityPolicy != "" {
+		headers.Set("Content-Security-Policy", cfg.ContentSecurityPolicy)
+	}
+	if cfg.ReferrerPolicy != "" {
+		headers.Set("Referrer-Policy", cfg.ReferrerPolicy)
+	}
+
+	return &SecurityHeaders{
+		Headers: headers,
+		Strip:   cfg.StripHeaders,
+	}
+}
+
+func (sh *SecurityHeaders) ServeHTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		for name := range sh.Headers {
+			h.Set(name, sh.Headers.Get(name))
+		}
+		if len(sh.Strip) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Headers are stripped as the response is written, because
+		// whatever sets them runs after this.
+		var stripped bool
+		strip := func() {
+			if stripped {
+				return
+			}
+			stripped = true
+			for _, name := range sh.Strip {
+				h.Del(name)
+			}
+		}
+
+		next.ServeHTTP(httpsnoop.Wrap(w, httpsnoop.Hooks{
+			WriteHeader: func(next httpsnoop.WriteHeaderFunc) httpsnoop.WriteHeaderFunc {
+				return func(code int) {
+					strip()
+					next(code)
+				}
+			},
+			Write: func(next httpsnoop.WriteFunc) httpsnoop.WriteFunc {
+				return func(b []byte) (int, error) {
+					strip()
+					return next(b)
+				}
+			},
+			ReadFrom: func(next httpsnoop.ReadFromFunc) httpsnoop.ReadFromFunc {
+				return func(src io.Reader) (int64, error) {
+					strip()
+					return next(src)
+				}
+			},
+			Flush: func(next httpsnoop.FlushFunc) httpsnoop.FlushFunc {
+				return func() {
+					strip()
+					next()
+				}
+			},
+		}), r)
+		strip()
+	})
+}
@@ -0,0 +1,71 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/savisec/hello-go/internal/config"
+	"github.com/savisec/hello-go/internal/middleware"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	sh := middleware.NewSecurityHeaders(config.SecurityHeadersConfig{
+		HSTS:                  config.HSTSConfig{MaxAge: 365 * 24 * time.Hour, IncludeSubdomains: true},
+		NoSniff:               true,
+		ContentSecurityPolicy: "default-src 'none'",
+		ReferrerPolicy:        "no-referrer",
+		StripHeaders:          []string{"Server", "X-Powered-By"},
+	})
+
+	tests := []struct {
+		handler http.HandlerFunc
+		name    string
+		wantCSP string
+	}{
+		{
+			name: "write",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Server", "hello-go/1.0")
+				w.Header().Set("X-Powered-By", "Go")
+				_, _ = w.Write([]byte("{}"))
+			},
+			wantCSP: "default-src 'none'",
+		},
+		{
+			name: "write header",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Server", "hello-go/1.0")
+				w.WriteHeader(http.StatusNoContent)
+			},
+			wantCSP: "default-src 'none'",
+		},
+		{
+			name: "handler overrides csp",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Security-Policy", "default-src 'self'")
+				w.Header().Set("Content-Type", "text/html")
+				_, _ = w.Write([]byte("<html></html>"))
+			},
+			wantCSP: "default-src 'self'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			sh.ServeHTTP(tt.handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			h := rec.Header()
+			assert.Equal(t, "max-age=31536000; includeSubDomains", h.Get("Strict-Transport-Security"))
+			assert.Equal(t, "nosniff", h.Get("X-Content-Type-Options"))
+			assert.Equal(t, "no-referrer", h.Get("Referrer-Policy"))
+			assert.Equal(t, tt.wantCSP, h.Get("Content-Security-Policy"))
+			assert.Empty(t, h.Get("Server"))
+			assert.Empty(t, h.Get("X-Powered-By"))
+		})
+	}
+}
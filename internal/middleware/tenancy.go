@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/savisec/hello-go/internal/lambdactx"
+	"github.com/savisec/hello-go/internal/operation"
+	"github.com/savisec/hello-go/internal/tenancy"
+)
+
+// Tenancy resolves the tenant of each request and stores it in the request
+// context, and records it on the request's logs, span and metrics. It
+// rejects requests for no or an unknown tenant, requests whose header or
+// subdomain contradicts their credentials, and operations the tenant has
+// switched off. It must run after Authentication so that the tenant of the
+// caller's credentials is known.
+type Tenancy struct {
+	Resolver *tenancy.Resolver
+	Logger   *slog.Logger
+}
+
+func NewTenancy(resolver *tenancy.Resolver, logger *slog.Logger) *Tenancy {
+	return &Tenancy{
+		Resolver: resolver,
+		Logger:   logger,
+	}
+}
+
+func (tn *Tenancy) ServeHTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t, source, err := tn.Resolver.Resolve(r, lambdactx.FromRequest(r))
+		if err != nil {
+			tn.Logger.WarnContext(r.Context(), "Request rejected by tenancy", "source", source, "error", err, "path", r.URL.Path)
+			if errors.Is(err, tenancy.ErrNoTenant) {
+				writeErrorResponse(w, http.StatusBadRequest, "Missing tenant", tn.Logger)
+				return
+			}
+			writeErrorResponse(w, http.StatusForbidden, "Forbidden", tn.Logger)
+			return
+		}
+
+		ctx := lambdactx.WithTenant(tenancy.NewContext(r.Context(), t), t.ID)
+		attr := attribute.String("tenant.id", t.ID)
+		trace.SpanFromContext(ctx).SetAttributes(attr)
+		if labeler, ok := otelhttp.LabelerFromContext(ctx); ok {
+			labeler.Add(attr)
+		}
+
+		if op, ok := operation.FromContext(ctx); ok && !t.Enabled(op.ID) {
+			tn.Logger.WarnContext(ctx, "Operation disabled for tenant", "operation", op.ID)
+			writeErrorResponse(w, http.StatusForbidden, "Forbidden", tn.Logger)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,72 @@
+package middleware_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/api"
+	"github.com/savisec/hello-go/internal/config"
+	"github.com/savisec/hello-go/internal/lambdactx"
+	"github.com/savisec/hello-go/internal/middleware"
+	"github.com/savisec/hello-go/internal/operation"
+	"github.com/savisec/hello-go/internal/tenancy"
+)
+
+func TestTenancy(t *testing.T) {
+	resolver, err := tenancy.NewResolver(config.TenancyConfig{
+		Tenants: map[string]config.TenantConfig{
+			"team-a": {},
+			"team-b": {Features: map[string]bool{"echo": false}},
+		},
+	}, false)
+	require.NoError(t, err)
+
+	ops, err := operation.NewResolver(api.OpenAPISpec)
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Use(lambdactx.Middleware)
+	r.Use(operation.Middleware(ops))
+	r.Use(middleware.NewTenancy(resolver, slog.New(slog.DiscardHandler)).ServeHTTP)
+	r.Post("/v1/echo", func(w http.ResponseWriter, r *http.Request) {
+		tenant, ok := tenancy.FromContext(r.Context())
+		require.True(t, ok)
+		assert.Equal(t, tenant.ID, lambdactx.FromRequest(r).Tenant)
+		_, _ = w.Write([]byte(tenant.ID))
+	})
+
+	tests := []struct {
+		name       string
+		tenant     string
+		wantBody   string
+		wantStatus int
+	}{
+		{name: "known tenant", tenant: "team-a", wantStatus: http.StatusOK, wantBody: "team-a"},
+		{name: "feature disabled", tenant: "team-b", wantStatus: http.StatusForbidden},
+		{name: "unknown tenant", tenant: "team-z", wantStatus: http.StatusForbidden},
+		{name: "no tenant", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/echo", strings.NewReader(`{}`))
+			if tt.tenant != "" {
+				req.Header.Set("X-Tenant-ID", tt.tenant)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantBody != "" {
+				assert.Equal(t, tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+// Package operation resolves HTTP requests to the OpenAPI operations that
+// describe them, so that middleware can apply per-operation policy declared
+// in api/openapi.yml.
+package operation
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+)
+
+// Requirement is one alternative of an operation's security requirements.
+type Requirement struct {
+	// Schemes names the security schemes that carry the credential.
+	Schemes []string
+	// Scopes are the scopes the credential must grant.
+	Scopes []string
+}
+
+// Operation is the part of an OpenAPI operation middleware cares about.
+type Operation struct {
+	ID     string
+	Method string
+	// Path is the path template, e.g. /v1/echo.
+	Path string
+	// ContentTypes are the media types the request body may have; it is
+	// empty for operations without one.
+	ContentTypes []string
+	// Security lists alternative requirements; meeting any one of them
+	// grants access. It is empty for public operations.
+	Security []Requirement
+}
+
+// Public reports whether the operation requires no credentials.
+func (o *Operation) Public() bool {
+	return len(o.Security) == 0
+}
+
+// Resolver matches requests against the operations in an OpenAPI spec.
+type Resolver struct {
+	router     routers.Router
+	operations map[*openapi3.Operation]*Operation
+}
+
+// NewResolver creates a Resolver for the OpenAPI spec in YAML or JSON.
+func NewResolver(spec []byte) (*Resolver, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OpenAPI spec: %w", err)
+	}
+
+	// The server URLs in the spec are for clients; match on paths alone so
+	// that requests resolve whatever host they were sent to.
+	doc.Servers = nil
+
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build OpenAPI router: %w", err)
+	}
+
+	operations := make(map[*openapi3.Operation]*Operation)
+	for path, item := range doc.Paths.Map() {
+		for method, op := range item.Operations() {
+			security := doc.Security
+			if op.Security != nil {
+				security = *op.Security
+			}
+
+			var contentTypes []string
+			if op.RequestBody != nil && op.RequestBody.Value != nil {
+				contentTypes = slices.Sorted(maps.Keys(op.RequestBody.Value.Content))
+			}
+
+			operations[op] = &Operation{
+				ID:           op.OperationID,
+				Method:       method,
+				Path:         path,
+				ContentTypes: contentTypes,
+				Security:     requirements(security),
+			}
+		}
+	}
+
+	return &Resolver{router: router, operations: operations}, nil
+}
+
+func requirements(security openapi3.SecurityRequirements) []Requirement {
+	reqs := make([]Requirement, 0, len(security))
+	for _, sr := range security {
+		var req Requirement
+		for scheme, scopes := range sr {
+			req.Schemes = append(req.Schemes, scheme)
+			for _, scope := range scopes {
+				if !slices.Contains(req.Scopes, scope) {
+					req.Scopes = append(req.Scopes, scope)
+				}
+			}
+		}
+		// An empty requirement object makes the operation optionally public.
+		if len(req.Schemes) == 0 {
+			return nil
+		}
+		slices.Sort(req.Schemes)
+		slices.Sort(req.Scopes)
+		reqs = append(reqs, req)
+	}
+	return reqs
+}
+
+// Lookup returns the operation with the given ID.
+func (res *Resolver) Lookup(id string) (*Operation, bool) {
+	for _, op := range res.operations {
+		if op.ID == id {
+			return op, true
+		}
+	}
+	return nil, false
+}
+
+// Resolve returns the operation r is a request for.
+func (res *Resolver) Resolve(r *http.Request) (*Operation, bool) {
+	route, _, err := res.router.FindRoute(r)
+	if err != nil {
+		return nil, false
+	}
+
+	op, ok := res.operations[route.Operation]
+	return op, ok
+}
+
+// ResolveAs returns the operation r would be a request for if it used
+// method, such as the method a CORS preflight asks about.
+func (res *Resolver) ResolveAs(r *http.Request, method string) (*Operation, bool) {
+	as := *r
+	as.Method = method
+	return res.Resolve(&as)
+}
+
+type ctxKey struct{}
+
+// NewContext returns a copy of ctx carrying op.
+func NewContext(ctx context.Context, op *Operation) context.Context {
+	return context.WithValue(ctx, ctxKey{}, op)
+}
+
+// FromContext returns the operation stored in ctx by Middleware.
+func FromContext(ctx context.Context) (*Operation, bool) {
+	op, ok := ctx.Value(ctxKey{}).(*Operation)
+	return op, ok
+}
+
+// Middleware stores the operation each request resolves to in its context.
+// Requests that match no operation pass through without one.
+func Middleware(resolver *Resolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if op, ok := resolver.Resolve(r); ok {
+				r = r.WithContext(NewContext(r.Context(), op))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package operation_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/api"
+	"github.com/savisec/hello-go/internal/operation"
+)
+
+func TestResolver_Resolve(t *testing.T) {
+	resolver, err := operation.NewResolver(api.OpenAPISpec)
+	require.NoError(t, err)
+
+	tests := []struct {
+		want   *operation.Operation
+		name   string
+		method string
+		target string
+	}{
+		{
+			name:   "public operation",
+			method: http.MethodGet,
+			target: "http://example.com/healthz",
+			want:   &operation.Operation{ID: "healthz", Method: http.MethodGet, Path: "/healthz", Security: []operation.Requirement{}},
+		},
+		{
+			name:   "operation with scopes on any host",
+			method: http.MethodPost,
+			target: "https://api.example.com/v1/echo",
+			want: &operation.Operation{ID: "echo", Method: http.MethodPost, Path: "/v1/echo", ContentTypes: []string{"application/json"}, Security: []operation.Requirement{
+				{Schemes: []string{"bearerAuth"}, Scopes: []string{"echo:write"}},
+				{Schemes: []string{"apiKeyAuth"}, Scopes: []string{"echo:write"}},
+				{Schemes: []string{"hmacSignature"}, Scopes: []string{"echo:write"}},
+			}},
+		},
+		{
+			name:   "unknown method",
+			method: http.MethodGet,
+			target: "/v1/echo",
+		},
+		{
+			name:   "unknown path",
+			method: http.MethodGet,
+			target: "/v2/echo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op, ok := resolver.Resolve(httptest.NewRequest(tt.method, tt.target, nil))
+
+			if tt.want == nil {
+				assert.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, tt.want, op)
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	resolver, err := operation.NewResolver(api.OpenAPISpec)
+	require.NoError(t, err)
+
+	var got *operation.Operation
+	handler := operation.Middleware(resolver)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = operation.FromContext(r.Context())
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	require.NotNil(t, got)
+	assert.Equal(t, "readyz", got.ID)
+	assert.True(t, got.Public())
+}
@@ -0,0 +1,294 @@
+// Package ratelimit implements per-client token bucket rate limiting with
+// buckets kept in memory or in a Redis-compatible server.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/savisec/hello-go/internal/config"
+	"github.com/savisec/hello-go/internal/lambdactx"
+)
+
+const (
+	backendMemory = "memory"
+	backendRedis  = "redis"
+
+	KeyAPIKey    = "api_key"
+	KeyPrincipal = "principal"
+	KeyIP        = "ip"
+
+	defaultKeyPrefix = "hello-go:ratelimit:"
+)
+
+// Limit is a token bucket: Rate tokens are added per second up to Burst,
+// and every request takes one.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Result reports the state of a bucket after a request took from it.
+type Result struct {
+	// Remaining is the number of whole tokens left.
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until a token is available; zero if allowed.
+	RetryAfter time.Duration
+	Allowed    bool
+}
+
+// Store keeps token buckets. Take removes a token from the bucket named
+// key, creating a full bucket if there is none. Peek reports whether Take
+// would be allowed without taking anything.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+	Peek(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// result derives a Result from the tokens left in a bucket.
+func result(limit Limit, tokens float64, allowed bool) Result {
+	r := Result{
+		Allowed:   allowed,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     secondsToDuration((float64(limit.Burst) - tokens) / limit.Rate),
+	}
+	if !allowed {
+		r.RetryAfter = secondsToDuration((1 - tokens) / limit.Rate)
+	}
+	return r
+}
+
+func secondsToDuration(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
+
+// Rule is the configured limit of an operation.
+type Rule struct {
+	Limit
+	// Requests and Period are the rule as configured, for the
+	// RateLimit-Policy header.
+	Requests int
+	Period   time.Duration
+}
+
+func newRule(cfg config.RateLimitRule) (Rule, error) {
+	if cfg.Requests <= 0 || cfg.Period <= 0 {
+		return Rule{}, errors.New("requests and period must be positive")
+	}
+	burst := cfg.Burst
+	if burst <= 0 {
+		burst = cfg.Requests
+	}
+	return Rule{
+		Limit:    Limit{Rate: float64(cfg.Requests) / cfg.Period.Seconds(), Burst: burst},
+		Requests: cfg.Requests,
+		Period:   cfg.Period,
+	}, nil
+}
+
+// rules are the per-operation rules and the default rule of a tenant.
+// A nil default falls back to the limiter's.
+type rules struct {
+	operations map[string]Rule
+	def        *Rule
+}
+
+// Limiter applies per-operation rules to clients.
+type Limiter struct {
+	store      Store
+	now        func() time.Time
+	operations map[string]Rule
+	tenants    map[string]rules
+	// failures limits failed authentication attempts per source IP; nil
+	// if not configured.
+	failures *Rule
+	keyBy    string
+	def      Rule
+}
+
+// NewLimiter creates a Limiter for the rules in cfg, keeping buckets in
+// store.
+func NewLimiter(cfg config.RateLimitConfig, store Store) (*Limiter, error) {
+	keyBy := strings.ToLower(cfg.Key)
+	switch keyBy {
+	case "":
+		keyBy = KeyAPIKey
+	case KeyAPIKey, KeyPrincipal, KeyIP:
+	default:
+		return nil, fmt.Errorf("unknown rate limit key %q", cfg.Key)
+	}
+
+	def, err := newRule(cfg.Default)
+	if err != nil {
+		return nil, fmt.Errorf("default rate limit: %w", err)
+	}
+
+	operations := make(map[string]Rule, len(cfg.Operations))
+	for id, rc := range cfg.Operations {
+		if operations[id], err = newRule(rc); err != nil {
+			return nil, fmt.Errorf("rate limit for %s: %w", id, err)
+		}
+	}
+
+	var failures *Rule
+	if cfg.AuthFailures.Requests > 0 {
+		rule, err := newRule(cfg.AuthFailures)
+		if err != nil {
+			return nil, fmt.Errorf("auth failure rate limit: %w", err)
+		}
+		failures = &rule
+	}
+
+	return &Limiter{
+		store:      store,
+		now:        time.Now,
+		operations: operations,
+		tenants:    map[string]rules{},
+		failures:   failures,
+		keyBy:      keyBy,
+		def:        def,
+	}, nil
+}
+
+// AddTenant overrides the rules of the tenant with those in cfg. Rules cfg
+// leaves zero fall back to the limiter's. It must be called before the
+// Limiter is used.
+func (l *Limiter) AddTenant(tenant string, cfg config.TenantRateLimitConfig) error {
+	var tr rules
+	if cfg.Default != (config.RateLimitRule{}) {
+		def, err := newRule(cfg.Default)
+		if err != nil {
+			return fmt.Errorf("default rate limit of tenant %s: %w", tenant, err)
+		}
+		tr.def = &def
+	}
+	tr.operations = make(map[string]Rule, len(cfg.Operations))
+	for id, rc := range cfg.Operations {
+		rule, err := newRule(rc)
+		if err != nil {
+			return fmt.Errorf("rate limit for %s of tenant %s: %w", id, tenant, err)
+		}
+		tr.operations[id] = rule
+	}
+	l.tenants[tenant] = tr
+	return nil
+}
+
+// New creates a Limiter with the backend cfg selects.
+func New(cfg config.RateLimitConfig) (*Limiter, error) {
+	var store Store
+	switch strings.ToLower(cfg.Backend) {
+	case "", backendMemory:
+		store = NewMemoryStore()
+	case backendRedis:
+		if cfg.Redis.Addr == "" {
+			return nil, errors.New("redis rate limit backend requires an addr")
+		}
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.Redis.Addr,
+			Username: cfg.Redis.Username,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		})
+		store = NewRedisStore(client, cfg.Redis.KeyPrefix)
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend %q", cfg.Backend)
+	}
+	return NewLimiter(cfg, store)
+}
+
+// ClientKey names who a request is counted against: its API key, its
+// principal or its source IP, as configured. Callers without an API key,
+// such as JWT callers, fall back to their principal, and unauthenticated
+// callers to their IP.
+func (l *Limiter) ClientKey(identity *lambdactx.Identity, sourceIP string) string {
+	if identity == nil || l.keyBy == KeyIP {
+		return "ip:" + sourceIP
+	}
+	if l.keyBy == KeyAPIKey {
+		// API keys and signing keys are separate namespaces.
+		if id := identity.Claims["key_id"]; id != "" {
+			return identity.Authorizer + ":" + id
+		}
+	}
+	return "principal:" + identity.Subject
+}
+
+// Take takes a token for client from the bucket of an operation. Operations
+// without a rule of their own share one default bucket per client. Each
+// tenant has buckets of its own, so clients of one tenant never use up
+// another's; the rule is the tenant's for the operation, else the
+// operation's, else the tenant's default, else the default.
+func (l *Limiter) Take(ctx context.Context, tenant, operationID, client string) (Result, Rule, error) {
+	rule, bucket := l.rule(tenant, operationID)
+
+	key := bucket + "|" + client
+	if tenant != "" {
+		key = "tenant:" + tenant + "|" + key
+	}
+	res, err := l.store.Take(ctx, key, rule.Limit, l.now())
+	if err != nil {
+		return Result{}, rule, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+	return res, rule, nil
+}
+
+// rule returns the rule for an operation of a tenant and the name of its
+// bucket.
+func (l *Limiter) rule(tenant, operationID string) (Rule, string) {
+	tr := l.tenants[tenant]
+	if rule, ok := tr.operations[operationID]; ok {
+		return rule, operationID
+	}
+	if rule, ok := l.operations[operationID]; ok {
+		return rule, operationID
+	}
+	if tr.def != nil {
+		return *tr.def, "*"
+	}
+	return l.def, "*"
+}
+
+// LimitsFailures reports whether failed authentication attempts are
+// limited.
+func (l *Limiter) LimitsFailures() bool {
+	return l.failures != nil
+}
+
+// CheckFailures reports whether the source IP may still attempt to
+// authenticate, without counting an attempt. It must only be called if
+// LimitsFailures.
+func (l *Limiter) CheckFailures(ctx context.Context, sourceIP string) (Result, error) {
+	res, err := l.store.Peek(ctx, failureKey(sourceIP), l.failures.Limit, l.now())
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to check auth failure rate limit: %w", err)
+	}
+	return res, nil
+}
+
+// TakeFailure counts a failed authentication attempt against the source
+// IP. It must only be called if LimitsFailures.
+func (l *Limiter) TakeFailure(ctx context.Context, sourceIP string) (Result, error) {
+	res, err := l.store.Take(ctx, failureKey(sourceIP), l.failures.Limit, l.now())
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to take auth failure rate limit token: %w", err)
+	}
+	return res, nil
+}
+
+// failureKey names the failed authentication bucket of a source IP, apart
+// from the buckets of operations.
+func failureKey(sourceIP string) string {
+	return "auth-failures|ip:" + sourceIP
+}
@@ -0,0 +1,230 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/internal/config"
+	"github.com/savisec/hello-go/internal/lambdactx"
+)
+
+func newRedisStore(t *testing.T) *RedisStore {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return NewRedisStore(client, "")
+}
+
+func TestStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(*testing.T) Store { return NewMemoryStore() },
+		"redis":  func(t *testing.T) Store { return newRedisStore(t) },
+	}
+
+	// One token a second, three at most.
+	limit := Limit{Rate: 1, Burst: 3}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newStore(t)
+			now := time.Unix(1_700_000_000, 0)
+
+			for want := 2; want >= 0; want-- {
+				res, err := store.Take(ctx, "c", limit, now)
+				require.NoError(t, err)
+				assert.True(t, res.Allowed)
+				assert.Equal(t, want, res.Remaining)
+			}
+
+			res, err := store.Take(ctx, "c", limit, now)
+			require.NoError(t, err)
+			assert.False(t, res.Allowed)
+			assert.Equal(t, 0, res.Remaining)
+			assert.Equal(t, time.Second, res.RetryAfter)
+			assert.Equal(t, 3*time.Second, res.Reset)
+
+			res, err = store.Take(ctx, "other", limit, now)
+			require.NoError(t, err)
+			assert.True(t, res.Allowed, "buckets are per key")
+
+			now = now.Add(1500 * time.Millisecond)
+			res, err = store.Take(ctx, "c", limit, now)
+			require.NoError(t, err)
+			assert.True(t, res.Allowed, "refilled")
+			assert.Equal(t, 0, res.Remaining)
+
+			res, err = store.Take(ctx, "c", limit, now)
+			require.NoError(t, err)
+			assert.False(t, res.Allowed)
+			assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+
+			now = now.Add(time.Hour)
+			res, err = store.Take(ctx, "c", limit, now)
+			require.NoError(t, err)
+			assert.Equal(t, 2, res.Remaining, "refills no higher than burst")
+
+			res, err = store.Peek(ctx, "c", limit, now)
+			require.NoError(t, err)
+			assert.True(t, res.Allowed)
+			assert.Equal(t, 2, res.Remaining, "peeking takes nothing")
+			res, err = store.Peek(ctx, "new", limit, now)
+			require.NoError(t, err)
+			assert.Equal(t, 3, res.Remaining, "unknown buckets are full")
+
+			for range 2 {
+				_, err = store.Take(ctx, "c", limit, now)
+				require.NoError(t, err)
+			}
+			res, err = store.Peek(ctx, "c", limit, now)
+			require.NoError(t, err)
+			assert.False(t, res.Allowed)
+			assert.Equal(t, time.Second, res.RetryAfter)
+		})
+	}
+}
+
+func TestMemoryStore_SweepsFullBuckets(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Unix(1_700_000_000, 0)
+	limit := Limit{Rate: 1, Burst: 1}
+
+	_, err := store.Take(context.Background(), "a", limit, now)
+	require.NoError(t, err)
+
+	_, err = store.Take(context.Background(), "b", limit, now.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.Len(t, store.buckets, 1)
+}
+
+func TestLimiter_ClientKey(t *testing.T) {
+	apiKey := &lambdactx.Identity{Subject: "alice", Authorizer: "api_key", Claims: map[string]string{"key_id": "k1"}}
+	jwt := &lambdactx.Identity{Subject: "bob", Authorizer: "jwt"}
+
+	tests := []struct {
+		identity *lambdactx.Identity
+		name     string
+		keyBy    string
+		want     string
+	}{
+		{name: "anonymous", keyBy: KeyAPIKey, want: "ip:192.0.2.1"},
+		{name: "api key", keyBy: KeyAPIKey, identity: apiKey, want: "api_key:k1"},
+		{name: "no key id", keyBy: KeyAPIKey, identity: jwt, want: "principal:bob"},
+		{name: "principal", keyBy: KeyPrincipal, identity: apiKey, want: "principal:alice"},
+		{name: "ip", keyBy: KeyIP, identity: apiKey, want: "ip:192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := NewLimiter(config.RateLimitConfig{
+				Key:     tt.keyBy,
+				Default: config.RateLimitRule{Requests: 1, Period: time.Second},
+			}, NewMemoryStore())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, l.ClientKey(tt.identity, "192.0.2.1"))
+		})
+	}
+}
+
+func TestLimiter_Take(t *testing.T) {
+	l, err := NewLimiter(config.RateLimitConfig{
+		Default:    config.RateLimitRule{Requests: 60, Period: time.Minute, Burst: 2},
+		Operations: map[string]config.RateLimitRule{"echo": {Requests: 1, Period: time.Minute}},
+	}, NewMemoryStore())
+	require.NoError(t, err)
+	l.now = func() time.Time { return time.Unix(1_700_000_000, 0) }
+
+	ctx := context.Background()
+
+	res, rule, err := l.Take(ctx, "", "echo", "c")
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, rule.Burst, "burst defaults to requests")
+
+	res, _, err = l.Take(ctx, "", "echo", "c")
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+
+	res, rule, err = l.Take(ctx, "", "healthz", "c")
+	require.NoError(t, err)
+	assert.True(t, res.Allowed, "other operations have their own bucket")
+	assert.Equal(t, 2, rule.Burst)
+
+	res, _, err = l.Take(ctx, "", "readyz", "c")
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	res, _, err = l.Take(ctx, "", "readyz", "c")
+	require.NoError(t, err)
+	assert.False(t, res.Allowed, "operations without a rule share the default bucket")
+}
+
+func TestLimiter_TakeTenants(t *testing.T) {
+	l, err := NewLimiter(config.RateLimitConfig{
+		Default:    config.RateLimitRule{Requests: 60, Period: time.Minute, Burst: 1},
+		Operations: map[string]config.RateLimitRule{"echo": {Requests: 1, Period: time.Minute}},
+	}, NewMemoryStore())
+	require.NoError(t, err)
+	require.NoError(t, l.AddTenant("team-a", config.TenantRateLimitConfig{
+		Operations: map[string]config.RateLimitRule{"echo": {Requests: 2, Period: time.Minute}},
+		Default:    config.RateLimitRule{Requests: 60, Period: time.Minute, Burst: 3},
+	}))
+	l.now = func() time.Time { return time.Unix(1_700_000_000, 0) }
+
+	ctx := context.Background()
+	take := func(tenant, operationID string) (bool, int) {
+		res, rule, err := l.Take(ctx, tenant, operationID, "c")
+		require.NoError(t, err)
+		return res.Allowed, rule.Burst
+	}
+
+	allowed, burst := take("team-a", "echo")
+	assert.True(t, allowed)
+	assert.Equal(t, 2, burst, "tenant operation rule")
+	allowed, _ = take("team-a", "echo")
+	assert.True(t, allowed)
+	allowed, _ = take("team-a", "echo")
+	assert.False(t, allowed)
+
+	allowed, burst = take("team-b", "echo")
+	assert.True(t, allowed, "tenants do not share buckets")
+	assert.Equal(t, 1, burst, "tenants without overrides get the operation rule")
+	allowed, _ = take("", "echo")
+	assert.True(t, allowed)
+
+	_, burst = take("team-a", "readyz")
+	assert.Equal(t, 3, burst, "tenant default rule")
+	_, burst = take("team-b", "readyz")
+	assert.Equal(t, 1, burst)
+
+	assert.Error(t, l.AddTenant("team-c", config.TenantRateLimitConfig{Default: config.RateLimitRule{Requests: 1}}))
+}
+
+func TestNew_Validates(t *testing.T) {
+	def := config.RateLimitRule{Requests: 1, Period: time.Second}
+
+	tests := []struct {
+		name string
+		cfg  config.RateLimitConfig
+	}{
+		{name: "unknown backend", cfg: config.RateLimitConfig{Backend: "etcd", Default: def}},
+		{name: "redis without addr", cfg: config.RateLimitConfig{Backend: "redis", Default: def}},
+		{name: "unknown key", cfg: config.RateLimitConfig{Key: "user_agent", Default: def}},
+		{name: "no default", cfg: config.RateLimitConfig{}},
+		{name: "bad operation", cfg: config.RateLimitConfig{Default: def, Operations: map[string]config.RateLimitRule{"echo": {Requests: 1}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg)
+			assert.Error(t, err)
+		})
+	}
+}
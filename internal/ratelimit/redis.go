@@ -0,0 +1,114 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeScript refills and takes from a bucket atomically. Buckets are hashes
+// of the tokens left and when they were counted, in milliseconds, and
+// expire once they would have refilled. Tokens are returned as a string
+// because Redis truncates Lua numbers to integers.
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(state[1])
+local updated = tonumber(state[2])
+if tokens == nil or updated == nil then
+  tokens = burst
+  updated = now
+end
+
+if now > updated then
+  tokens = math.min(burst, tokens + (now - updated) * rate)
+  updated = now
+end
+
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "updated", updated)
+redis.call("PEXPIRE", KEYS[1], math.ceil((burst - tokens) / rate) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// peekScript computes what takeScript would find in a bucket without
+// changing it.
+var peekScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(state[1])
+local updated = tonumber(state[2])
+if tokens == nil or updated == nil then
+  return {1, tostring(burst)}
+end
+
+if now > updated then
+  tokens = math.min(burst, tokens + (now - updated) * rate)
+end
+
+local allowed = 0
+if tokens >= 1 then
+  allowed = 1
+end
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore keeps buckets in a Redis-compatible server, so that every
+// instance sharing it enforces one limit per client. Instances pass their
+// own clock, so their clocks should be in sync.
+type RedisStore struct {
+	client redis.Scripter
+	prefix string
+}
+
+// NewRedisStore creates a RedisStore whose keys start with prefix, or with
+// "hello-go:ratelimit:" if prefix is empty.
+func NewRedisStore(client redis.Scripter, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = defaultKeyPrefix
+	}
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	return s.run(ctx, takeScript, key, limit, now)
+}
+
+func (s *RedisStore) Peek(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	return s.run(ctx, peekScript, key, limit, now)
+}
+
+func (s *RedisStore) run(ctx context.Context, script *redis.Script, key string, limit Limit, now time.Time) (Result, error) {
+	// The scripts work in milliseconds.
+	rate := limit.Rate / 1000
+	reply, err := script.Run(ctx, s.client, []string{s.prefix + key},
+		strconv.FormatFloat(rate, 'g', -1, 64), limit.Burst, now.UnixMilli()).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(reply) != 2 {
+		return Result{}, fmt.Errorf("unexpected rate limit script reply %v", reply)
+	}
+
+	allowed, _ := reply[0].(int64)
+	tokensText, _ := reply[1].(string)
+	tokens, err := strconv.ParseFloat(tokensText, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("unexpected token count %q: %w", tokensText, err)
+	}
+	return result(limit, tokens, allowed == 1), nil
+}
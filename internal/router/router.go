@@ -1,25 +1,223 @@
 package router
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
+	"net/http"
+	"slices"
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/savisec/hello-go/api"
+	"github.com/savisec/hello-go/internal/audit"
+	"github.com/savisec/hello-go/internal/auth"
+	"github.com/savisec/hello-go/internal/authz"
+	"github.com/savisec/hello-go/internal/config"
+	"github.com/savisec/hello-go/internal/cors"
 	"github.com/savisec/hello-go/internal/handlers"
 	"github.com/savisec/hello-go/internal/httpserver"
+	"github.com/savisec/hello-go/internal/ipfilter"
+	"github.com/savisec/hello-go/internal/logging"
+	"github.com/savisec/hello-go/internal/middleware"
+	"github.com/savisec/hello-go/internal/operation"
+	"github.com/savisec/hello-go/internal/ratelimit"
 	"github.com/savisec/hello-go/internal/services"
+	"github.com/savisec/hello-go/internal/signing"
+	"github.com/savisec/hello-go/internal/tenancy"
 )
 
+// Route groups, which IP filters are configured for.
+const (
+	groupAPI    = "api"
+	groupHealth = "health"
+)
+
+var routeGroups = []string{groupAPI, groupHealth}
+
 // BuildRouter creates and configures the chi router with all routes
-func BuildRouter(logger *slog.Logger) chi.Router {
-	router := httpserver.NewRouter()
+func BuildRouter(cfg *config.Config, logger *slog.Logger, auditLogger *audit.Logger, fingerprinter *logging.Fingerprinter) (chi.Router, error) {
+	resolver, err := operation.NewResolver(api.OpenAPISpec)
+	if err != nil {
+		return nil, err
+	}
+
+	var global []func(http.Handler) http.Handler
+	if cfg.Security.Headers.Enabled {
+		global = append(global, middleware.NewSecurityHeaders(cfg.Security.Headers).ServeHTTP)
+	}
+	if cfg.CORS.Enabled {
+		policies, err := cors.New(cfg.CORS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup cors: %w", err)
+		}
+		for _, id := range policies.Operations() {
+			if _, ok := resolver.Lookup(id); !ok {
+				logger.Warn("CORS config names an unknown operation", "operation", id)
+			}
+		}
+		// CORS runs ahead of the routes, so that preflights for any
+		// operation are answered without credentials.
+		global = append(global, middleware.NewCORS(policies, resolver, logger).ServeHTTP)
+	}
+	if cfg.Security.Requests.Enabled {
+		for id := range cfg.Security.Requests.Operations {
+			if _, ok := resolver.Lookup(id); !ok {
+				logger.Warn("Request limits name an unknown operation", "operation", id)
+			}
+		}
+		// Limits run after CORS, so browsers can read the rejections.
+		global = append(global, middleware.NewRequestLimits(cfg.Security.Requests, resolver, logger).ServeHTTP)
+	}
+
+	trustedProxies, err := ipfilter.ParsePrefixes(cfg.Server.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse trusted proxies: %w", err)
+	}
+
+	ipFilters := map[string]*middleware.IPFilter{}
+	if cfg.IPFilter.Enabled {
+		// The lists, and the watches on their files, live as long as the
+		// process.
+		lists, err := ipfilter.NewLists(cfg.IPFilter.Lists, auditLogger, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load ip lists: %w", err)
+		}
+		for group, rc := range cfg.IPFilter.Groups {
+			if !slices.Contains(routeGroups, group) {
+				return nil, fmt.Errorf("ip filter for unknown route group %q", group)
+			}
+			rule, err := lists.Rule(rc)
+			if err != nil {
+				return nil, fmt.Errorf("ip filter for %s: %w", group, err)
+			}
+			if !rule.Empty() {
+				ipFilters[group] = middleware.NewIPFilter(group, rule, logger)
+			}
+		}
+	}
+
+	router := httpserver.NewRouter(logger, trustedProxies, global...)
+
+	router.Group(func(r chi.Router) {
+		if filter, ok := ipFilters[groupHealth]; ok {
+			r.Use(filter.ServeHTTP)
+		}
+		httpserver.AddHealthRoutes(r, logger)
+	})
 
-	httpserver.AddHealthRoutes(router, logger)
+	var authentication *middleware.Authentication
+	if cfg.Auth.Enabled {
+		if !cfg.Auth.HasCredentials() {
+			return nil, errors.New("auth is enabled but no api keys, jwt validation, signing keys or gateway authorizer are configured")
+		}
+		store, err := auth.NewConfigKeyStore(cfg.Auth.APIKeys)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load api keys: %w", err)
+		}
 
-	echoService := services.NewEchoService(logger)
+		var validator *auth.JWTValidator
+		if cfg.Auth.JWT.Enabled {
+			keys, err := auth.NewJWKS(cfg.Auth.JWT)
+			if err != nil {
+				return nil, fmt.Errorf("failed to setup jwks: %w", err)
+			}
+			if validator, err = auth.NewJWTValidator(cfg.Auth.JWT, keys); err != nil {
+				return nil, fmt.Errorf("failed to setup jwt validation: %w", err)
+			}
+		}
+
+		var signatures *signing.Verifier
+		if cfg.Auth.Signing.Enabled {
+			if signatures, err = signing.New(cfg.Auth.Signing); err != nil {
+				return nil, fmt.Errorf("failed to setup request signing: %w", err)
+			}
+		}
+
+		authentication = middleware.NewAuthentication(auth.NewAuthenticator(store, validator), signatures, cfg.Auth.GatewayAuthorizer, auditLogger, logger)
+	}
+
+	var authorization *middleware.Authorization
+	if cfg.Authz.Enabled {
+		policy, err := authz.LoadPolicy(cfg.Authz.PolicyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load authorization policy: %w", err)
+		}
+		for _, id := range policy.Operations() {
+			if _, ok := resolver.Lookup(id); !ok {
+				logger.Warn("Authorization policy names an unknown operation", "operation", id)
+			}
+		}
+		authorization = middleware.NewAuthorization(authz.NewEngine(policy), auditLogger, logger)
+	}
+
+	var tenants *middleware.Tenancy
+	if cfg.Tenancy.Enabled {
+		tenantResolver, err := tenancy.NewResolver(cfg.Tenancy, cfg.Auth.Enabled)
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup tenancy: %w", err)
+		}
+		for id, tc := range cfg.Tenancy.Tenants {
+			for op := range tc.Features {
+				if _, ok := resolver.Lookup(op); !ok {
+					logger.Warn("Tenant features name an unknown operation", "tenant", id, "operation", op)
+				}
+			}
+		}
+		tenants = middleware.NewTenancy(tenantResolver, logger)
+	}
+
+	var rateLimit *middleware.RateLimit
+	var authFailureLimit *middleware.AuthFailureLimit
+	if cfg.RateLimit.Enabled {
+		limiter, err := ratelimit.New(cfg.RateLimit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup rate limiting: %w", err)
+		}
+		if cfg.Tenancy.Enabled {
+			for id, tc := range cfg.Tenancy.Tenants {
+				if err := limiter.AddTenant(id, tc.RateLimit); err != nil {
+					return nil, fmt.Errorf("failed to setup rate limiting: %w", err)
+				}
+			}
+		}
+		rateLimit = middleware.NewRateLimit(limiter, logger)
+		if authentication != nil && limiter.LimitsFailures() {
+			authFailureLimit = middleware.NewAuthFailureLimit(limiter, logger)
+		}
+	}
+
+	echoService := services.NewEchoService(logger, fingerprinter)
 	echoHandler := handlers.NewEchoHandler(echoService, logger)
 
-	router.Post("/v1/echo", echoHandler.PostV1Echo)
+	// API routes are filtered by client address, described in
+	// api/openapi.yml and protected as it declares, with failed attempts
+	// throttled per source IP, attributed to a tenant, rate limited per
+	// tenant and client, then authorized by policy; the health routes
+	// above stay public.
+	router.Group(func(r chi.Router) {
+		if filter, ok := ipFilters[groupAPI]; ok {
+			r.Use(filter.ServeHTTP)
+		}
+		r.Use(operation.Middleware(resolver))
+		if authFailureLimit != nil {
+			r.Use(authFailureLimit.ServeHTTP)
+		}
+		if authentication != nil {
+			r.Use(authentication.ServeHTTP)
+		}
+		if tenants != nil {
+			r.Use(tenants.ServeHTTP)
+		}
+		if rateLimit != nil {
+			r.Use(rateLimit.ServeHTTP)
+		}
+		if authorization != nil {
+			r.Use(authorization.ServeHTTP)
+		}
+
+		r.Post("/v1/echo", echoHandler.PostV1Echo)
+	})
 
-	return router
+	return router, nil
 }
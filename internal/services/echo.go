@@ -1,32 +1,61 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"log/slog"
 
 	"github.com/savisec/hello-go/internal/api"
+	"github.com/savisec/hello-go/internal/lambdactx"
+	"github.com/savisec/hello-go/internal/logging"
 )
 
+// ErrMissingEchoFields is returned when an echo message lacks a message or author.
+var ErrMissingEchoFields = errors.New("missing required fields: message and author")
+
 // EchoService provides echo functionality.
 type EchoService struct {
-	logger *slog.Logger
+	logger      *slog.Logger
+	fingerprint *logging.Fingerprinter
 }
 
-// NewEchoService creates a new EchoService instance with the provided logger.
-func NewEchoService(logger *slog.Logger) *EchoService {
+// NewEchoService creates a new EchoService instance with the provided
+// logger. Authors are logged as fingerprints derived by fingerprinter.
+func NewEchoService(logger *slog.Logger, fingerprinter *logging.Fingerprinter) *EchoService {
 	return &EchoService{
-		logger: logger,
+		logger:      logger,
+		fingerprint: fingerprinter,
 	}
 }
 
 // Echo processes the EchoRequest and returns an EchoResponse.
-func (s *EchoService) Echo(msg api.EchoMessage) api.EchoMessage {
-	s.logger.Info("Processing echo request",
-		"message", msg.Message,
-		"author", msg.Author,
-	)
+// User content is never logged verbatim; only its length and a fingerprint.
+// The caller's identity, when ctx carries one, is logged alongside.
+func (s *EchoService) Echo(ctx context.Context, msg api.EchoMessage) api.EchoMessage {
+	attrs := []any{
+		"message_length", len(msg.Message),
+		"author_hash", s.fingerprint.Fingerprint(msg.Author),
+	}
+	if rc, ok := lambdactx.FromContext(ctx); ok {
+		attrs = append(attrs, "request_id", rc.RequestID)
+		if rc.Identity != nil {
+			attrs = append(attrs, "principal", rc.Identity.Subject)
+		}
+	}
+
+	s.logger.InfoContext(ctx, "Processing echo request", attrs...)
 
 	return api.EchoMessage{
 		Message: msg.Message,
 		Author:  msg.Author,
 	}
 }
+
+// ValidateEchoMessage checks that msg has every field the echo operation
+// requires. It is shared by all entrypoints that accept echo messages.
+func ValidateEchoMessage(msg api.EchoMessage) error {
+	if msg.Message == "" || msg.Author == "" {
+		return ErrMissingEchoFields
+	}
+	return nil
+}
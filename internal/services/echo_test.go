@@ -1,6 +1,9 @@
 package services_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"log/slog"
 	"os"
 	"testing"
@@ -9,6 +12,8 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/savisec/hello-go/internal/api"
+	"github.com/savisec/hello-go/internal/lambdactx"
+	"github.com/savisec/hello-go/internal/logging"
 	services "github.com/savisec/hello-go/internal/services"
 )
 
@@ -17,7 +22,7 @@ func TestEchoService_Echo(t *testing.T) {
 		Level: slog.LevelError,
 	}))
 
-	service := services.NewEchoService(logger)
+	service := services.NewEchoService(logger, logging.NewFingerprinter("test"))
 
 	tests := []struct {
 		expected api.EchoMessage
@@ -61,7 +66,7 @@ func TestEchoService_Echo(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := service.Echo(tt.request)
+			result := service.Echo(context.Background(), tt.request)
 
 			assert.Equal(t, tt.expected.Message, result.Message)
 			assert.Equal(t, tt.expected.Author, result.Author)
@@ -69,10 +74,28 @@ func TestEchoService_Echo(t *testing.T) {
 	}
 }
 
+func TestEchoService_EchoLogsIdentity(t *testing.T) {
+	var buf bytes.Buffer
+	service := services.NewEchoService(slog.New(slog.NewJSONHandler(&buf, nil)), logging.NewFingerprinter("test"))
+
+	ctx := lambdactx.NewContext(context.Background(), lambdactx.RequestContext{
+		RequestID: "req-1",
+		Identity:  &lambdactx.Identity{Subject: "user-42", Authorizer: "jwt"},
+	})
+	service.Echo(ctx, api.EchoMessage{Message: "hi", Author: "Alice"})
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "req-1", record["request_id"])
+	assert.Equal(t, "user-42", record["principal"])
+	assert.Equal(t, logging.NewFingerprinter("test").Fingerprint("Alice"), record["author_hash"])
+	assert.NotEqual(t, logging.NewFingerprinter("other").Fingerprint("Alice"), record["author_hash"])
+}
+
 func TestNewEchoService(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
-	service := services.NewEchoService(logger)
+	service := services.NewEchoService(logger, logging.NewFingerprinter("test"))
 
 	require.NotNil(t, service)
 }
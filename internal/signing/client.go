@@ -0,0 +1,32 @@
+package signing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// NewRequestSigner returns a request editor that signs each request with
+// the shared secret of keyID, for operations that accept the hmacSignature
+// scheme. Pass it to the generated client's api.WithRequestEditorFn, after
+// any editor that changes the query or Content-Type, since both are signed.
+func NewRequestSigner(keyID string, secret []byte) func(context.Context, *http.Request) error {
+	return func(_ context.Context, req *http.Request) error {
+		var body []byte
+		if req.Body != nil {
+			var err error
+			if body, err = io.ReadAll(req.Body); err != nil {
+				return fmt.Errorf("failed to read request body: %w", err)
+			}
+			_ = req.Body.Close()
+			req.Body = io.NopCloser(bytes.NewReader(body))
+			req.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(body)), nil
+			}
+		}
+		return Sign(req, body, keyID, secret, time.Now())
+	}
+}
@@ -0,0 +1,54 @@
+package signing
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// NonceStore remembers the nonces of accepted requests so that none is
+// accepted twice. Implementations backed by a shared store let several
+// instances reject each other's replays.
+type NonceStore interface {
+	// Use records nonce for keyID until expires. It returns false if the
+	// nonce was already recorded and has not expired.
+	Use(ctx context.Context, keyID, nonce string, expires time.Time) (bool, error)
+}
+
+// MemoryNonceStore is a NonceStore local to the process.
+type MemoryNonceStore struct {
+	seen map[string]time.Time
+	now  func() time.Time
+	// nextSweep is when expired nonces are next dropped.
+	nextSweep time.Time
+	mu        sync.Mutex
+}
+
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{
+		seen: make(map[string]time.Time),
+		now:  time.Now,
+	}
+}
+
+func (s *MemoryNonceStore) Use(_ context.Context, keyID, nonce string, expires time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if !now.Before(s.nextSweep) {
+		for k, exp := range s.seen {
+			if !now.Before(exp) {
+				delete(s.seen, k)
+			}
+		}
+		s.nextSweep = now.Add(time.Minute)
+	}
+
+	key := keyID + "\x00" + nonce
+	if exp, ok := s.seen[key]; ok && now.Before(exp) {
+		return false, nil
+	}
+	s.seen[key] = expires
+	return true, nil
+}
@@ -0,0 +1,264 @@
+package signing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/internal/config"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func newTestVerifier(t *testing.T, now time.Time) *Verifier {
+	t.Helper()
+
+	keys, err := NewConfigKeyStore([]config.SigningKeyConfig{{
+		ID:        "partner",
+		Principal: "acme",
+		Scopes:    []string{"echo:write"},
+		Secret:    hex.EncodeToString(testSecret),
+	}})
+	require.NoError(t, err)
+
+	nonces := NewMemoryNonceStore()
+	nonces.now = func() time.Time { return now }
+
+	v := NewVerifier(keys, nonces, time.Minute)
+	v.now = func() time.Time { return now }
+	return v
+}
+
+func signedRequest(t *testing.T, method, path string, body []byte, signedAt time.Time) *http.Request {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	require.NoError(t, Sign(req, body, "partner", testSecret, signedAt))
+	return req
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	body := []byte(`{"message":"hi"}`)
+
+	tests := []struct {
+		tamper  func(r *http.Request)
+		name    string
+		body    []byte
+		at      time.Time
+		wantErr bool
+	}{
+		{name: "valid", body: body, at: now},
+		{name: "valid within skew", body: body, at: now.Add(50 * time.Second)},
+		{name: "empty body", body: nil, at: now},
+		{name: "expired", body: body, at: now.Add(-2 * time.Minute), wantErr: true},
+		{name: "from the future", body: body, at: now.Add(2 * time.Minute), wantErr: true},
+		{
+			name: "tampered path", body: body, at: now, wantErr: true,
+			tamper: func(r *http.Request) { r.URL.Path = "/v1/other" },
+		},
+		{
+			name: "reordered query", body: body, at: now,
+			tamper: func(r *http.Request) { r.URL.RawQuery = "b=2&a=1&a=0" },
+		},
+		{
+			name: "tampered query", body: body, at: now, wantErr: true,
+			tamper: func(r *http.Request) { r.URL.RawQuery = "a=0&a=1&b=3" },
+		},
+		{
+			name: "added query parameter", body: body, at: now, wantErr: true,
+			tamper: func(r *http.Request) { r.URL.RawQuery = "a=0&a=1&b=2&debug=true" },
+		},
+		{
+			name: "tampered content type", body: body, at: now, wantErr: true,
+			tamper: func(r *http.Request) { r.Header.Set("Content-Type", "text/plain") },
+		},
+		{
+			name: "tampered method", body: body, at: now, wantErr: true,
+			tamper: func(r *http.Request) { r.Method = http.MethodPut },
+		},
+		{
+			name: "tampered timestamp", body: body, at: now, wantErr: true,
+			tamper: func(r *http.Request) { r.Header.Set(HeaderTimestamp, "1700000001") },
+		},
+		{
+			name: "tampered nonce", body: body, at: now, wantErr: true,
+			tamper: func(r *http.Request) { r.Header.Set(HeaderNonce, "00") },
+		},
+		{
+			name: "unknown key", body: body, at: now, wantErr: true,
+			tamper: func(r *http.Request) { r.Header.Set(HeaderKeyID, "other") },
+		},
+		{
+			name: "missing digest", body: body, at: now, wantErr: true,
+			tamper: func(r *http.Request) { r.Header.Del(HeaderDigest) },
+		},
+		{
+			name: "malformed signature", body: body, at: now, wantErr: true,
+			tamper: func(r *http.Request) { r.Header.Set(HeaderSignature, "zz") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVerifier(t, now)
+			req := signedRequest(t, http.MethodPost, "/v1/echo?a=0&a=1&b=2", tt.body, tt.at)
+			if tt.tamper != nil {
+				tt.tamper(req)
+			}
+
+			key, err := v.Verify(context.Background(), req, tt.body)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidSignature)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "acme", key.Principal)
+			assert.Equal(t, []string{"echo:write"}, key.Scopes)
+		})
+	}
+}
+
+func TestCanonicalQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{name: "empty", raw: "", want: ""},
+		{name: "sorted by key then value", raw: "b=2&a=y&a=x", want: "a=x&a=y&b=2"},
+		{name: "re-encoded", raw: "q=hello%20world&k=a%2Bb", want: "k=a%2Bb&q=hello+world"},
+		{name: "key without value", raw: "flag&x=1", want: "flag=&x=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, CanonicalQuery(tt.raw))
+		})
+	}
+}
+
+func TestNewRequestSigner(t *testing.T) {
+	body := []byte(`{"message":"hi"}`)
+	req := httptest.NewRequest(http.MethodPost, "/v1/echo?b=2&a=1", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	require.NoError(t, NewRequestSigner("partner", testSecret)(context.Background(), req))
+
+	// The body is still there to send, and to resend on redirects.
+	sent, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.Equal(t, body, sent)
+	resent, err := req.GetBody()
+	require.NoError(t, err)
+	resentBody, err := io.ReadAll(resent)
+	require.NoError(t, err)
+	assert.Equal(t, body, resentBody)
+
+	key, err := newTestVerifier(t, time.Now()).Verify(context.Background(), req, body)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", key.Principal)
+}
+
+func TestVerifier_RejectsTamperedBody(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	v := newTestVerifier(t, now)
+
+	req := signedRequest(t, http.MethodPost, "/v1/echo", []byte(`{"message":"hi"}`), now)
+
+	_, err := v.Verify(context.Background(), req, []byte(`{"message":"bye"}`))
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestVerifier_RejectsReplays(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	v := newTestVerifier(t, now)
+	body := []byte(`{}`)
+
+	req := signedRequest(t, http.MethodPost, "/v1/echo", body, now)
+	_, err := v.Verify(context.Background(), req, body)
+	require.NoError(t, err)
+
+	_, err = v.Verify(context.Background(), req, body)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	assert.ErrorContains(t, err, "replayed")
+}
+
+func TestVerifier_ForgeriesDoNotBurnNonces(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	v := newTestVerifier(t, now)
+	body := []byte(`{}`)
+
+	req := signedRequest(t, http.MethodPost, "/v1/echo", body, now)
+	forged := req.Clone(context.Background())
+	forged.Header.Set(HeaderSignature, Compute([]byte("wrong"), "anything"))
+
+	_, err := v.Verify(context.Background(), forged, body)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	_, err = v.Verify(context.Background(), req, body)
+	assert.NoError(t, err)
+}
+
+type failingNonceStore struct{}
+
+func (failingNonceStore) Use(context.Context, string, string, time.Time) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func TestVerifier_NonceStoreFailure(t *testing.T) {
+	now := time.Now()
+	keys, err := NewConfigKeyStore([]config.SigningKeyConfig{{ID: "partner", Secret: hex.EncodeToString(testSecret)}})
+	require.NoError(t, err)
+	v := NewVerifier(keys, failingNonceStore{}, time.Minute)
+
+	req := signedRequest(t, http.MethodPost, "/v1/echo", nil, now)
+	_, err = v.Verify(context.Background(), req, nil)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestMemoryNonceStore_Expires(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	store := NewMemoryNonceStore()
+	store.now = func() time.Time { return now }
+
+	fresh, err := store.Use(context.Background(), "k", "n", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, fresh)
+
+	fresh, err = store.Use(context.Background(), "k", "n", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, fresh)
+
+	fresh, err = store.Use(context.Background(), "other", "n", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, fresh, "nonces are per key")
+
+	now = now.Add(2 * time.Minute)
+	fresh, err = store.Use(context.Background(), "k", "n", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, fresh)
+	assert.Len(t, store.seen, 1, "expired nonces are swept")
+}
+
+func TestNewConfigKeyStore_Validates(t *testing.T) {
+	_, err := NewConfigKeyStore([]config.SigningKeyConfig{{ID: "k", Secret: "abcd"}})
+	assert.Error(t, err, "short secret")
+
+	_, err = NewConfigKeyStore([]config.SigningKeyConfig{{ID: "k", Secret: "not hex"}})
+	assert.Error(t, err)
+
+	_, err = NewConfigKeyStore([]config.SigningKeyConfig{{Secret: "30313233343536373839616263646566"}})
+	assert.Error(t, err, "missing id")
+}
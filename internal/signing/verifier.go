@@ -0,0 +1,173 @@
+package signing
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/savisec/hello-go/internal/config"
+)
+
+const (
+	nonceStoreMemory = "memory"
+
+	defaultReplayWindow = 5 * time.Minute
+)
+
+var (
+	// ErrInvalidSignature is returned for requests whose signature is
+	// missing parts, wrong, outside the replay window or replayed.
+	ErrInvalidSignature = errors.New("invalid request signature")
+
+	// ErrKeyNotFound is returned by a KeyStore that has no key with the
+	// given ID.
+	ErrKeyNotFound = errors.New("signing key not found")
+)
+
+// Key is a shared signing secret and the caller it identifies.
+type Key struct {
+	ID        string
+	Principal string
+	Scopes    []string
+	Secret    []byte
+	// Tenant is the tenant the key's owner belongs to, if any.
+	Tenant string
+}
+
+// KeyStore looks up signing keys by ID.
+type KeyStore interface {
+	Lookup(ctx context.Context, id string) (Key, error)
+}
+
+// ConfigKeyStore serves the keys listed in the config file.
+type ConfigKeyStore struct {
+	keys map[string]Key
+}
+
+// NewConfigKeyStore validates keys and indexes them by ID.
+func NewConfigKeyStore(keys []config.SigningKeyConfig) (*ConfigKeyStore, error) {
+	store := &ConfigKeyStore{keys: make(map[string]Key, len(keys))}
+	for i, k := range keys {
+		if k.ID == "" {
+			return nil, fmt.Errorf("signing key %d: id must be set", i)
+		}
+		if _, ok := store.keys[k.ID]; ok {
+			return nil, fmt.Errorf("signing key %q: duplicate id", k.ID)
+		}
+		secret, err := hex.DecodeString(k.Secret)
+		if err != nil || len(secret) < 16 {
+			return nil, fmt.Errorf("signing key %q: secret must be at least 16 hex encoded bytes", k.ID)
+		}
+
+		principal := k.Principal
+		if principal == "" {
+			principal = k.ID
+		}
+		store.keys[k.ID] = Key{ID: k.ID, Principal: principal, Scopes: k.Scopes, Secret: secret, Tenant: k.Tenant}
+	}
+	return store, nil
+}
+
+func (s *ConfigKeyStore) Lookup(_ context.Context, id string) (Key, error) {
+	key, ok := s.keys[id]
+	if !ok {
+		return Key{}, ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// Verifier checks request signatures and rejects replays: a request must
+// be signed within the replay window of now, and its nonce must not have
+// been seen in that time.
+type Verifier struct {
+	keys   KeyStore
+	nonces NonceStore
+	now    func() time.Time
+	window time.Duration
+}
+
+// NewVerifier creates a Verifier. A zero window defaults to five minutes.
+func NewVerifier(keys KeyStore, nonces NonceStore, window time.Duration) *Verifier {
+	if window <= 0 {
+		window = defaultReplayWindow
+	}
+	return &Verifier{keys: keys, nonces: nonces, now: time.Now, window: window}
+}
+
+// New creates a Verifier for the keys and nonce store in cfg.
+func New(cfg config.SigningConfig) (*Verifier, error) {
+	keys, err := NewConfigKeyStore(cfg.Keys)
+	if err != nil {
+		return nil, err
+	}
+
+	var nonces NonceStore
+	switch strings.ToLower(cfg.NonceStore) {
+	case "", nonceStoreMemory:
+		nonces = NewMemoryNonceStore()
+	default:
+		return nil, fmt.Errorf("unknown nonce store %q", cfg.NonceStore)
+	}
+
+	return NewVerifier(keys, nonces, cfg.ReplayWindow), nil
+}
+
+// Verify checks the signature of r, whose body has already been read into
+// body, and returns the key it was signed with. Requests that fail the
+// check return an error wrapping ErrInvalidSignature; a nonce store that
+// cannot be reached returns a different error.
+func (v *Verifier) Verify(ctx context.Context, r *http.Request, body []byte) (Key, error) {
+	keyID := r.Header.Get(HeaderKeyID)
+	timestamp := r.Header.Get(HeaderTimestamp)
+	nonce := r.Header.Get(HeaderNonce)
+	digest := r.Header.Get(HeaderDigest)
+	signature := r.Header.Get(HeaderSignature)
+	if keyID == "" || timestamp == "" || nonce == "" || digest == "" || signature == "" {
+		return Key{}, fmt.Errorf("%w: missing signature headers", ErrInvalidSignature)
+	}
+
+	key, err := v.keys.Lookup(ctx, keyID)
+	if errors.Is(err, ErrKeyNotFound) {
+		return Key{}, fmt.Errorf("%w: unknown key %q", ErrInvalidSignature, keyID)
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("failed to look up signing key: %w", err)
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return Key{}, fmt.Errorf("%w: malformed timestamp", ErrInvalidSignature)
+	}
+	signedAt := time.Unix(unix, 0)
+	if skew := v.now().Sub(signedAt).Abs(); skew > v.window {
+		return Key{}, fmt.Errorf("%w: timestamp outside the replay window", ErrInvalidSignature)
+	}
+
+	if !strings.EqualFold(digest, Digest(body)) {
+		return Key{}, fmt.Errorf("%w: body digest mismatch", ErrInvalidSignature)
+	}
+
+	want, _ := hex.DecodeString(Compute(key.Secret, requestStringToSign(r, timestamp, nonce, strings.ToLower(digest))))
+	got, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(got, want) {
+		return Key{}, fmt.Errorf("%w: signature mismatch", ErrInvalidSignature)
+	}
+
+	// Nonces are only recorded once the signature is known to be good, so
+	// forged requests cannot burn a client's nonces.
+	fresh, err := v.nonces.Use(ctx, keyID, nonce, signedAt.Add(v.window))
+	if err != nil {
+		return Key{}, fmt.Errorf("failed to record nonce: %w", err)
+	}
+	if !fresh {
+		return Key{}, fmt.Errorf("%w: replayed nonce", ErrInvalidSignature)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,192 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+// maxEMFDimensions is the CloudWatch limit on dimensions per metric.
+const maxEMFDimensions = 30
+
+// EMFExporter writes metrics in the CloudWatch Embedded Metric Format, one
+// JSON document per data point. Written to stdout in Lambda, CloudWatch Logs
+// turns the documents into metrics without any collector.
+type EMFExporter struct {
+	w         io.Writer
+	namespace string
+	mu        sync.Mutex
+}
+
+// NewEMFExporter creates an EMFExporter writing to w. Metrics are published
+// under namespace.
+func NewEMFExporter(w io.Writer, namespace string) *EMFExporter {
+	return &EMFExporter{w: w, namespace: namespace}
+}
+
+// Temporality reports deltas for counters and histograms, since CloudWatch
+// aggregates the values it receives. Up-down counters stay cumulative so
+// they read as a current level.
+func (e *EMFExporter) Temporality(kind metric.InstrumentKind) metricdata.Temporality {
+	switch kind {
+	case metric.InstrumentKindUpDownCounter, metric.InstrumentKindObservableUpDownCounter:
+		return metricdata.CumulativeTemporality
+	default:
+		return metricdata.DeltaTemporality
+	}
+}
+
+func (e *EMFExporter) Aggregation(kind metric.InstrumentKind) metric.Aggregation {
+	return metric.DefaultAggregationSelector(kind)
+}
+
+// Export writes one EMF document per data point in rm.
+func (e *EMFExporter) Export(_ context.Context, rm *metricdata.ResourceMetrics) error {
+	var service string
+	if rm.Resource != nil {
+		if v, ok := rm.Resource.Set().Value(semconv.ServiceNameKey); ok {
+			service = v.AsString()
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			for _, doc := range e.documents(service, m) {
+				line, err := json.Marshal(doc)
+				if err != nil {
+					return fmt.Errorf("failed to encode EMF document for %s: %w", m.Name, err)
+				}
+				if _, err := e.w.Write(append(line, '\n')); err != nil {
+					return fmt.Errorf("failed to write EMF document: %w", err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (e *EMFExporter) documents(service string, m metricdata.Metrics) []map[string]any {
+	var docs []map[string]any
+	add := func(attrs attribute.Set, t time.Time, value any) {
+		docs = append(docs, e.document(service, m, attrs, t, value))
+	}
+
+	switch data := m.Data.(type) {
+	case metricdata.Sum[int64]:
+		for _, dp := range data.DataPoints {
+			add(dp.Attributes, dp.Time, dp.Value)
+		}
+	case metricdata.Sum[float64]:
+		for _, dp := range data.DataPoints {
+			add(dp.Attributes, dp.Time, dp.Value)
+		}
+	case metricdata.Gauge[int64]:
+		for _, dp := range data.DataPoints {
+			add(dp.Attributes, dp.Time, dp.Value)
+		}
+	case metricdata.Gauge[float64]:
+		for _, dp := range data.DataPoints {
+			add(dp.Attributes, dp.Time, dp.Value)
+		}
+	case metricdata.Histogram[int64]:
+		for _, dp := range data.DataPoints {
+			add(dp.Attributes, dp.Time, statisticSet(dp.Count, float64(dp.Sum), dp.Min, dp.Max))
+		}
+	case metricdata.Histogram[float64]:
+		for _, dp := range data.DataPoints {
+			add(dp.Attributes, dp.Time, statisticSet(dp.Count, dp.Sum, dp.Min, dp.Max))
+		}
+	}
+	return docs
+}
+
+func (e *EMFExporter) document(service string, m metricdata.Metrics, attrs attribute.Set, t time.Time, value any) map[string]any {
+	doc := map[string]any{m.Name: value}
+
+	var dimensions []string
+	if service != "" {
+		doc["service"] = service
+		dimensions = append(dimensions, "service")
+	}
+	iter := attrs.Iter()
+	for iter.Next() && len(dimensions) < maxEMFDimensions {
+		kv := iter.Attribute()
+		key := string(kv.Key)
+		if key == m.Name {
+			continue
+		}
+		doc[key] = kv.Value.Emit()
+		dimensions = append(dimensions, key)
+	}
+
+	doc["_aws"] = map[string]any{
+		"Timestamp": t.UnixMilli(),
+		"CloudWatchMetrics": []map[string]any{{
+			"Namespace":  e.namespace,
+			"Dimensions": [][]string{dimensions},
+			"Metrics":    []map[string]string{{"Name": m.Name, "Unit": emfUnit(m.Unit)}},
+		}},
+	}
+	return doc
+}
+
+// statisticSet summarizes a histogram data point the way EMF accepts
+// pre-aggregated values.
+func statisticSet[N int64 | float64](count uint64, sum float64, minimum, maximum metricdata.Extrema[N]) map[string]float64 {
+	mean := 0.0
+	if count > 0 {
+		mean = sum / float64(count)
+	}
+
+	set := map[string]float64{"Count": float64(count), "Sum": sum, "Min": mean, "Max": mean}
+	if v, ok := minimum.Value(); ok {
+		set["Min"] = float64(v)
+	}
+	if v, ok := maximum.Value(); ok {
+		set["Max"] = float64(v)
+	}
+	return set
+}
+
+// emfUnit maps UCUM units, as used by OpenTelemetry, to CloudWatch units.
+func emfUnit(unit string) string {
+	switch unit {
+	case "s":
+		return "Seconds"
+	case "ms":
+		return "Milliseconds"
+	case "us":
+		return "Microseconds"
+	case "By":
+		return "Bytes"
+	case "%":
+		return "Percent"
+	case "", "1":
+		return "None"
+	default:
+		// Annotations such as {request} denote counts.
+		if unit[0] == '{' {
+			return "Count"
+		}
+		return "None"
+	}
+}
+
+func (e *EMFExporter) ForceFlush(context.Context) error {
+	return nil
+}
+
+func (e *EMFExporter) Shutdown(context.Context) error {
+	return nil
+}
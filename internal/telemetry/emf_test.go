@@ -0,0 +1,68 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+func TestEMFExporter(t *testing.T) {
+	var buf bytes.Buffer
+	reader := metric.NewPeriodicReader(NewEMFExporter(&buf, "hello-go"))
+	provider := metric.NewMeterProvider(
+		metric.WithReader(reader),
+		metric.WithResource(resource.NewSchemaless(semconv.ServiceName("hello-go"))),
+	)
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	meter := provider.Meter("test")
+	counter, err := meter.Int64Counter("echo.requests", otelmetric.WithUnit("{request}"))
+	require.NoError(t, err)
+	histogram, err := meter.Float64Histogram("echo.duration", otelmetric.WithUnit("s"))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	counter.Add(ctx, 2, otelmetric.WithAttributes(attribute.String("route", "/v1/echo")))
+	histogram.Record(ctx, 0.5)
+	histogram.Record(ctx, 1.5)
+	require.NoError(t, provider.ForceFlush(ctx))
+
+	// Counters are deltas, so a second flush only reports new measurements.
+	counter.Add(ctx, 1, otelmetric.WithAttributes(attribute.String("route", "/v1/echo")))
+	require.NoError(t, provider.ForceFlush(ctx))
+
+	docs := map[string][]map[string]any{}
+	for line := range strings.SplitSeq(strings.TrimSpace(buf.String()), "\n") {
+		var doc map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &doc))
+		aws := doc["_aws"].(map[string]any)
+		directive := aws["CloudWatchMetrics"].([]any)[0].(map[string]any)
+		name := directive["Metrics"].([]any)[0].(map[string]any)["Name"].(string)
+		docs[name] = append(docs[name], doc)
+	}
+
+	require.Len(t, docs["echo.requests"], 2)
+	requests := docs["echo.requests"][0]
+	assert.EqualValues(t, 2, requests["echo.requests"])
+	assert.EqualValues(t, 1, docs["echo.requests"][1]["echo.requests"])
+	assert.Equal(t, "/v1/echo", requests["route"])
+	assert.Equal(t, "hello-go", requests["service"])
+
+	directive := requests["_aws"].(map[string]any)["CloudWatchMetrics"].([]any)[0].(map[string]any)
+	assert.Equal(t, "hello-go", directive["Namespace"])
+	assert.Equal(t, []any{[]any{"service", "route"}}, directive["Dimensions"])
+	assert.Equal(t, "Count", directive["Metrics"].([]any)[0].(map[string]any)["Unit"])
+
+	require.Len(t, docs["echo.duration"], 1)
+	assert.Equal(t, map[string]any{"Count": 2.0, "Sum": 2.0, "Min": 0.5, "Max": 1.5}, docs["echo.duration"][0]["echo.duration"])
+}
@@ -0,0 +1,97 @@
+package telemetry
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InitPhase is one timed step of process initialization.
+type InitPhase struct {
+	Start    time.Time
+	Name     string
+	Duration time.Duration
+}
+
+// InitTimer breaks process initialization down into phases so that the
+// cost of a cold start can be attributed.
+type InitTimer struct {
+	start  time.Time
+	last   time.Time
+	now    func() time.Time
+	phases []InitPhase
+}
+
+// NewInitTimer starts timing initialization.
+func NewInitTimer() *InitTimer {
+	return newInitTimer(time.Now)
+}
+
+func newInitTimer(now func() time.Time) *InitTimer {
+	start := now()
+	return &InitTimer{start: start, last: start, now: now}
+}
+
+// Mark ends the current phase, naming it, and starts the next one.
+func (t *InitTimer) Mark(name string) {
+	now := t.now()
+	t.phases = append(t.phases, InitPhase{Name: name, Start: t.last, Duration: now.Sub(t.last)})
+	t.last = now
+}
+
+// Phases returns the phases marked so far, in order.
+func (t *InitTimer) Phases() []InitPhase {
+	return t.phases
+}
+
+// Total is the time from NewInitTimer to the last Mark.
+func (t *InitTimer) Total() time.Duration {
+	return t.last.Sub(t.start)
+}
+
+// RecordInit emits the timed phases as a span, with one child span per
+// phase, and as duration histograms. It uses the global providers, so it
+// must run after Setup. The span is exported by the first invocation's
+// flush.
+func RecordInit(ctx context.Context, timer *InitTimer) {
+	tracer := otel.Tracer(tracerName)
+	ctx, span := tracer.Start(ctx, "init",
+		trace.WithTimestamp(timer.start),
+		trace.WithAttributes(semconv.FaaSColdstart(true)),
+	)
+	for _, phase := range timer.phases {
+		_, child := tracer.Start(ctx, "init "+phase.Name, trace.WithTimestamp(phase.Start))
+		child.End(trace.WithTimestamp(phase.Start.Add(phase.Duration)))
+	}
+	span.End(trace.WithTimestamp(timer.last))
+
+	meter := otel.Meter(tracerName)
+	total, err := meter.Float64Histogram("faas.init.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of process initialization"),
+	)
+	if err != nil {
+		otel.Handle(err)
+		return
+	}
+	phaseDuration, err := meter.Float64Histogram("faas.init.phase.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of each process initialization phase"),
+	)
+	if err != nil {
+		otel.Handle(err)
+		return
+	}
+
+	total.Record(ctx, timer.Total().Seconds())
+	for _, phase := range timer.phases {
+		phaseDuration.Record(ctx, phase.Duration.Seconds(),
+			metric.WithAttributes(attribute.String("faas.init.phase", phase.Name)),
+		)
+	}
+}
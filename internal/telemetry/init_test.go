@@ -0,0 +1,110 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestInitTimer_Phases(t *testing.T) {
+	now := time.Unix(0, 0)
+	timer := newInitTimer(func() time.Time { return now })
+
+	now = now.Add(30 * time.Millisecond)
+	timer.Mark("config")
+	now = now.Add(70 * time.Millisecond)
+	timer.Mark("router")
+
+	phases := timer.Phases()
+	require.Len(t, phases, 2)
+	assert.Equal(t, "config", phases[0].Name)
+	assert.Equal(t, 30*time.Millisecond, phases[0].Duration)
+	assert.Equal(t, 70*time.Millisecond, phases[1].Duration)
+	assert.Equal(t, phases[0].Start.Add(phases[0].Duration), phases[1].Start)
+	assert.Equal(t, 100*time.Millisecond, timer.Total())
+}
+
+func TestRecordInit(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	otel.SetTracerProvider(tp)
+
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+
+	now := time.Unix(100, 0)
+	timer := newInitTimer(func() time.Time { return now })
+	now = now.Add(time.Second)
+	timer.Mark("config")
+	now = now.Add(2 * time.Second)
+	timer.Mark("telemetry")
+
+	RecordInit(context.Background(), timer)
+
+	ended := spans.Ended()
+	require.Len(t, ended, 3)
+	root := ended[2]
+	assert.Equal(t, "init", root.Name())
+	assert.Equal(t, time.Unix(100, 0), root.StartTime())
+	assert.Equal(t, time.Unix(103, 0), root.EndTime())
+	assert.Equal(t, "init config", ended[0].Name())
+	assert.Equal(t, root.SpanContext().SpanID(), ended[0].Parent().SpanID())
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+
+	recorded := map[string]int{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			hist, ok := m.Data.(metricdata.Histogram[float64])
+			require.True(t, ok)
+			for _, dp := range hist.DataPoints {
+				recorded[m.Name] += int(dp.Count)
+			}
+		}
+	}
+	assert.Equal(t, map[string]int{"faas.init.duration": 1, "faas.init.phase.duration": 2}, recorded)
+}
+
+type countingSpanExporter struct {
+	exported int
+	shutdown bool
+}
+
+func (e *countingSpanExporter) ExportSpans(_ context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.exported += len(spans)
+	return nil
+}
+
+func (e *countingSpanExporter) Shutdown(context.Context) error {
+	e.shutdown = true
+	return nil
+}
+
+func TestLazySpanExporter(t *testing.T) {
+	builds := 0
+	inner := &countingSpanExporter{}
+	exporter := newLazySpanExporter(func() (sdktrace.SpanExporter, error) {
+		builds++
+		return inner, nil
+	})
+
+	require.NoError(t, exporter.Shutdown(context.Background()))
+	assert.Zero(t, builds, "shutdown must not build an unused exporter")
+
+	require.NoError(t, exporter.ExportSpans(context.Background(), make([]sdktrace.ReadOnlySpan, 2)))
+	require.NoError(t, exporter.ExportSpans(context.Background(), make([]sdktrace.ReadOnlySpan, 1)))
+	require.NoError(t, exporter.Shutdown(context.Background()))
+
+	assert.Equal(t, 1, builds)
+	assert.Equal(t, 3, inner.exported)
+	assert.True(t, inner.shutdown)
+}
@@ -0,0 +1,186 @@
+package telemetry
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/savisec/hello-go/internal/logging"
+)
+
+const (
+	tracerName = "github.com/savisec/hello-go/internal/telemetry"
+
+	defaultFlushTimeout = 2 * time.Second
+)
+
+// invocationTracer wraps Lambda invocations in a span carrying FaaS
+// attributes and flushes buffered telemetry when an invocation finishes.
+// Without the flush, spans sit in the batch processor while the execution
+// environment is frozen and may never be exported.
+type invocationTracer struct {
+	provider     *Provider
+	tracer       trace.Tracer
+	flushTimeout time.Duration
+	warm         atomic.Bool
+}
+
+func newInvocationTracer(provider *Provider, flushTimeout time.Duration) *invocationTracer {
+	if flushTimeout <= 0 {
+		flushTimeout = defaultFlushTimeout
+	}
+
+	return &invocationTracer{
+		provider:     provider,
+		tracer:       otel.Tracer(tracerName),
+		flushTimeout: flushTimeout,
+	}
+}
+
+func (t *invocationTracer) start(ctx context.Context) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, spanName(),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(t.invocationAttributes(ctx)...),
+	)
+}
+
+// finish ends span and exports buffered telemetry, bounded by the flush
+// timeout and by the invocation deadline.
+func (t *invocationTracer) finish(ctx context.Context, span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+
+	flushCtx, cancel := context.WithTimeout(ctx, t.flushTimeout)
+	defer cancel()
+
+	logging.Flush(flushCtx)
+	if err := t.provider.ForceFlush(flushCtx); err != nil {
+		slog.Warn("Failed to flush telemetry", "error", err)
+	}
+}
+
+func (t *invocationTracer) invocationAttributes(ctx context.Context) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.CloudProviderAWS,
+		semconv.CloudPlatformAWSLambda,
+		semconv.FaaSColdstart(!t.warm.Swap(true)),
+	}
+
+	if lambdacontext.FunctionName != "" {
+		attrs = append(attrs, semconv.FaaSName(lambdacontext.FunctionName))
+	}
+	if lambdacontext.FunctionVersion != "" {
+		attrs = append(attrs, semconv.FaaSVersion(lambdacontext.FunctionVersion))
+	}
+
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		attrs = append(attrs,
+			semconv.FaaSInvocationID(lc.AwsRequestID),
+			semconv.CloudResourceID(lc.InvokedFunctionArn),
+		)
+	}
+
+	return attrs
+}
+
+func spanName() string {
+	if lambdacontext.FunctionName != "" {
+		return lambdacontext.FunctionName
+	}
+	return "lambda.invoke"
+}
+
+// LambdaHandler wraps a lambda.Handler so that every invocation is traced
+// and telemetry is flushed before the invocation returns.
+type LambdaHandler struct {
+	next   lambda.Handler
+	tracer *invocationTracer
+}
+
+// NewLambdaHandler wraps next. A non-positive flushTimeout uses a default.
+func NewLambdaHandler(next lambda.Handler, provider *Provider, flushTimeout time.Duration) *LambdaHandler {
+	return &LambdaHandler{
+		next:   next,
+		tracer: newInvocationTracer(provider, flushTimeout),
+	}
+}
+
+// Invoke implements lambda.Handler.
+func (h *LambdaHandler) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	ctx, span := h.tracer.start(ctx)
+
+	resp, err := h.next.Invoke(ctx, payload)
+
+	h.tracer.finish(ctx, span, err)
+
+	return resp, err
+}
+
+// StreamingFunc is the signature of a Lambda response streaming handler.
+type StreamingFunc func(context.Context, events.LambdaFunctionURLRequest) (*events.LambdaFunctionURLStreamingResponse, error)
+
+// WrapStreaming traces a streaming handler. Because the body is still being
+// produced after the handler returns, the span ends and telemetry is flushed
+// once the runtime has read the whole body.
+func WrapStreaming(next StreamingFunc, provider *Provider, flushTimeout time.Duration) StreamingFunc {
+	tracer := newInvocationTracer(provider, flushTimeout)
+
+	return func(ctx context.Context, req events.LambdaFunctionURLRequest) (*events.LambdaFunctionURLStreamingResponse, error) {
+		ctx, span := tracer.start(ctx)
+
+		resp, err := next(ctx, req)
+		if err != nil || resp == nil || resp.Body == nil {
+			tracer.finish(ctx, span, err)
+			return resp, err
+		}
+
+		resp.Body = &finishOnEOF{
+			Reader: resp.Body,
+			finish: func(err error) { tracer.finish(ctx, span, err) },
+		}
+		return resp, nil
+	}
+}
+
+// finishOnEOF calls finish exactly once, when the wrapped reader is drained
+// or fails.
+type finishOnEOF struct {
+	io.Reader
+	finish func(error)
+	once   sync.Once
+}
+
+func (f *finishOnEOF) Read(p []byte) (int, error) {
+	n, err := f.Reader.Read(p)
+	if err != nil {
+		var bodyErr error
+		if err != io.EOF {
+			bodyErr = err
+		}
+		f.once.Do(func() { f.finish(bodyErr) })
+	}
+	return n, err
+}
+
+func (f *finishOnEOF) Close() error {
+	f.once.Do(func() { f.finish(nil) })
+	if closer, ok := f.Reader.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+type lambdaHandlerFunc func(ctx context.Context, payload []byte) ([]byte, error)
+
+func (f lambdaHandlerFunc) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	return f(ctx, payload)
+}
+
+func newTestProvider(t *testing.T) (*Provider, *tracetest.InMemoryExporter) {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	// A batch timeout far beyond the test duration proves spans are exported
+	// by the explicit flush rather than by the batcher's own schedule.
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter, sdktrace.WithBatchTimeout(time.Hour)))
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+
+	return &Provider{tracerProvider: tp}, exporter
+}
+
+func spanAttributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value, len(span.Attributes))
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestLambdaHandler_FlushesSpanPerInvocation(t *testing.T) {
+	provider, exporter := newTestProvider(t)
+
+	handler := NewLambdaHandler(lambdaHandlerFunc(func(ctx context.Context, payload []byte) ([]byte, error) {
+		return payload, nil
+	}), provider, time.Second)
+
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{
+		AwsRequestID:       "req-1",
+		InvokedFunctionArn: "arn:aws:lambda:us-west-2:123456789012:function:hello-go",
+	})
+
+	resp, err := handler.Invoke(ctx, []byte(`{}`))
+	require.NoError(t, err)
+	assert.Equal(t, []byte(`{}`), resp)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1, "span must be exported before Invoke returns")
+
+	attrs := spanAttributes(spans[0])
+	assert.True(t, attrs[semconv.FaaSColdstartKey].AsBool())
+	assert.Equal(t, "req-1", attrs[semconv.FaaSInvocationIDKey].AsString())
+	assert.Equal(t, "arn:aws:lambda:us-west-2:123456789012:function:hello-go", attrs[semconv.CloudResourceIDKey].AsString())
+
+	exporter.Reset()
+	_, err = handler.Invoke(ctx, []byte(`{}`))
+	require.NoError(t, err)
+
+	spans = exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.False(t, spanAttributes(spans[0])[semconv.FaaSColdstartKey].AsBool())
+}
+
+func TestLambdaHandler_RecordsErrors(t *testing.T) {
+	provider, exporter := newTestProvider(t)
+
+	handler := NewLambdaHandler(lambdaHandlerFunc(func(context.Context, []byte) ([]byte, error) {
+		return nil, errors.New("boom")
+	}), provider, 0)
+
+	_, err := handler.Invoke(context.Background(), nil)
+	require.EqualError(t, err, "boom")
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+}
+
+func TestWrapStreaming_FlushesAfterBodyIsRead(t *testing.T) {
+	provider, exporter := newTestProvider(t)
+
+	handler := WrapStreaming(func(context.Context, events.LambdaFunctionURLRequest) (*events.LambdaFunctionURLStreamingResponse, error) {
+		return &events.LambdaFunctionURLStreamingResponse{
+			StatusCode: 200,
+			Body:       strings.NewReader("streamed"),
+		}, nil
+	}, provider, time.Second)
+
+	resp, err := handler(context.Background(), events.LambdaFunctionURLRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, exporter.GetSpans(), "span stays open while the body streams")
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "streamed", string(body))
+	assert.Len(t, exporter.GetSpans(), 1)
+}
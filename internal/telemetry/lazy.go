@@ -0,0 +1,105 @@
+package telemetry
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/trace"
+)
+
+// lazy builds a value on first use. Exporters are wrapped in it so that
+// their setup, which for network exporters means dialing and loading
+// credentials, happens on the first export instead of during a cold start.
+type lazy[T any] struct {
+	build func() (T, error)
+	value T
+	err   error
+	mu    sync.Mutex
+	built bool
+}
+
+func (l *lazy[T]) get() (T, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.built {
+		l.value, l.err = l.build()
+		l.built = true
+	}
+	return l.value, l.err
+}
+
+// ifBuilt calls fn with the value only if it has already been built, so
+// flushing or shutting down never builds an exporter that was never used.
+func (l *lazy[T]) ifBuilt(fn func(T) error) error {
+	l.mu.Lock()
+	built, value, err := l.built, l.value, l.err
+	l.mu.Unlock()
+
+	if !built || err != nil {
+		return nil
+	}
+	return fn(value)
+}
+
+type lazySpanExporter struct {
+	exporter lazy[trace.SpanExporter]
+}
+
+func newLazySpanExporter(build func() (trace.SpanExporter, error)) *lazySpanExporter {
+	return &lazySpanExporter{exporter: lazy[trace.SpanExporter]{build: build}}
+}
+
+func (e *lazySpanExporter) ExportSpans(ctx context.Context, spans []trace.ReadOnlySpan) error {
+	exporter, err := e.exporter.get()
+	if err != nil {
+		return err
+	}
+	return exporter.ExportSpans(ctx, spans)
+}
+
+func (e *lazySpanExporter) Shutdown(ctx context.Context) error {
+	return e.exporter.ifBuilt(func(exporter trace.SpanExporter) error {
+		return exporter.Shutdown(ctx)
+	})
+}
+
+// lazyMetricExporter reports the SDK's default temporality and aggregation,
+// which the reader asks for before anything is exported.
+type lazyMetricExporter struct {
+	exporter lazy[metric.Exporter]
+}
+
+func newLazyMetricExporter(build func() (metric.Exporter, error)) *lazyMetricExporter {
+	return &lazyMetricExporter{exporter: lazy[metric.Exporter]{build: build}}
+}
+
+func (e *lazyMetricExporter) Temporality(kind metric.InstrumentKind) metricdata.Temporality {
+	return metric.DefaultTemporalitySelector(kind)
+}
+
+func (e *lazyMetricExporter) Aggregation(kind metric.InstrumentKind) metric.Aggregation {
+	return metric.DefaultAggregationSelector(kind)
+}
+
+func (e *lazyMetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	exporter, err := e.exporter.get()
+	if err != nil {
+		return err
+	}
+	return exporter.Export(ctx, rm)
+}
+
+func (e *lazyMetricExporter) ForceFlush(ctx context.Context) error {
+	return e.exporter.ifBuilt(func(exporter metric.Exporter) error {
+		return exporter.ForceFlush(ctx)
+	})
+}
+
+func (e *lazyMetricExporter) Shutdown(ctx context.Context) error {
+	return e.exporter.ifBuilt(func(exporter metric.Exporter) error {
+		return exporter.Shutdown(ctx)
+	})
+}
@@ -2,11 +2,19 @@ package telemetry
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"os"
+	"strings"
 
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
 	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	"go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
@@ -15,13 +23,21 @@ import (
 	"github.com/savisec/hello-go/internal/config"
 )
 
+const (
+	exporterStdout = "stdout"
+	exporterOTLP   = "otlp"
+	exporterEMF    = "emf"
+)
+
 type Provider struct {
 	tracerProvider *trace.TracerProvider
+	meterProvider  *metric.MeterProvider
 }
 
 func Setup(ctx context.Context, cfg config.TelemetryConfig) (*Provider, error) {
 	if !cfg.Enabled {
 		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetMeterProvider(metricnoop.NewMeterProvider())
 		return &Provider{}, nil
 	}
 
@@ -35,33 +51,125 @@ func Setup(ctx context.Context, cfg config.TelemetryConfig) (*Provider, error) {
 		return nil, fmt.Errorf("failed to create resource: %w", err)
 	}
 
-	traceExporter, err := stdouttrace.New(
-		stdouttrace.WithPrettyPrint(),
-	)
+	traceExporter, metricExporter, err := newExporters(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+		return nil, err
+	}
+
+	tracerOpts := []trace.TracerProviderOption{trace.WithResource(res)}
+	if traceExporter != nil {
+		tracerOpts = append(tracerOpts, trace.WithBatcher(traceExporter))
 	}
+	tracerProvider := trace.NewTracerProvider(tracerOpts...)
 
-	tracerProvider := trace.NewTracerProvider(
-		trace.WithBatcher(traceExporter),
-		trace.WithResource(res),
+	meterProvider := metric.NewMeterProvider(
+		metric.WithReader(metric.NewPeriodicReader(metricExporter)),
+		metric.WithResource(res),
 	)
 
 	otel.SetTracerProvider(tracerProvider)
+	otel.SetMeterProvider(meterProvider)
 
 	return &Provider{
 		tracerProvider: tracerProvider,
+		meterProvider:  meterProvider,
 	}, nil
 }
 
+// newExporters returns the span and metric exporters selected by
+// cfg.Exporter. Stdout and OTLP exporters are built lazily, on first export.
+// EMF has no span exporter: spans are still created, for trace context, but
+// not exported.
+func newExporters(cfg config.TelemetryConfig) (trace.SpanExporter, metric.Exporter, error) {
+	switch cfg.Exporter {
+	case "", exporterStdout:
+		traceExporter := newLazySpanExporter(func() (trace.SpanExporter, error) {
+			exporter, err := stdouttrace.New(
+				stdouttrace.WithPrettyPrint(),
+			)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+			}
+			return exporter, nil
+		})
+
+		metricExporter := newLazyMetricExporter(func() (metric.Exporter, error) {
+			exporter, err := stdoutmetric.New()
+			if err != nil {
+				return nil, fmt.Errorf("failed to create metric exporter: %w", err)
+			}
+			return exporter, nil
+		})
+
+		return traceExporter, metricExporter, nil
+	case exporterOTLP:
+		if cfg.Endpoint == "" {
+			return nil, nil, fmt.Errorf("telemetry exporter %q requires an endpoint", cfg.Exporter)
+		}
+		endpoint := strings.TrimSuffix(cfg.Endpoint, "/")
+
+		traceExporter := newLazySpanExporter(func() (trace.SpanExporter, error) {
+			exporter, err := otlptracehttp.New(context.Background(),
+				otlptracehttp.WithEndpointURL(endpoint+"/v1/traces"),
+			)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+			}
+			return exporter, nil
+		})
+
+		metricExporter := newLazyMetricExporter(func() (metric.Exporter, error) {
+			exporter, err := otlpmetrichttp.New(context.Background(),
+				otlpmetrichttp.WithEndpointURL(endpoint+"/v1/metrics"),
+			)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create metric exporter: %w", err)
+			}
+			return exporter, nil
+		})
+
+		return traceExporter, metricExporter, nil
+	case exporterEMF:
+		return nil, NewEMFExporter(os.Stdout, cfg.ServiceName), nil
+	default:
+		return nil, nil, fmt.Errorf("unknown telemetry exporter %q", cfg.Exporter)
+	}
+}
+
+// ForceFlush exports all buffered spans and metrics without shutting the
+// providers down. It is used where the process may be frozen between
+// requests, such as at the end of a Lambda invocation.
+func (p *Provider) ForceFlush(ctx context.Context) error {
+	var errs []error
+
+	if p.tracerProvider != nil {
+		if err := p.tracerProvider.ForceFlush(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to flush tracer provider: %w", err))
+		}
+	}
+
+	if p.meterProvider != nil {
+		if err := p.meterProvider.ForceFlush(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to flush meter provider: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
 func (p *Provider) Shutdown(ctx context.Context) error {
-	if p.tracerProvider == nil {
-		return nil
+	if p.tracerProvider != nil {
+		if err := p.tracerProvider.Shutdown(ctx); err != nil {
+			slog.Error("Failed to shutdown tracer provider", "error", err)
+			return err
+		}
 	}
 
-	if err := p.tracerProvider.Shutdown(ctx); err != nil {
-		slog.Error("Failed to shutdown tracer provider", "error", err)
-		return err
+	if p.meterProvider != nil {
+		if err := p.meterProvider.Shutdown(ctx); err != nil {
+			slog.Error("Failed to shutdown meter provider", "error", err)
+			return err
+		}
 	}
 
 	return nil
@@ -0,0 +1,220 @@
+// Package tenancy attributes API requests to the tenants sharing a
+// deployment and holds each tenant's settings.
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/savisec/hello-go/internal/auth"
+	"github.com/savisec/hello-go/internal/config"
+	"github.com/savisec/hello-go/internal/lambdactx"
+	"github.com/savisec/hello-go/internal/signing"
+)
+
+// Sources a tenant can be resolved from.
+const (
+	SourceHeader    = "header"
+	SourceSubdomain = "subdomain"
+	SourceAPIKey    = "api_key"
+	SourceJWTClaim  = "jwt_claim"
+	sourceDefault   = "default"
+
+	defaultHeader = "X-Tenant-ID"
+	defaultClaim  = "tenant"
+)
+
+var (
+	sources = []string{SourceHeader, SourceSubdomain, SourceAPIKey, SourceJWTClaim}
+	// defaultSources leave out subdomain, which needs a domain.
+	defaultSources = []string{SourceHeader, SourceAPIKey, SourceJWTClaim}
+)
+
+var (
+	// ErrNoTenant is returned when no source names a tenant and there is
+	// no default tenant.
+	ErrNoTenant = errors.New("no tenant")
+	// ErrUnknownTenant is returned for a tenant that is not configured.
+	ErrUnknownTenant = errors.New("unknown tenant")
+	// ErrTenantMismatch is returned when a header or subdomain names a
+	// tenant other than the one of the caller's credentials.
+	ErrTenantMismatch = errors.New("tenant does not match credentials")
+	// ErrTenantNotBound is returned, when requests are authenticated, for
+	// a header or subdomain naming a tenant the caller's credentials are
+	// neither bound to nor listed as a principal of.
+	ErrTenantNotBound = errors.New("credentials are not bound to tenant")
+)
+
+// Tenant is a tenant and its settings.
+type Tenant struct {
+	Features map[string]bool
+	ID       string
+	// Principals may name the tenant by header or subdomain without
+	// credentials bound to it.
+	Principals []string
+	// MaxMessageBytes bounds echo messages; zero leaves them unbounded.
+	MaxMessageBytes int
+}
+
+// Enabled reports whether the tenant may call the operation. Operations
+// not listed in its features are enabled.
+func (t *Tenant) Enabled(operationID string) bool {
+	enabled, ok := t.Features[operationID]
+	return !ok || enabled
+}
+
+type ctxKey struct{}
+
+// NewContext returns a copy of ctx carrying t.
+func NewContext(ctx context.Context, t *Tenant) context.Context {
+	return context.WithValue(ctx, ctxKey{}, t)
+}
+
+// FromContext returns the Tenant stored in ctx by NewContext.
+func FromContext(ctx context.Context) (*Tenant, bool) {
+	t, ok := ctx.Value(ctxKey{}).(*Tenant)
+	return t, ok
+}
+
+// Resolver works out which tenant a request is for.
+type Resolver struct {
+	tenants       map[string]*Tenant
+	header        string
+	domain        string
+	claim         string
+	defaultTenant string
+	sources       []string
+	// authenticated restricts header and subdomain tenants to callers
+	// bound to them.
+	authenticated bool
+}
+
+// NewResolver creates a Resolver for the tenants and sources in cfg.
+// When authenticated is set, as it is whenever requests carry
+// credentials, a header or subdomain may only name a tenant the caller's
+// credentials are bound to or whose principals list the caller; anyone
+// else could otherwise pick any tenant, and its rate limits, at will.
+func NewResolver(cfg config.TenancyConfig, authenticated bool) (*Resolver, error) {
+	r := &Resolver{
+		tenants:       make(map[string]*Tenant, len(cfg.Tenants)),
+		header:        cfg.Header,
+		domain:        strings.ToLower(strings.Trim(cfg.Domain, ".")),
+		claim:         cfg.Claim,
+		defaultTenant: cfg.DefaultTenant,
+		sources:       defaultSources,
+		authenticated: authenticated,
+	}
+	if r.header == "" {
+		r.header = defaultHeader
+	}
+	if r.claim == "" {
+		r.claim = defaultClaim
+	}
+
+	if len(cfg.Sources) > 0 {
+		r.sources = make([]string, 0, len(cfg.Sources))
+		for _, source := range cfg.Sources {
+			source = strings.ToLower(source)
+			if !slices.Contains(sources, source) {
+				return nil, fmt.Errorf("unknown tenant source %q", source)
+			}
+			r.sources = append(r.sources, source)
+		}
+	}
+	if slices.Contains(r.sources, SourceSubdomain) && r.domain == "" {
+		return nil, errors.New("subdomain tenant source requires a domain")
+	}
+
+	for id, tc := range cfg.Tenants {
+		if tc.MaxMessageBytes < 0 {
+			return nil, fmt.Errorf("tenant %s: max_message_bytes must not be negative", id)
+		}
+		r.tenants[id] = &Tenant{ID: id, Features: tc.Features, Principals: tc.Principals, MaxMessageBytes: tc.MaxMessageBytes}
+	}
+	if r.defaultTenant != "" {
+		if _, ok := r.tenants[r.defaultTenant]; !ok {
+			return nil, fmt.Errorf("default tenant %q is not configured", r.defaultTenant)
+		}
+	}
+	return r, nil
+}
+
+// Resolve returns the tenant of req and the source that named it. The
+// tenant of the caller's credentials is authoritative: a header or
+// subdomain may repeat it but not name another. The first source, in
+// configured order, that names a tenant wins otherwise, subject to the
+// binding NewResolver describes.
+func (r *Resolver) Resolve(req *http.Request, rc lambdactx.RequestContext) (*Tenant, string, error) {
+	var claimed, claimedFrom, bound, boundFrom string
+	for _, source := range r.sources {
+		id := r.lookup(source, req, rc)
+		if id == "" {
+			continue
+		}
+		switch source {
+		case SourceAPIKey, SourceJWTClaim:
+			if bound == "" {
+				bound, boundFrom = id, source
+			}
+		default:
+			if claimed == "" {
+				claimed, claimedFrom = id, source
+			}
+		}
+	}
+
+	id, source := claimed, claimedFrom
+	if bound != "" {
+		if claimed != "" && claimed != bound {
+			return nil, claimedFrom, fmt.Errorf("%w: %s names %q, credentials %q", ErrTenantMismatch, claimedFrom, claimed, bound)
+		}
+		id, source = bound, boundFrom
+	}
+	if id == "" {
+		if r.defaultTenant == "" {
+			return nil, "", ErrNoTenant
+		}
+		id, source = r.defaultTenant, sourceDefault
+	}
+
+	t, ok := r.tenants[id]
+	if !ok {
+		return nil, source, fmt.Errorf("%w %q", ErrUnknownTenant, id)
+	}
+	if r.authenticated && bound == "" && id == claimed && !t.allows(rc.Identity) {
+		return nil, source, fmt.Errorf("%w %q named by %s", ErrTenantNotBound, id, source)
+	}
+	return t, source, nil
+}
+
+// allows reports whether the tenant's principals list the caller.
+func (t *Tenant) allows(identity *lambdactx.Identity) bool {
+	return identity != nil && slices.Contains(t.Principals, identity.Subject)
+}
+
+func (r *Resolver) lookup(source string, req *http.Request, rc lambdactx.RequestContext) string {
+	switch source {
+	case SourceHeader:
+		return strings.TrimSpace(req.Header.Get(r.header))
+	case SourceSubdomain:
+		host := strings.ToLower(strings.TrimSuffix(rc.DomainName, "."))
+		label, ok := strings.CutSuffix(host, "."+r.domain)
+		if !ok || label == "" || strings.Contains(label, ".") {
+			return ""
+		}
+		return label
+	case SourceAPIKey:
+		if id := rc.Identity; id != nil && (id.Authorizer == auth.AuthorizerAPIKey || id.Authorizer == signing.AuthorizerHMAC) {
+			return id.Claims[auth.TenantClaim]
+		}
+	case SourceJWTClaim:
+		if id := rc.Identity; id != nil && id.Authorizer == auth.AuthorizerJWT {
+			return id.Claims[r.claim]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,130 @@
+package tenancy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/internal/auth"
+	"github.com/savisec/hello-go/internal/config"
+	"github.com/savisec/hello-go/internal/lambdactx"
+	"github.com/savisec/hello-go/internal/signing"
+)
+
+func TestResolver_Resolve(t *testing.T) {
+	tenants := map[string]config.TenantConfig{"team-a": {}, "team-b": {Principals: []string{"ops"}}}
+	all := config.TenancyConfig{
+		Tenants: tenants,
+		Sources: []string{"header", "subdomain", "api_key", "jwt_claim"},
+		Domain:  "api.example.com",
+		Claim:   "org",
+	}
+	withDefault := all
+	withDefault.DefaultTenant = "team-b"
+
+	apiKey := func(tenant string) *lambdactx.Identity {
+		return &lambdactx.Identity{Authorizer: auth.AuthorizerAPIKey, Claims: map[string]string{"key_id": "k", auth.TenantClaim: tenant}}
+	}
+	unbound := func(subject string) *lambdactx.Identity {
+		return &lambdactx.Identity{Subject: subject, Authorizer: auth.AuthorizerAPIKey, Claims: map[string]string{"key_id": "k"}}
+	}
+
+	tests := []struct {
+		identity      *lambdactx.Identity
+		wantErr       error
+		name          string
+		header        string
+		host          string
+		want          string
+		wantSource    string
+		cfg           config.TenancyConfig
+		authenticated bool
+	}{
+		{name: "header", cfg: all, header: "team-a", want: "team-a", wantSource: SourceHeader},
+		{name: "subdomain", cfg: all, host: "team-a.api.example.com", want: "team-a", wantSource: SourceSubdomain},
+		{name: "nested subdomain", cfg: all, host: "x.team-a.api.example.com", wantErr: ErrNoTenant},
+		{name: "api key", cfg: all, identity: apiKey("team-a"), want: "team-a", wantSource: SourceAPIKey},
+		{
+			name: "signing key", cfg: all, want: "team-a", wantSource: SourceAPIKey,
+			identity: &lambdactx.Identity{Authorizer: signing.AuthorizerHMAC, Claims: map[string]string{auth.TenantClaim: "team-a"}},
+		},
+		{
+			name: "jwt claim", cfg: all, want: "team-b", wantSource: SourceJWTClaim,
+			identity: &lambdactx.Identity{Authorizer: auth.AuthorizerJWT, Claims: map[string]string{"org": "team-b", "tenant": "team-a"}},
+		},
+		{name: "header agrees with credentials", cfg: all, header: "team-a", identity: apiKey("team-a"), want: "team-a", wantSource: SourceAPIKey},
+		{name: "header contradicts credentials", cfg: all, header: "team-b", identity: apiKey("team-a"), wantErr: ErrTenantMismatch},
+		{name: "subdomain contradicts credentials", cfg: all, host: "team-b.api.example.com", identity: apiKey("team-a"), wantErr: ErrTenantMismatch},
+		{name: "unknown tenant", cfg: all, header: "team-z", wantErr: ErrUnknownTenant},
+		{name: "no tenant", cfg: all, wantErr: ErrNoTenant},
+		{name: "default tenant", cfg: withDefault, want: "team-b", wantSource: sourceDefault},
+		{
+			name: "authenticated, bound credentials", cfg: all, authenticated: true,
+			header: "team-a", identity: apiKey("team-a"), want: "team-a", wantSource: SourceAPIKey,
+		},
+		{name: "authenticated, unbound credentials", cfg: all, authenticated: true, header: "team-a", identity: unbound("alice"), wantErr: ErrTenantNotBound},
+		{name: "authenticated, unbound subdomain", cfg: all, authenticated: true, host: "team-a.api.example.com", identity: unbound("alice"), wantErr: ErrTenantNotBound},
+		{name: "authenticated, anonymous", cfg: all, authenticated: true, header: "team-a", wantErr: ErrTenantNotBound},
+		{
+			name: "authenticated, listed principal", cfg: all, authenticated: true,
+			header: "team-b", identity: unbound("ops"), want: "team-b", wantSource: SourceHeader,
+		},
+		{name: "authenticated, principal of another tenant", cfg: all, authenticated: true, header: "team-a", identity: unbound("ops"), wantErr: ErrTenantNotBound},
+		{name: "authenticated, default tenant", cfg: withDefault, authenticated: true, identity: unbound("alice"), want: "team-b", wantSource: sourceDefault},
+		{
+			name: "sources not configured are ignored", header: "team-a", identity: apiKey("team-b"), want: "team-a", wantSource: SourceHeader,
+			cfg: config.TenancyConfig{Tenants: tenants, Sources: []string{"header"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewResolver(tt.cfg, tt.authenticated)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/echo", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Tenant-ID", tt.header)
+			}
+			rc := lambdactx.RequestContext{DomainName: tt.host, Identity: tt.identity}
+
+			tenant, source, err := r.Resolve(req, rc)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, tenant.ID)
+			assert.Equal(t, tt.wantSource, source)
+		})
+	}
+}
+
+func TestTenant_Enabled(t *testing.T) {
+	tenant := &Tenant{Features: map[string]bool{"echo": false, "readyz": true}}
+	assert.False(t, tenant.Enabled("echo"))
+	assert.True(t, tenant.Enabled("readyz"))
+	assert.True(t, tenant.Enabled("healthz"), "unlisted operations are enabled")
+}
+
+func TestNewResolver_Validates(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.TenancyConfig
+	}{
+		{name: "unknown source", cfg: config.TenancyConfig{Sources: []string{"cookie"}}},
+		{name: "subdomain without domain", cfg: config.TenancyConfig{Sources: []string{"subdomain"}}},
+		{name: "unknown default tenant", cfg: config.TenancyConfig{DefaultTenant: "team-a"}},
+		{name: "negative message limit", cfg: config.TenancyConfig{Tenants: map[string]config.TenantConfig{"team-a": {MaxMessageBytes: -1}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewResolver(tt.cfg, false)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package triggers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+
+	"github.com/savisec/hello-go/internal/api"
+	"github.com/savisec/hello-go/internal/services"
+)
+
+// Source identifies a non-HTTP Lambda trigger.
+type Source string
+
+const (
+	SourceSQS         Source = "sqs"
+	SourceSNS         Source = "sns"
+	SourceEventBridge Source = "eventbridge"
+)
+
+// sourceProbe holds just enough of an event to tell trigger shapes apart.
+// SQS uses "eventSource" and SNS "EventSource"; JSON field matching is
+// case-insensitive, so one field covers both.
+type sourceProbe struct {
+	DetailType string `json:"detail-type"`
+	Records    []struct {
+		EventSource string `json:"eventSource"`
+	} `json:"Records"`
+}
+
+// DetectSource reports which trigger produced payload, if it is one of the
+// supported non-HTTP sources.
+func DetectSource(payload []byte) (Source, bool) {
+	var probe sourceProbe
+	if err := json.Unmarshal(payload, &probe); err != nil {
+		return "", false
+	}
+
+	switch {
+	case probe.DetailType != "":
+		return SourceEventBridge, true
+	case len(probe.Records) > 0 && probe.Records[0].EventSource == "aws:sqs":
+		return SourceSQS, true
+	case len(probe.Records) > 0 && probe.Records[0].EventSource == "aws:sns":
+		return SourceSNS, true
+	default:
+		return "", false
+	}
+}
+
+// Handler is a lambda.Handler that feeds SQS, SNS and EventBridge payloads
+// carrying an api.EchoMessage to the echo service. Any other payload is
+// passed on to next.
+type Handler struct {
+	next        lambda.Handler
+	echoService *services.EchoService
+	publisher   Publisher
+	logger      *slog.Logger
+}
+
+// NewHandler creates a Handler that falls back to next for other events.
+func NewHandler(echoService *services.EchoService, publisher Publisher, next lambda.Handler, logger *slog.Logger) *Handler {
+	return &Handler{
+		next:        next,
+		echoService: echoService,
+		publisher:   publisher,
+		logger:      logger,
+	}
+}
+
+// Invoke implements lambda.Handler.
+func (h *Handler) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	source, ok := DetectSource(payload)
+	if !ok {
+		return h.next.Invoke(ctx, payload)
+	}
+
+	var (
+		resp any
+		err  error
+	)
+	switch source {
+	case SourceSQS:
+		resp, err = h.handleSQS(ctx, payload)
+	case SourceSNS:
+		resp, err = h.handleSNS(ctx, payload)
+	case SourceEventBridge:
+		resp, err = h.handleEventBridge(ctx, payload)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(resp)
+}
+
+// handleSQS processes a batch and reports failed messages individually, so
+// that only those are retried. The event source mapping must have
+// ReportBatchItemFailures enabled.
+func (h *Handler) handleSQS(ctx context.Context, payload []byte) (events.SQSEventResponse, error) {
+	var event events.SQSEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return events.SQSEventResponse{}, fmt.Errorf("failed to decode SQS event: %w", err)
+	}
+
+	resp := events.SQSEventResponse{
+		BatchItemFailures: []events.SQSBatchItemFailure{},
+	}
+	for _, record := range event.Records {
+		if _, err := h.process(ctx, []byte(record.Body)); err != nil {
+			h.logger.ErrorContext(ctx, "Failed to process SQS message", "message_id", record.MessageId, "error", err)
+			resp.BatchItemFailures = append(resp.BatchItemFailures, events.SQSBatchItemFailure{
+				ItemIdentifier: record.MessageId,
+			})
+		}
+	}
+
+	return resp, nil
+}
+
+func (h *Handler) handleSNS(ctx context.Context, payload []byte) ([]api.EchoMessage, error) {
+	var event events.SNSEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode SNS event: %w", err)
+	}
+
+	var (
+		results []api.EchoMessage
+		errs    []error
+	)
+	for _, record := range event.Records {
+		result, err := h.process(ctx, []byte(record.SNS.Message))
+		if err != nil {
+			h.logger.ErrorContext(ctx, "Failed to process SNS notification", "message_id", record.SNS.MessageID, "error", err)
+			errs = append(errs, fmt.Errorf("notification %s: %w", record.SNS.MessageID, err))
+			continue
+		}
+		results = append(results, result)
+	}
+
+	return results, errors.Join(errs...)
+}
+
+func (h *Handler) handleEventBridge(ctx context.Context, payload []byte) (api.EchoMessage, error) {
+	var event events.EventBridgeEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return api.EchoMessage{}, fmt.Errorf("failed to decode EventBridge event: %w", err)
+	}
+
+	result, err := h.process(ctx, event.Detail)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Failed to process EventBridge event", "event_id", event.ID, "error", err)
+		return api.EchoMessage{}, err
+	}
+
+	return result, nil
+}
+
+// process decodes, validates and echoes one message, then publishes the result.
+func (h *Handler) process(ctx context.Context, body []byte) (api.EchoMessage, error) {
+	var msg api.EchoMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return api.EchoMessage{}, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	if err := services.ValidateEchoMessage(msg); err != nil {
+		return api.EchoMessage{}, err
+	}
+
+	result := h.echoService.Echo(ctx, msg)
+
+	if err := h.publisher.Publish(ctx, result); err != nil {
+		return api.EchoMessage{}, err
+	}
+
+	return result, nil
+}
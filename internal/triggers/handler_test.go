@@ -0,0 +1,149 @@
+package triggers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/savisec/hello-go/internal/api"
+	"github.com/savisec/hello-go/internal/config"
+	"github.com/savisec/hello-go/internal/logging"
+	"github.com/savisec/hello-go/internal/services"
+	"github.com/savisec/hello-go/internal/triggers"
+)
+
+type recordingPublisher struct {
+	err       error
+	published []api.EchoMessage
+	mu        sync.Mutex
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, msg api.EchoMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, msg)
+	return nil
+}
+
+type fallbackHandler struct {
+	called bool
+}
+
+func (f *fallbackHandler) Invoke(context.Context, []byte) ([]byte, error) {
+	f.called = true
+	return []byte(`"http"`), nil
+}
+
+func newTestHandler(publisher triggers.Publisher) (*triggers.Handler, *fallbackHandler) {
+	logger := slog.New(slog.DiscardHandler)
+	fallback := &fallbackHandler{}
+	return triggers.NewHandler(services.NewEchoService(logger, logging.NewFingerprinter("test")), publisher, fallback, logger), fallback
+}
+
+func loadFixture(t *testing.T, name string) []byte {
+	t.Helper()
+
+	payload, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return payload
+}
+
+func TestHandler_SQSPartialBatchFailure(t *testing.T) {
+	publisher := &recordingPublisher{}
+	handler, _ := newTestHandler(publisher)
+
+	out, err := handler.Invoke(context.Background(), loadFixture(t, "sqs.json"))
+	require.NoError(t, err)
+
+	var resp events.SQSEventResponse
+	require.NoError(t, json.Unmarshal(out, &resp))
+
+	var failed []string
+	for _, f := range resp.BatchItemFailures {
+		failed = append(failed, f.ItemIdentifier)
+	}
+	assert.Equal(t, []string{
+		"2e1424d4-f796-459a-8184-9c92662be6da",
+		"5d3a4b2c-1f0e-4d9c-8b7a-6e5f4d3c2b1a",
+	}, failed)
+	assert.Equal(t, []api.EchoMessage{{Message: "Hello from SQS", Author: "queue"}}, publisher.published)
+}
+
+func TestHandler_SNS(t *testing.T) {
+	publisher := &recordingPublisher{}
+	handler, _ := newTestHandler(publisher)
+
+	out, err := handler.Invoke(context.Background(), loadFixture(t, "sns.json"))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `[{"message":"Hello from SNS","author":"topic"}]`, string(out))
+	assert.Len(t, publisher.published, 1)
+}
+
+func TestHandler_EventBridge(t *testing.T) {
+	publisher := &recordingPublisher{}
+	handler, _ := newTestHandler(publisher)
+
+	out, err := handler.Invoke(context.Background(), loadFixture(t, "eventbridge.json"))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{"message":"Hello from EventBridge","author":"bus"}`, string(out))
+	assert.Len(t, publisher.published, 1)
+}
+
+func TestHandler_PublishFailureIsReported(t *testing.T) {
+	handler, _ := newTestHandler(&recordingPublisher{err: errors.New("destination down")})
+
+	_, err := handler.Invoke(context.Background(), loadFixture(t, "eventbridge.json"))
+
+	assert.ErrorContains(t, err, "destination down")
+}
+
+func TestHandler_FallsBackForHTTPEvents(t *testing.T) {
+	handler, fallback := newTestHandler(&recordingPublisher{})
+
+	out, err := handler.Invoke(context.Background(), []byte(`{"version":"2.0","rawPath":"/healthz"}`))
+	require.NoError(t, err)
+
+	assert.True(t, fallback.called)
+	assert.Equal(t, `"http"`, string(out))
+}
+
+func TestNewPublisher_HTTP(t *testing.T) {
+	var received api.EchoMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(server.Close)
+
+	publisher, err := triggers.NewPublisher(config.DestinationConfig{Type: "http", URL: server.URL}, slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+
+	msg := api.EchoMessage{Message: "hi", Author: "webhook"}
+	require.NoError(t, publisher.Publish(context.Background(), msg))
+	assert.Equal(t, msg, received)
+}
+
+func TestNewPublisher_Invalid(t *testing.T) {
+	_, err := triggers.NewPublisher(config.DestinationConfig{Type: "carrier-pigeon"}, nil)
+	require.Error(t, err)
+
+	_, err = triggers.NewPublisher(config.DestinationConfig{Type: "http"}, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,96 @@
+package triggers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/savisec/hello-go/internal/api"
+	"github.com/savisec/hello-go/internal/config"
+)
+
+const defaultPublishTimeout = 5 * time.Second
+
+// Publisher delivers the result of an asynchronously triggered echo.
+type Publisher interface {
+	Publish(ctx context.Context, msg api.EchoMessage) error
+}
+
+// NewPublisher creates the Publisher selected by cfg.
+func NewPublisher(cfg config.DestinationConfig, logger *slog.Logger) (Publisher, error) {
+	switch strings.ToLower(cfg.Type) {
+	case "none":
+		return discardPublisher{}, nil
+	case "", "log":
+		return &logPublisher{logger: logger}, nil
+	case "http":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("http destination requires a url")
+		}
+		timeout := cfg.Timeout
+		if timeout <= 0 {
+			timeout = defaultPublishTimeout
+		}
+		return &httpPublisher{
+			url:    cfg.URL,
+			client: &http.Client{Timeout: timeout},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown destination type %q", cfg.Type)
+	}
+}
+
+type discardPublisher struct{}
+
+func (discardPublisher) Publish(context.Context, api.EchoMessage) error {
+	return nil
+}
+
+// logPublisher records that a result was produced without logging its content.
+type logPublisher struct {
+	logger *slog.Logger
+}
+
+func (p *logPublisher) Publish(ctx context.Context, msg api.EchoMessage) error {
+	p.logger.InfoContext(ctx, "Published echo result", "message_length", len(msg.Message))
+	return nil
+}
+
+// httpPublisher POSTs each result as JSON to a webhook URL.
+type httpPublisher struct {
+	client *http.Client
+	url    string
+}
+
+func (p *httpPublisher) Publish(ctx context.Context, msg api.EchoMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode echo result: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create publish request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to publish echo result: %w", err)
+	}
+
+	//nolint:errcheck
+	//goland:noinspection GoUnhandledErrorResult
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("failed to publish echo result: destination returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
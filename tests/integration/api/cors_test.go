@@ -0,0 +1,67 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/savisec/hello-go/tests/integration/config"
+)
+
+func TestEchoCORSPreflight(t *testing.T) {
+	cfg := config.LoadConfig(t)
+	cfg.RequireCORS(t)
+
+	tests := []struct {
+		name       string
+		origin     string
+		headers    string
+		wantOrigin string
+	}{
+		{name: "allowed origin", origin: "http://localhost:3000", wantOrigin: "http://localhost:3000"},
+		{
+			name: "signed request", origin: "http://localhost:3000", wantOrigin: "http://localhost:3000",
+			headers: "content-type, x-signature, x-signature-key-id, x-signature-timestamp, x-signature-nonce, x-content-sha256",
+		},
+		{name: "wildcard subdomain", origin: "http://app.localhost:3000", wantOrigin: "http://app.localhost:3000"},
+		{name: "other origin", origin: "https://evil.example", wantOrigin: ""},
+	}
+
+	client := &http.Client{
+		Timeout: 5 * time.Second,
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodOptions, fmt.Sprintf("%s/v1/echo", cfg.Server.URL()), nil)
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", "POST")
+			headers := tt.headers
+			if headers == "" {
+				headers = "content-type, x-api-key"
+			}
+			req.Header.Set("Access-Control-Request-Headers", headers)
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Failed to send preflight: %v", err)
+			}
+			defer func() {
+				if err := resp.Body.Close(); err != nil {
+					t.Errorf("Error closing response body: %v", err)
+				}
+			}()
+
+			if resp.StatusCode != http.StatusNoContent {
+				t.Errorf("Expected status code 204, got %d", resp.StatusCode)
+			}
+			if got := resp.Header.Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Expected Access-Control-Allow-Origin '%s', got '%s'", tt.wantOrigin, got)
+			}
+		})
+	}
+}
@@ -2,12 +2,14 @@ package api
 
 import (
 	"context"
+	"encoding/hex"
 	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/savisec/hello-go/internal/api"
+	"github.com/savisec/hello-go/internal/signing"
 	"github.com/savisec/hello-go/tests/integration/config"
 )
 
@@ -16,7 +18,11 @@ func TestPOSTEcho(t *testing.T) {
 	cfg := config.LoadConfig(t)
 
 	// Create the generated client (point to correct server address).
-	client, err := api.NewClientWithResponses(cfg.Server.URL())
+	client, err := api.NewClientWithResponses(cfg.Server.URL(),
+		api.WithRequestEditorFn(func(ctx context.Context, req *http.Request) error {
+			req.Header.Set("X-API-Key", cfg.Auth.APIKey)
+			return nil
+		}))
 	require.NoError(t, err)
 
 	// Prepare a request body matching the EchoMessage struct.
@@ -35,3 +41,51 @@ func TestPOSTEcho(t *testing.T) {
 	require.Equal(t, request.Message, resp.JSON200.Message)
 	require.Equal(t, request.Author, resp.JSON200.Author)
 }
+
+func TestPOSTEchoRequiresAPIKey(t *testing.T) {
+	cfg := config.LoadConfig(t)
+	cfg.RequireAPIKey(t)
+
+	client, err := api.NewClientWithResponses(cfg.Server.URL())
+	require.NoError(t, err)
+
+	resp, err := client.EchoWithResponse(context.Background(), api.EchoMessage{
+		Message: "Hello, Echo!",
+		Author:  "IntegrationTest",
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode())
+	require.NotNil(t, resp.JSON401)
+}
+
+func TestPOSTEchoSigned(t *testing.T) {
+	cfg := config.LoadConfig(t)
+	cfg.RequireSigningKey(t)
+
+	secret, err := hex.DecodeString(cfg.Auth.SigningSecret)
+	require.NoError(t, err)
+
+	client, err := api.NewClientWithResponses(cfg.Server.URL(),
+		api.WithRequestEditorFn(signing.NewRequestSigner(cfg.Auth.SigningKeyID, secret)))
+	require.NoError(t, err)
+
+	request := api.EchoMessage{
+		Message: "Hello, signed Echo!",
+		Author:  "IntegrationTest",
+	}
+
+	resp, err := client.EchoWithResponse(context.Background(), request)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.NotNil(t, resp.JSON200)
+	require.Equal(t, request.Message, resp.JSON200.Message)
+
+	// A signature made with another secret is rejected.
+	badClient, err := api.NewClientWithResponses(cfg.Server.URL(),
+		api.WithRequestEditorFn(signing.NewRequestSigner(cfg.Auth.SigningKeyID, []byte("not-the-shared-secret"))))
+	require.NoError(t, err)
+
+	resp, err = badClient.EchoWithResponse(context.Background(), request)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode())
+}
@@ -17,6 +17,23 @@ import (
 type Config struct {
 	Server ServerConfig `mapstructure:"server"`
 	Lambda LambdaConfig `mapstructure:"lambda"`
+	Auth   AuthConfig   `mapstructure:"auth"`
+	CORS   CORSConfig   `mapstructure:"cors"`
+}
+
+type CORSConfig struct {
+	// Enabled says the servers answer CORS preflights for
+	// http://localhost:3000 and http://*.localhost:3000, as the
+	// configs/integration.yml written by setup allows.
+	Enabled bool `mapstructure:"enabled"`
+}
+
+type AuthConfig struct {
+	// APIKey is sent with requests to protected endpoints.
+	APIKey string `mapstructure:"api_key"`
+	// SigningKeyID and SigningSecret sign requests with HMAC.
+	SigningKeyID  string `mapstructure:"signing_key_id"`
+	SigningSecret string `mapstructure:"signing_secret"`
 }
 
 type ServerConfig struct {
@@ -27,6 +44,9 @@ type ServerConfig struct {
 
 type LambdaConfig struct {
 	Host string `mapstructure:"host"`
+	// Mode is the lambda.mode "lambda serve" runs with; streaming sends
+	// plain HTTP requests through the response streaming entrypoint.
+	Mode string `mapstructure:"mode"`
 	Port int    `mapstructure:"port"`
 }
 
@@ -48,6 +68,11 @@ func (s *ServerConfig) URL() string {
 	return fmt.Sprintf("%s://%s:%d", protocol, s.Host, s.Port)
 }
 
+// URL is where "lambda serve" accepts plain HTTP requests.
+func (l *LambdaConfig) URL() string {
+	return fmt.Sprintf("http://%s:%d", l.Host, l.Port)
+}
+
 func (l *LambdaConfig) InvocationURL() string {
 	return fmt.Sprintf("http://%s:%d/2015-03-31/functions/function/invocations", l.Host, l.Port)
 }
@@ -69,23 +94,32 @@ func loadConfig() (*Config, error) {
 	// Layer 4: Environment variables (highest precedence)
 	// TEST_SERVER_HOST -> server.host
 	// TEST_SERVER_PORT -> server.port
-	// TEST_LAMBDA_HOST -> lambda.host
+	// TEST_AUTH_API_KEY -> auth.api_key
+	keys := map[string]string{}
+	for _, key := range k.Keys() {
+		keys[strings.ReplaceAll(key, ".", "_")] = key
+	}
+	for _, key := range []string{"auth.api_key", "auth.signing_key_id", "auth.signing_secret", "cors.enabled"} {
+		keys[strings.ReplaceAll(key, ".", "_")] = key
+	}
 	if err := k.Load(env.Provider(".", env.Opt{
 		Prefix: "TEST_",
 		TransformFunc: func(k, v string) (string, any) {
 			// Convert TEST_SERVER_HOST to server.host
-			// Remove prefix, convert to lowercase, replace _ with .
-			k = strings.TrimPrefix(k, "TEST_")
-			k = strings.ToLower(k)
-			k = strings.ReplaceAll(k, "_", ".")
-			return k, v
+			// Remove prefix, convert to lowercase, then match a known key or
+			// replace _ with .
+			k = strings.ToLower(strings.TrimPrefix(k, "TEST_"))
+			if key, ok := keys[k]; ok {
+				return key, v
+			}
+			return strings.ReplaceAll(k, "_", "."), v
 		},
 	}), nil); err != nil {
 		return nil, fmt.Errorf("error loading environment variables: %w", err)
 	}
 
 	var cfg Config
-	if err := k.Unmarshal("", &cfg); err != nil {
+	if err := k.UnmarshalWithConf("", &cfg, koanf.UnmarshalConf{Tag: "mapstructure"}); err != nil {
 		return nil, fmt.Errorf("unable to decode config into struct: %w", err)
 	}
 	return &cfg, nil
@@ -113,3 +147,36 @@ func LoadConfig(t *testing.T) *Config {
 	}
 	return onceCfg
 }
+
+// RequireAPIKey skips the test unless an API key is configured.
+func (c *Config) RequireAPIKey(t *testing.T) {
+	t.Helper()
+	if c.Auth.APIKey == "" {
+		t.Skip("no API key configured; run go run ./tests/integration/setup")
+	}
+}
+
+// RequireCORS skips the test unless the servers have CORS enabled.
+func (c *Config) RequireCORS(t *testing.T) {
+	t.Helper()
+	if !c.CORS.Enabled {
+		t.Skip("CORS is not enabled; run go run ./tests/integration/setup")
+	}
+}
+
+// RequireLambdaStreaming skips the test unless "lambda serve" runs in
+// streaming mode.
+func (c *Config) RequireLambdaStreaming(t *testing.T) {
+	t.Helper()
+	if c.Lambda.Mode != "streaming" {
+		t.Skip("lambda serve is not in streaming mode; run go run ./tests/integration/setup")
+	}
+}
+
+// RequireSigningKey skips the test unless a signing key is configured.
+func (c *Config) RequireSigningKey(t *testing.T) {
+	t.Helper()
+	if c.Auth.SigningKeyID == "" || c.Auth.SigningSecret == "" {
+		t.Skip("no signing key configured; run go run ./tests/integration/setup")
+	}
+}
@@ -0,0 +1,141 @@
+package lambda
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/savisec/hello-go/tests/integration/config"
+)
+
+const shapesEchoBody = `{"message":"Hello from every front door","author":"Integration Test"}`
+
+func assertEchoed(t *testing.T, response LambdaResponse) {
+	t.Helper()
+
+	if response.StatusCode != 200 {
+		t.Fatalf("Expected status code 200, got %d. Response body: %s", response.StatusCode, response.Body)
+	}
+
+	var echoResp map[string]string
+	if err := json.Unmarshal([]byte(response.Body), &echoResp); err != nil {
+		t.Fatalf("Failed to decode echo response: %v", err)
+	}
+
+	if echoResp["message"] != "Hello from every front door" {
+		t.Errorf("Unexpected message '%s'", echoResp["message"])
+	}
+}
+
+func TestLambdaAPIGatewayV1Echo(t *testing.T) {
+	cfg := config.LoadConfig(t)
+
+	lambdaReq := LambdaAPIGatewayV1Request{
+		HTTPMethod: "POST",
+		Resource:   "/v1/echo",
+		Path:       "/v1/echo",
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+			"X-API-Key":    cfg.Auth.APIKey,
+		},
+		MultiValueHeaders: map[string][]string{
+			"Content-Type": {"application/json"},
+			"X-API-Key":    {cfg.Auth.APIKey},
+		},
+		RequestContext: V1RequestContext{
+			Stage:      "prod",
+			HTTPMethod: "POST",
+			Identity:   V1Identity{SourceIP: "127.0.0.1"},
+		},
+		Body: shapesEchoBody,
+	}
+
+	response := invokeLambda(t, cfg, lambdaReq)
+
+	assertEchoed(t, response)
+	if len(response.MultiValueHeaders["Content-Type"]) == 0 {
+		t.Errorf("Expected multiValueHeaders in API Gateway v1 response, got %v", response.MultiValueHeaders)
+	}
+}
+
+func TestLambdaFunctionURLEcho(t *testing.T) {
+	cfg := config.LoadConfig(t)
+
+	lambdaReq := LambdaAPIGatewayV2Request{
+		Version:  "2.0",
+		RouteKey: "$default",
+		RawPath:  "/v1/echo",
+		Headers: map[string]string{
+			"content-type": "application/json",
+			"x-api-key":    cfg.Auth.APIKey,
+		},
+		RequestContext: RequestContext{
+			DomainName: "abcdefghij.lambda-url.us-west-2.on.aws",
+			HTTP: HTTP{
+				Method:   "POST",
+				Path:     "/v1/echo",
+				Protocol: "HTTP/1.1",
+				SourceIP: "127.0.0.1",
+			},
+			RouteKey: "$default",
+			Stage:    "$default",
+		},
+		Body: shapesEchoBody,
+	}
+
+	response := invokeLambda(t, cfg, lambdaReq)
+
+	assertEchoed(t, response)
+}
+
+func TestLambdaALBEcho(t *testing.T) {
+	cfg := config.LoadConfig(t)
+
+	albContext := ALBRequestContext{
+		ELB: ELBContext{TargetGroupArn: "arn:aws:elasticloadbalancing:us-west-2:123456789012:targetgroup/hello-go/abc"},
+	}
+
+	t.Run("single value headers", func(t *testing.T) {
+		lambdaReq := LambdaALBRequest{
+			HTTPMethod: "POST",
+			Path:       "/v1/echo",
+			Headers: map[string]string{
+				"host":         "hello-go.example.com",
+				"content-type": "application/json",
+				"x-api-key":    cfg.Auth.APIKey,
+			},
+			RequestContext: albContext,
+			Body:           shapesEchoBody,
+		}
+
+		response := invokeLambda(t, cfg, lambdaReq)
+
+		assertEchoed(t, response)
+		if response.StatusDescription != "200 OK" {
+			t.Errorf("Expected statusDescription '200 OK', got '%s'", response.StatusDescription)
+		}
+		if len(response.MultiValueHeaders) != 0 {
+			t.Errorf("Expected no multiValueHeaders, got %v", response.MultiValueHeaders)
+		}
+	})
+
+	t.Run("multi value headers", func(t *testing.T) {
+		lambdaReq := LambdaALBRequest{
+			HTTPMethod: "POST",
+			Path:       "/v1/echo",
+			MultiValueHeaders: map[string][]string{
+				"host":         {"hello-go.example.com"},
+				"content-type": {"application/json"},
+				"x-api-key":    {cfg.Auth.APIKey},
+			},
+			RequestContext: albContext,
+			Body:           shapesEchoBody,
+		}
+
+		response := invokeLambda(t, cfg, lambdaReq)
+
+		assertEchoed(t, response)
+		if len(response.MultiValueHeaders["Content-Type"]) == 0 {
+			t.Errorf("Expected multiValueHeaders in ALB response, got %v", response.MultiValueHeaders)
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package lambda
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/savisec/hello-go/tests/integration/config"
+)
+
+// TestLambdaGeneratedFixtures replays the events in testdata, which are
+// generated from api/openapi.yml with `hello-go lambda fixtures`.
+func TestLambdaGeneratedFixtures(t *testing.T) {
+	cfg := config.LoadConfig(t)
+
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		t.Fatalf("Failed to list fixtures: %v", err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("No fixtures found in testdata")
+	}
+
+	for _, path := range paths {
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".json"), func(t *testing.T) {
+			event, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read fixture: %v", err)
+			}
+
+			// Fixtures carry no credentials; add the test key for protected operations.
+			var payload map[string]any
+			if err := json.Unmarshal(event, &payload); err != nil {
+				t.Fatalf("Failed to decode fixture: %v", err)
+			}
+			headers, _ := payload["headers"].(map[string]any)
+			if headers == nil {
+				headers = map[string]any{}
+			}
+			headers["x-api-key"] = cfg.Auth.APIKey
+			payload["headers"] = headers
+
+			response := invokeLambda(t, cfg, payload)
+
+			if response.StatusCode != 200 {
+				t.Errorf("Expected status code 200, got %d. Response body: %s", response.StatusCode, response.Body)
+			}
+		})
+	}
+}
@@ -44,9 +44,54 @@ type HTTP struct {
 	UserAgent string `json:"userAgent"`
 }
 
+// API Gateway REST API (v1) proxy request format
+type LambdaAPIGatewayV1Request struct {
+	Headers           map[string]string   `json:"headers"`
+	MultiValueHeaders map[string][]string `json:"multiValueHeaders"`
+	HTTPMethod        string              `json:"httpMethod"`
+	Resource          string              `json:"resource"`
+	Path              string              `json:"path"`
+	Body              string              `json:"body"`
+	RequestContext    V1RequestContext    `json:"requestContext"`
+	IsBase64Encoded   bool                `json:"isBase64Encoded"`
+}
+
+type V1RequestContext struct {
+	AccountID  string     `json:"accountId"`
+	APIID      string     `json:"apiId"`
+	Stage      string     `json:"stage"`
+	RequestID  string     `json:"requestId"`
+	HTTPMethod string     `json:"httpMethod"`
+	Identity   V1Identity `json:"identity"`
+}
+
+type V1Identity struct {
+	SourceIP string `json:"sourceIp"`
+}
+
+// ALB target group request format
+type LambdaALBRequest struct {
+	Headers           map[string]string   `json:"headers,omitempty"`
+	MultiValueHeaders map[string][]string `json:"multiValueHeaders,omitempty"`
+	HTTPMethod        string              `json:"httpMethod"`
+	Path              string              `json:"path"`
+	Body              string              `json:"body"`
+	RequestContext    ALBRequestContext   `json:"requestContext"`
+	IsBase64Encoded   bool                `json:"isBase64Encoded"`
+}
+
+type ALBRequestContext struct {
+	ELB ELBContext `json:"elb"`
+}
+
+type ELBContext struct {
+	TargetGroupArn string `json:"targetGroupArn"`
+}
+
 type LambdaResponse struct {
 	Headers           map[string]string   `json:"headers"`
 	MultiValueHeaders map[string][]string `json:"multiValueHeaders"`
+	StatusDescription string              `json:"statusDescription"`
 	Body              string              `json:"body"`
 	Cookies           []string            `json:"cookies"`
 	StatusCode        int                 `json:"statusCode"`
@@ -108,6 +153,7 @@ func TestLambdaEchoEndpoint(t *testing.T) {
 		Headers: map[string]string{
 			"accept":       "application/json",
 			"content-type": "application/json",
+			"x-api-key":    cfg.Auth.APIKey,
 		},
 		RequestContext: RequestContext{
 			HTTP: HTTP{
@@ -143,6 +189,46 @@ func TestLambdaEchoEndpoint(t *testing.T) {
 	}
 }
 
+func TestLambdaEchoStreams(t *testing.T) {
+	cfg := config.LoadConfig(t)
+	cfg.RequireAPIKey(t)
+	cfg.RequireLambdaStreaming(t)
+
+	req, err := http.NewRequest(http.MethodPost, cfg.Lambda.URL()+"/v1/echo",
+		bytes.NewBufferString(`{"message":"Hello Lambda Stream","author":"Integration Test"}`))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", cfg.Auth.APIKey)
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to call lambda serve: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code 200, got %d", resp.StatusCode)
+	}
+
+	// The streaming entrypoint hands the body over as it is written, so it
+	// arrives chunked rather than with a Content-Length.
+	if len(resp.TransferEncoding) != 1 || resp.TransferEncoding[0] != "chunked" {
+		t.Errorf("Expected a chunked response, got transfer encoding %v and content length %d",
+			resp.TransferEncoding, resp.ContentLength)
+	}
+
+	var echoResp map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&echoResp); err != nil {
+		t.Fatalf("Failed to decode echo response: %v", err)
+	}
+	if echoResp["message"] != "Hello Lambda Stream" {
+		t.Errorf("Expected message 'Hello Lambda Stream', got '%s'", echoResp["message"])
+	}
+}
+
 func TestLambdaEchoEndpointValidation(t *testing.T) {
 	cfg := config.LoadConfig(t)
 
@@ -159,6 +245,7 @@ func TestLambdaEchoEndpointValidation(t *testing.T) {
 		Headers: map[string]string{
 			"accept":       "application/json",
 			"content-type": "application/json",
+			"x-api-key":    cfg.Auth.APIKey,
 		},
 		RequestContext: RequestContext{
 			HTTP: HTTP{
@@ -190,7 +277,83 @@ func TestLambdaEchoEndpointValidation(t *testing.T) {
 	}
 }
 
-func invokeLambda(t *testing.T, cfg *config.Config, req LambdaAPIGatewayV2Request) LambdaResponse {
+func TestLambdaEchoRequiresAPIKey(t *testing.T) {
+	cfg := config.LoadConfig(t)
+	cfg.RequireAPIKey(t)
+
+	tests := []struct {
+		headers map[string]string
+		name    string
+	}{
+		{name: "missing key", headers: map[string]string{"content-type": "application/json"}},
+		{name: "wrong key", headers: map[string]string{"content-type": "application/json", "x-api-key": "dev.wrong"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lambdaReq := LambdaAPIGatewayV2Request{
+				Version:  "2.0",
+				RouteKey: "POST /v1/echo",
+				RawPath:  "/v1/echo",
+				Headers:  tt.headers,
+				RequestContext: RequestContext{
+					HTTP: HTTP{
+						Method:   "POST",
+						Path:     "/v1/echo",
+						Protocol: "HTTP/1.1",
+						SourceIP: "127.0.0.1",
+					},
+					RouteKey: "POST /v1/echo",
+					Stage:    "$default",
+				},
+				Body: `{"message":"Hello","author":"Integration Test"}`,
+			}
+
+			response := invokeLambda(t, cfg, lambdaReq)
+
+			if response.StatusCode != 401 {
+				t.Errorf("Expected status code 401, got %d. Response body: %s", response.StatusCode, response.Body)
+			}
+		})
+	}
+}
+
+func TestLambdaEchoCORSPreflight(t *testing.T) {
+	cfg := config.LoadConfig(t)
+	cfg.RequireCORS(t)
+
+	lambdaReq := LambdaAPIGatewayV2Request{
+		Version:  "2.0",
+		RouteKey: "OPTIONS /v1/echo",
+		RawPath:  "/v1/echo",
+		Headers: map[string]string{
+			"origin":                         "http://localhost:3000",
+			"access-control-request-method":  "POST",
+			"access-control-request-headers": "content-type, x-api-key",
+		},
+		RequestContext: RequestContext{
+			HTTP: HTTP{
+				Method:   "OPTIONS",
+				Path:     "/v1/echo",
+				Protocol: "HTTP/1.1",
+				SourceIP: "127.0.0.1",
+			},
+			RouteKey: "OPTIONS /v1/echo",
+			Stage:    "$default",
+		},
+	}
+
+	response := invokeLambda(t, cfg, lambdaReq)
+
+	if response.StatusCode != 204 {
+		t.Errorf("Expected status code 204, got %d. Response body: %s", response.StatusCode, response.Body)
+	}
+	if got := response.Headers["Access-Control-Allow-Origin"]; got != "http://localhost:3000" {
+		t.Errorf("Expected Access-Control-Allow-Origin 'http://localhost:3000', got '%s' in %v", got, response.Headers)
+	}
+}
+
+func invokeLambda(t *testing.T, cfg *config.Config, req any) LambdaResponse {
 	t.Helper()
 
 	reqJSON, err := json.Marshal(req)
@@ -0,0 +1,105 @@
+// Command setup generates throwaway credentials for the integration tests.
+// It writes the server side, with only the salted hash of the API key, to
+// configs/integration.yml, and the client side to the tests' private.yml.
+// Both are gitignored. Run it from the repository root, then serve with
+// APP_ENV=integration:
+//
+//	go run ./tests/integration/setup
+//	APP_ENV=integration go run ./cmd/api serve
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/knadh/koanf/parsers/yaml"
+
+	"github.com/savisec/hello-go/internal/auth"
+	"github.com/savisec/hello-go/internal/signing"
+)
+
+const (
+	principal = "integration"
+	scope     = "echo:write"
+)
+
+func main() {
+	serverPath := flag.String("server", "configs/integration.yml", "server config to write")
+	clientPath := flag.String("client", "tests/integration/config/private.yml", "test config to write")
+	flag.Parse()
+
+	if err := run(*serverPath, *clientPath); err != nil {
+		slog.Error("failed to set up integration credentials", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(serverPath, clientPath string) error {
+	rawKey, key, err := auth.GenerateKey("", principal, []string{scope})
+	if err != nil {
+		return err
+	}
+	secret, err := signing.GenerateSecret()
+	if err != nil {
+		return err
+	}
+	signingKeyID := "signing-" + key.ID
+
+	server := map[string]any{
+		"auth": map[string]any{
+			"enabled": true,
+			"api_keys": []map[string]any{{
+				"id": key.ID, "principal": principal, "scopes": []string{scope},
+				"salt": key.Salt, "hash": key.Hash,
+			}},
+			"signing": map[string]any{
+				"enabled": true,
+				"keys": []map[string]any{{
+					"id": signingKeyID, "principal": principal, "scopes": []string{scope},
+					"secret": secret,
+				}},
+			},
+		},
+		"cors": map[string]any{
+			"enabled": true,
+			"default": map[string]any{
+				"allowed_origins": []string{"http://localhost:3000", "http://*.localhost:3000"},
+			},
+		},
+		// Only read by "lambda serve", which then runs the response
+		// streaming entrypoint for plain HTTP requests.
+		"lambda": map[string]any{"mode": "streaming"},
+	}
+	client := map[string]any{
+		"auth": map[string]any{
+			"api_key":        rawKey,
+			"signing_key_id": signingKeyID,
+			"signing_secret": secret,
+		},
+		"cors":   map[string]any{"enabled": true},
+		"lambda": map[string]any{"mode": "streaming"},
+	}
+
+	if err := writeYAML(serverPath, server); err != nil {
+		return err
+	}
+	if err := writeYAML(clientPath, client); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s and %s; serve with APP_ENV=integration.\n", serverPath, clientPath)
+	return nil
+}
+
+func writeYAML(path string, values map[string]any) error {
+	data, err := yaml.Parser().Marshal(values)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	data = append([]byte("# Generated by tests/integration/setup; do not commit.\n"), data...)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}